@@ -0,0 +1,31 @@
+package main
+
+import (
+	"fmt"
+)
+
+// accessible is the plain mode for screen readers: every state is spelled
+// out, such as the severity of the updates shown by colors otherwise
+var accessible bool
+
+// itemState describes an option of the picker, as item 3 of 42, selected
+func itemState(i, n int, checked bool) string {
+	state := tr("not selected")
+	if checked {
+		state = tr("selected")
+	}
+	return tr("item %d of %d, %s", i+1, n, state)
+}
+
+// selectionState sums up the selection once made
+func selectionState(choice []int, n int) string {
+	return tr("%d of %d modules selected", len(choice), n)
+}
+
+// severityLabel names the severity of the update, in accessible mode
+func severityLabel(module Module) string {
+	if !accessible {
+		return ""
+	}
+	return fmt.Sprintf(" (%s)", module.severity)
+}
@@ -0,0 +1,131 @@
+package main
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/Masterminds/semver/v3"
+	"gopkg.in/yaml.v3"
+)
+
+// allowlistLocation and allowlistOverride are the --allowlist and --override
+// flags
+var (
+	allowlistLocation string
+	allowlistOverride bool
+)
+
+// allowlist is a curated registry of the approved versions, maintained by
+// the organization. Modules, matched by path pattern, list the version
+// constraints of their approved versions.
+type allowlist struct {
+	Modules map[string][]string `yaml:"modules"`
+	// override applies unapproved upgrades anyway, with a warning
+	override bool
+}
+
+// loadAllowlist reads the allowlist from an http(s) URL or a file, relative
+// to the directory of the configuration
+func loadAllowlist(location string, override bool) (*allowlist, error) {
+	var data []byte
+	if strings.HasPrefix(location, "http://") || strings.HasPrefix(location, "https://") {
+		client := &http.Client{Timeout: 30 * time.Second}
+		resp, err := client.Get(location)
+		if err != nil {
+			return nil, fmt.Errorf("allowlist: %v", err)
+		}
+		defer resp.Body.Close()
+		if resp.StatusCode != http.StatusOK {
+			return nil, fmt.Errorf("allowlist: GET %s: %s", location, resp.Status)
+		}
+		if data, err = ioutil.ReadAll(resp.Body); err != nil {
+			return nil, fmt.Errorf("allowlist: %v", err)
+		}
+	} else {
+		if !filepath.IsAbs(location) {
+			if file, err := configFile(); err == nil {
+				location = filepath.Join(filepath.Dir(file), location)
+			}
+		}
+		var err error
+		if data, err = ioutil.ReadFile(location); err != nil {
+			return nil, fmt.Errorf("allowlist: %v", err)
+		}
+	}
+	a := &allowlist{override: override}
+	if err := yaml.Unmarshal(data, a); err != nil {
+		return nil, fmt.Errorf("allowlist %s: %v", location, err)
+	}
+	for pattern, constraints := range a.Modules {
+		for _, c := range constraints {
+			if _, err := semver.NewConstraint(c); err != nil && !strings.HasPrefix(c, "v") {
+				return nil, fmt.Errorf("allowlist %s: invalid version %q for %s: %v", location, c, pattern, err)
+			}
+		}
+	}
+	return a, nil
+}
+
+// configuredAllowlist loads the allowlist given with --allowlist, or else the
+// one of the configuration, nil without any
+func configuredAllowlist(cfg *config) (*allowlist, error) {
+	location := allowlistLocation
+	if location == "" {
+		location = cfg.Allowlist
+	}
+	if location == "" {
+		return nil, nil
+	}
+	return loadAllowlist(location, allowlistOverride)
+}
+
+// approved tells whether the target version of the module is allowed
+func (a *allowlist) approved(m Module) bool {
+	for pattern, constraints := range a.Modules {
+		if !matchPattern(pattern, m.name) {
+			continue
+		}
+		for _, c := range constraints {
+			// Exact versions, which also cover non-semver ones
+			if c == m.toVersion {
+				return true
+			}
+			constraint, err := semver.NewConstraint(c)
+			if err == nil && m.to != nil && constraint.Check(m.to) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// permits tells whether the module may be upgraded, without printing
+// anything, for the JSON-RPC server
+func (a *allowlist) permits(m Module) bool {
+	return a == nil || a.override || a.approved(m)
+}
+
+// enforce drops the upgrades to unapproved versions, or keeps them with a
+// warning when overridden. Everything is allowed without an allowlist.
+func (a *allowlist) enforce(modules []Module) []Module {
+	if a == nil {
+		return modules
+	}
+	kept := []Module{}
+	for _, x := range modules {
+		if a.approved(x) {
+			kept = append(kept, x)
+		} else if a.override {
+			fmt.Printf("Warning: %s %s is not in the allowlist, upgrading anyway (--override)\n", x.name, x.toVersion)
+			kept = append(kept, x)
+		} else {
+			fmt.Printf("Refusing to upgrade %s to %s, not in the allowlist (use --override to force)\n", x.name, x.toVersion)
+			explanations.hide(x.dir, x.name, "%s not in the allowlist", x.toVersion)
+		}
+	}
+	return kept
+}
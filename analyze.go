@@ -0,0 +1,149 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/kballard/go-shellquote"
+)
+
+const defaultAnalyzer = "go vet ./..."
+
+// analysisColumn is the column summarizing the findings of an upgrade
+const analysisColumn = "analysis"
+
+// findingLine is a finding as file:line[:column]: message, the format of go
+// vet, staticcheck and golangci-lint
+var findingLine = regexp.MustCompile(`^(?:vet: )?([^\s:]+\.go):\d+(?::\d+)?:\s*(.+)$`)
+
+// analysis is what an upgrade changes to the findings of the analyzer
+type analysis struct {
+	fixed      []string
+	introduced []string
+}
+
+func (a *analysis) String() string {
+	parts := []string{}
+	if len(a.fixed) > 0 {
+		parts = append(parts, fmt.Sprintf("fixes %d", len(a.fixed)))
+	}
+	if len(a.introduced) > 0 {
+		parts = append(parts, fmt.Sprintf("introduces %d", len(a.introduced)))
+	}
+	return strings.Join(parts, ", ")
+}
+
+// runAnalyzer runs the analyzer in dir and returns its findings, without
+// their line numbers which the other changes of the files shift. An analyzer
+// reporting findings usually exits with an error, which only counts when
+// there are none.
+func runAnalyzer(dir, command string) (map[string]bool, error) {
+	args, err := shellquote.Split(command)
+	if err != nil {
+		return nil, fmt.Errorf("analyzer %q: %v", command, err)
+	}
+	if len(args) == 0 {
+		return nil, fmt.Errorf("analyzer %q: empty command", command)
+	}
+	cmd := exec.Command(args[0], args[1:]...)
+	cmd.Dir = dir
+	if len(goEnv) > 0 {
+		cmd.Env = append(os.Environ(), goEnv...)
+	}
+	out, err := cmd.CombinedOutput()
+	var exitErr *exec.ExitError
+	if err != nil && !errors.As(err, &exitErr) {
+		return nil, fmt.Errorf("analyzer %q: %v", command, err)
+	}
+	findings := map[string]bool{}
+	for _, line := range strings.Split(string(out), "\n") {
+		if m := findingLine.FindStringSubmatch(strings.TrimSpace(line)); m != nil {
+			findings[m[1]+": "+m[2]] = true
+		}
+	}
+	if err != nil && len(findings) == 0 {
+		return nil, fmt.Errorf("analyzer %q: %v: %s", command, err, strings.TrimSpace(string(out)))
+	}
+	return findings, nil
+}
+
+// compareFindings returns the findings which are gone and the new ones
+func compareFindings(before, after map[string]bool) *analysis {
+	a := &analysis{}
+	for f := range before {
+		if !after[f] {
+			a.fixed = append(a.fixed, f)
+		}
+	}
+	for f := range after {
+		if !before[f] {
+			a.introduced = append(a.introduced, f)
+		}
+	}
+	sort.Strings(a.fixed)
+	sort.Strings(a.introduced)
+	return a
+}
+
+// analyzeUpgrades runs the analyzer before and after each upgrade, resolved
+// in a sandbox, and preselects the upgrades fixing findings without
+// introducing any
+func analyzeUpgrades(modules []Module, command string) {
+	if command == "" {
+		command = defaultAnalyzer
+	}
+	progress("Running %s before and after each upgrade...", command)
+	baselines := map[string]map[string]bool{}
+	for i, x := range modules {
+		before, ok := baselines[x.dir]
+		if !ok {
+			var err error
+			if before, err = runAnalyzer(x.dir, command); err != nil {
+				fmt.Println(err)
+				return
+			}
+			baselines[x.dir] = before
+		}
+		after, err := analyzeUpgrade(x, command)
+		if err != nil {
+			fmt.Printf("Error while analyzing %s: %v\n", x.name, err)
+			continue
+		}
+		a := compareFindings(before, after)
+		if len(a.fixed) == 0 && len(a.introduced) == 0 {
+			continue
+		}
+		modules[i].analysis = a
+		if modules[i].columns == nil {
+			modules[i].columns = map[string]string{}
+		}
+		modules[i].columns[analysisColumn] = a.String()
+		if len(a.fixed) > 0 && len(a.introduced) == 0 {
+			modules[i].preselected = true
+		}
+	}
+}
+
+// analyzeUpgrade runs the analyzer with the module upgraded in a sandbox
+func analyzeUpgrade(m Module, command string) (map[string]bool, error) {
+	s, err := newSandbox(m.dir)
+	if err != nil {
+		return nil, err
+	}
+	defer s.remove()
+	if err := s.get(m.name + "@" + m.toVersion); err != nil {
+		return nil, err
+	}
+	var findings map[string]bool
+	err = s.run(func() error {
+		var err error
+		findings, err = runAnalyzer(m.dir, command)
+		return err
+	})
+	return findings, err
+}
@@ -0,0 +1,153 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"sync/atomic"
+	"time"
+)
+
+// maxRateLimitWait is the longest we wait for a rate limit reset, beyond
+// which the enrichment is skipped
+const maxRateLimitWait = time.Minute
+
+var errRateLimited = errors.New("API rate limit exceeded")
+
+// errNotFound is wrapped by the errors of the requests answered with 404
+var errNotFound = errors.New("404 Not Found")
+
+// apiClient calls a JSON API, authenticated when a token is given and
+// honoring rate limits
+type apiClient struct {
+	base string
+	// auth formats the token into the Authorization header, or into the
+	// header named by authHeader
+	authHeader string
+	auth       func(token string) string
+	token      string
+	client     *http.Client
+	// limited is set atomically, as the enrichment calls the API from
+	// several goroutines
+	limited int32
+}
+
+func newAPIClient(base, token string, auth func(string) string) *apiClient {
+	return &apiClient{
+		base:   base,
+		token:  token,
+		auth:   auth,
+		client: &http.Client{Timeout: 30 * time.Second},
+	}
+}
+
+// isLimited tells whether the rate limit was exceeded
+func (c *apiClient) isLimited() bool {
+	return atomic.LoadInt32(&c.limited) == 1
+}
+
+func bearer(token string) string {
+	return "Bearer " + token
+}
+
+// get decodes the JSON response of the API path. Once the rate limit is
+// exceeded for longer than maxRateLimitWait, every call fails with
+// errRateLimited so that callers can skip the enrichment.
+func (c *apiClient) get(path string, v interface{}) error {
+	return c.do(http.MethodGet, path, nil, v)
+}
+
+// post sends the body as JSON to the API path, and decodes the JSON response
+func (c *apiClient) post(path string, body, v interface{}) error {
+	return c.send(http.MethodPost, path, body, v)
+}
+
+// put is post with the PUT method
+func (c *apiClient) put(path string, body, v interface{}) error {
+	return c.send(http.MethodPut, path, body, v)
+}
+
+func (c *apiClient) send(method, path string, body, v interface{}) error {
+	data, err := json.Marshal(body)
+	if err != nil {
+		return err
+	}
+	return c.do(method, path, data, v)
+}
+
+func (c *apiClient) do(method, path string, body []byte, v interface{}) error {
+	if c.isLimited() {
+		return errRateLimited
+	}
+	for attempt := 0; ; attempt++ {
+		var reader io.Reader
+		if body != nil {
+			reader = bytes.NewReader(body)
+		}
+		req, err := http.NewRequest(method, c.base+path, reader)
+		if err != nil {
+			return err
+		}
+		req.Header.Set("Accept", "application/json")
+		if body != nil {
+			req.Header.Set("Content-Type", "application/json")
+		}
+		if c.token != "" {
+			header := c.authHeader
+			if header == "" {
+				header = "Authorization"
+			}
+			req.Header.Set(header, c.auth(c.token))
+		}
+		resp, err := c.client.Do(req)
+		if err != nil {
+			return err
+		}
+		if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+			defer resp.Body.Close()
+			return json.NewDecoder(resp.Body).Decode(v)
+		}
+		resp.Body.Close()
+		if resp.StatusCode == http.StatusNotFound {
+			return fmt.Errorf("%s %s%s: %w", method, c.base, path, errNotFound)
+		}
+		wait, limited := rateLimitWait(resp, attempt)
+		if !limited {
+			return fmt.Errorf("%s %s%s: %s", method, c.base, path, resp.Status)
+		}
+		if wait > maxRateLimitWait || attempt >= 3 {
+			atomic.StoreInt32(&c.limited, 1)
+			return errRateLimited
+		}
+		time.Sleep(wait)
+	}
+}
+
+// rateLimitWait tells whether the response is a rate limit error and how
+// long to wait before retrying
+func rateLimitWait(resp *http.Response, attempt int) (time.Duration, bool) {
+	if resp.StatusCode != http.StatusForbidden && resp.StatusCode != http.StatusTooManyRequests {
+		return 0, false
+	}
+	if s, err := strconv.Atoi(resp.Header.Get("Retry-After")); err == nil {
+		return time.Duration(s) * time.Second, true
+	}
+	for _, prefix := range []string{"X-RateLimit", "RateLimit"} {
+		if resp.Header.Get(prefix+"-Remaining") == "0" {
+			reset, err := strconv.ParseInt(resp.Header.Get(prefix+"-Reset"), 10, 64)
+			if err != nil {
+				return 0, true
+			}
+			return time.Until(time.Unix(reset, 0)), true
+		}
+	}
+	if resp.StatusCode == http.StatusTooManyRequests {
+		// Secondary rate limits without hints, back off exponentially
+		return time.Duration(1<<uint(attempt)) * time.Second, true
+	}
+	return 0, false
+}
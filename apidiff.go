@@ -0,0 +1,128 @@
+package main
+
+import (
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// exportedAPI lists the exported identifiers of the public packages of a
+// module version, as pkg.Name or pkg.Type.Method with pkg relative to the
+// module root
+func exportedAPI(path, version string) (map[string]bool, error) {
+	info, err := downloadModule(path, version)
+	if err != nil {
+		return nil, err
+	}
+	api := map[string]bool{}
+	fset := token.NewFileSet()
+	err = filepath.Walk(info.Dir, func(file string, fi os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		name := fi.Name()
+		if fi.IsDir() {
+			if file == info.Dir {
+				return nil
+			}
+			// Internal packages are not part of the API, nested modules not
+			// part of this one
+			if name == "internal" || name == "testdata" || name == "vendor" || strings.HasPrefix(name, ".") || strings.HasPrefix(name, "_") {
+				return filepath.SkipDir
+			}
+			if _, err := os.Stat(filepath.Join(file, "go.mod")); err == nil {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if !strings.HasSuffix(name, ".go") || strings.HasSuffix(name, "_test.go") {
+			return nil
+		}
+		f, err := parser.ParseFile(fset, file, nil, 0)
+		if err != nil || f.Name.Name == "main" {
+			return nil
+		}
+		rel, _ := filepath.Rel(info.Dir, filepath.Dir(file))
+		pkg := filepath.ToSlash(rel)
+		if pkg == "." {
+			pkg = f.Name.Name
+		}
+		for _, d := range f.Decls {
+			for _, id := range exportedNames(d) {
+				api[pkg+"."+id] = true
+			}
+		}
+		return nil
+	})
+	return api, err
+}
+
+func exportedNames(d ast.Decl) []string {
+	names := []string{}
+	switch d := d.(type) {
+	case *ast.FuncDecl:
+		if !d.Name.IsExported() {
+			break
+		}
+		if d.Recv == nil || len(d.Recv.List) == 0 {
+			names = append(names, d.Name.Name)
+			break
+		}
+		if recv := receiverType(d.Recv.List[0].Type); ast.IsExported(recv) {
+			names = append(names, recv+"."+d.Name.Name)
+		}
+	case *ast.GenDecl:
+		for _, s := range d.Specs {
+			switch s := s.(type) {
+			case *ast.TypeSpec:
+				if s.Name.IsExported() {
+					names = append(names, s.Name.Name)
+				}
+			case *ast.ValueSpec:
+				for _, n := range s.Names {
+					if n.IsExported() {
+						names = append(names, n.Name)
+					}
+				}
+			}
+		}
+	}
+	return names
+}
+
+func receiverType(e ast.Expr) string {
+	switch e := e.(type) {
+	case *ast.StarExpr:
+		return receiverType(e.X)
+	case *ast.IndexExpr:
+		return receiverType(e.X)
+	case *ast.Ident:
+		return e.Name
+	}
+	return ""
+}
+
+// apiRemovals lists the exported identifiers removed by the upgrade, which
+// break their users
+func apiRemovals(m Module) ([]string, error) {
+	from, err := exportedAPI(m.name, m.fromVersion)
+	if err != nil {
+		return nil, err
+	}
+	to, err := exportedAPI(m.name, m.toVersion)
+	if err != nil {
+		return nil, err
+	}
+	removed := []string{}
+	for id := range from {
+		if !to[id] {
+			removed = append(removed, id)
+		}
+	}
+	sort.Strings(removed)
+	return removed, nil
+}
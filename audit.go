@@ -0,0 +1,106 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"os/user"
+	"path/filepath"
+	"time"
+)
+
+// auditConfig configures the audit trail of the applied updates
+type auditConfig struct {
+	// File is relative to the directory of the configuration
+	File string `yaml:"file"`
+	// Commit commits the audit trail after each run
+	Commit bool `yaml:"commit,omitempty"`
+}
+
+// auditEntry is a line of the audit trail, a JSON lines file only ever
+// appended to
+type auditEntry struct {
+	Time     time.Time `json:"time"`
+	User     string    `json:"user"`
+	Hostname string    `json:"hostname"`
+	Module   string    `json:"module"`
+	Dir      string    `json:"dir,omitempty"`
+	From     string    `json:"from"`
+	To       string    `json:"to"`
+	Flags    []string  `json:"flags"`
+}
+
+type auditLog struct {
+	file   string
+	commit bool
+}
+
+// openAudit returns nil when no audit trail is configured
+func openAudit(c *auditConfig) (*auditLog, error) {
+	if c == nil || c.File == "" {
+		return nil, nil
+	}
+	file := c.File
+	if !filepath.IsAbs(file) {
+		root, err := projectRoot()
+		if err != nil {
+			return nil, err
+		}
+		file = filepath.Join(root, file)
+	}
+	return &auditLog{file: file, commit: c.Commit}, nil
+}
+
+// record appends the applied updates to the audit trail, and commits it
+// when configured
+func (a *auditLog) record(applied []Module) error {
+	if a == nil || len(applied) == 0 {
+		return nil
+	}
+	f, err := os.OpenFile(a.file, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return err
+	}
+	hostname, _ := os.Hostname()
+	username := ""
+	if u, err := user.Current(); err == nil {
+		username = u.Username
+	}
+	enc := json.NewEncoder(f)
+	now := time.Now()
+	for _, x := range applied {
+		err := enc.Encode(auditEntry{
+			Time:     now,
+			User:     username,
+			Hostname: hostname,
+			Module:   x.name,
+			Dir:      x.dir,
+			From:     x.fromVersion,
+			To:       x.toVersion,
+			Flags:    os.Args[1:],
+		})
+		if err != nil {
+			f.Close()
+			return err
+		}
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	if !a.commit {
+		return nil
+	}
+	dir, name := filepath.Split(a.file)
+	for _, args := range [][]string{
+		{"add", name},
+		{"commit", "-m", fmt.Sprintf("Record %d module update(s) in the audit trail", len(applied)), "--", name},
+	} {
+		cmd := exec.Command("git", args...)
+		cmd.Dir = dir
+		if out, err := cmd.CombinedOutput(); err != nil {
+			return fmt.Errorf("git %s: %v: %s", args[0], err, out)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,179 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"runtime"
+	"strings"
+
+	"github.com/AlecAivazis/survey/v2"
+	term "github.com/AlecAivazis/survey/v2/terminal"
+	"golang.org/x/crypto/ssh/terminal"
+)
+
+// failedModule matches the module named by a failed go command, as in
+// go: example.com/org/repo@v1.0.0: reading ...: 404 Not Found
+var failedModule = regexp.MustCompile(`(?:go: |module )([a-z0-9.-]+\.[a-z]{2,}(?:/[A-Za-z0-9._~-]+)+)(?:@|: )`)
+
+// authHelped remembers the organizations already set up during this run
+var authHelped = map[string]bool{}
+
+// netrcFile returns the .netrc file read by git and the go command
+func netrcFile() string {
+	if netrc := os.Getenv("NETRC"); netrc != "" {
+		return netrc
+	}
+	home, _ := os.UserHomeDir()
+	if runtime.GOOS == "windows" {
+		return filepath.Join(home, "_netrc")
+	}
+	return filepath.Join(home, ".netrc")
+}
+
+// askConfirm asks a yes or no question, exiting when interrupted
+func askConfirm(message string) bool {
+	confirm := false
+	err := ask(&survey.Confirm{Message: message}, &confirm)
+	if err == term.InterruptErr {
+		fmt.Println(tr("Bye"))
+		os.Exit(0)
+	}
+	return err == nil && confirm
+}
+
+// setupPrivate adds the organization to GOPRIVATE, so that its modules are
+// fetched from their repositories instead of the public proxy and checksum
+// database, which don't know them
+func setupPrivate(path, org string) error {
+	out, err := goCommand("env", "GOPRIVATE").Output()
+	if err != nil {
+		return newGoError([]string{"env", "GOPRIVATE"}, err)
+	}
+	current := strings.TrimSpace(string(out))
+	if matchesPrefix(strings.Split(current, ","), path) {
+		fmt.Printf("GOPRIVATE=%s already covers %s\n", current, path)
+		return nil
+	}
+	value := org
+	if current != "" {
+		value = current + "," + org
+	}
+	if os.Getenv("GOPRIVATE") != "" {
+		// go env -w can't override the environment
+		fmt.Printf("GOPRIVATE is set in the environment, add %s to it in your shell profile:\n  export GOPRIVATE=%s\n", org, value)
+		goEnv = append(goEnv, "GOPRIVATE="+value)
+		return nil
+	}
+	if !askConfirm(fmt.Sprintf("Add %s to GOPRIVATE with go env -w, so that it isn't looked up on the public proxy?", org)) {
+		return nil
+	}
+	args := []string{"env", "-w", "GOPRIVATE=" + value}
+	if out, err := goCommand(args...).CombinedOutput(); err != nil {
+		return fmt.Errorf("go %s: %v: %s", strings.Join(args, " "), err, strings.TrimSpace(string(out)))
+	}
+	fmt.Printf("GOPRIVATE=%s\n", value)
+	return nil
+}
+
+// setupCredentials configures git to authenticate to the host, with SSH
+// keys through a URL rewrite, or with a token in .netrc
+func setupCredentials(host string) error {
+	if d := checkGit(true); d.status == checkOK {
+		fmt.Printf("git credentials: %s\n", d.detail)
+		return nil
+	}
+	const (
+		useSSH   = "Use my SSH key, rewriting the HTTPS URLs in the git configuration"
+		useNetrc = "Store a login and an access token in "
+		skip     = "Skip, the credentials are configured elsewhere"
+	)
+	netrc := netrcFile()
+	answer := ""
+	prompt := &survey.Select{
+		Message: fmt.Sprintf("How should git authenticate to %s?", host),
+		Options: []string{useSSH, useNetrc + netrc, skip},
+	}
+	if err := ask(prompt, &answer); err == term.InterruptErr {
+		fmt.Println(tr("Bye"))
+		os.Exit(0)
+	} else if err != nil {
+		return err
+	}
+	switch answer {
+	case useSSH:
+		args := []string{"config", "--global", fmt.Sprintf("url.git@%s:.insteadOf", host), fmt.Sprintf("https://%s/", host)}
+		if out, err := exec.Command("git", args...).CombinedOutput(); err != nil {
+			return fmt.Errorf("git %s: %v: %s", strings.Join(args, " "), err, strings.TrimSpace(string(out)))
+		}
+		fmt.Printf("git now fetches https://%s/ over SSH\n", host)
+	case useNetrc + netrc:
+		login, token := "", ""
+		if err := ask(&survey.Input{Message: "Login"}, &login, survey.WithValidator(survey.Required)); err != nil {
+			return err
+		}
+		if err := ask(&survey.Password{Message: "Access token"}, &token, survey.WithValidator(survey.Required)); err != nil {
+			return err
+		}
+		f, err := os.OpenFile(netrc, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0600)
+		if err != nil {
+			return err
+		}
+		if _, err := fmt.Fprintf(f, "\nmachine %s login %s password %s\n", host, login, token); err != nil {
+			f.Close()
+			return err
+		}
+		if err := f.Close(); err != nil {
+			return err
+		}
+		fmt.Printf("Added the credentials of %s to %s\n", host, netrc)
+	}
+	return nil
+}
+
+// authHelp walks through the access to the private modules of the
+// organization of the module path
+func authHelp(path string) error {
+	org := strings.TrimSuffix(orgPattern(path), "/*")
+	authHelped[org] = true
+	fmt.Printf("Setting up the access to the private modules of %s\n", org)
+	if err := setupPrivate(path, org); err != nil {
+		return err
+	}
+	return setupCredentials(strings.Split(path, "/")[0])
+}
+
+// offerAuthHelp offers to set up the access to the private module whose
+// download failed, in a terminal, reporting whether it was set up
+func offerAuthHelp(err error) bool {
+	if err == nil || classifyFailure(err.Error()) != "auth" || !terminal.IsTerminal(int(os.Stdin.Fd())) {
+		return false
+	}
+	match := failedModule.FindStringSubmatch(err.Error())
+	if match == nil || authHelped[strings.TrimSuffix(orgPattern(match[1]), "/*")] {
+		return false
+	}
+	if !askConfirm(fmt.Sprintf("%s may be private, set up the access to it?", match[1])) {
+		return false
+	}
+	if herr := authHelp(match[1]); herr != nil {
+		fmt.Println(herr)
+		return false
+	}
+	return true
+}
+
+func authCommand(args []string) error {
+	fs := flag.NewFlagSet("auth", flag.ExitOnError)
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+	if fs.NArg() != 1 {
+		return errors.New("auth: expected a module path, as github.com/myorg/repo")
+	}
+	return authHelp(fs.Arg(0))
+}
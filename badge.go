@@ -0,0 +1,39 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+// badge is a shields.io endpoint, see https://shields.io/badges/endpoint-badge
+type badge struct {
+	SchemaVersion int    `json:"schemaVersion"`
+	Label         string `json:"label"`
+	Message       string `json:"message"`
+	Color         string `json:"color"`
+}
+
+// badge summarizes the outdated direct dependencies, as 7 outdated, 1 major,
+// red when a major upgrade is pending
+func (s *stats) badge() badge {
+	b := badge{SchemaVersion: 1, Label: "dependencies", Message: "up to date", Color: "brightgreen"}
+	if s.Outdated == 0 {
+		return b
+	}
+	b.Message, b.Color = fmt.Sprintf("%d outdated", s.Outdated), "yellow"
+	if majors := s.Severities[SeverityMajor.String()]; majors > 0 {
+		b.Message += fmt.Sprintf(", %d major", majors)
+		b.Color = "red"
+	}
+	return b
+}
+
+// writeBadge writes the badge endpoint of the statistics, replaced
+// atomically so that a server never serves it half written
+func writeBadge(file string, s *stats) error {
+	data, err := json.Marshal(s.badge())
+	if err != nil {
+		return err
+	}
+	return writeFileAtomic(file, append(data, '\n'))
+}
@@ -0,0 +1,62 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+
+	"github.com/kballard/go-shellquote"
+)
+
+// bazelCommands keeps the Bazel files in sync with go.mod: the repositories
+// generated by Gazelle for WORKSPACE setups, the module extension for
+// MODULE.bazel (bzlmod) ones
+func bazelCommands(root string) []string {
+	exists := func(name string) bool {
+		_, err := os.Stat(filepath.Join(root, name))
+		return err == nil
+	}
+	commands := []string{}
+	if exists("MODULE.bazel") {
+		commands = append(commands, "bazel mod tidy")
+	} else if exists("WORKSPACE") || exists("WORKSPACE.bazel") {
+		commands = append(commands, "bazel run //:gazelle -- update-repos -from_file=go.mod -to_macro=deps.bzl%go_dependencies -prune")
+	}
+	return commands
+}
+
+// runPostUpdate runs the post-update commands in the project root, showing
+// their output
+func runPostUpdate(commands []string) error {
+	root, err := projectRoot()
+	if err != nil {
+		return err
+	}
+	return runCommands(root, "post-update", commands)
+}
+
+// runCommands runs the commands of a kind in dir, showing their output
+func runCommands(dir, kind string, commands []string) error {
+	for _, c := range commands {
+		args, err := shellquote.Split(c)
+		if err != nil {
+			return fmt.Errorf("%s command %q: %v", kind, c, err)
+		}
+		if len(args) == 0 {
+			continue
+		}
+		progress("Running %s...", c)
+		cmd := exec.Command(args[0], args[1:]...)
+		cmd.Dir = dir
+		if len(goEnv) > 0 {
+			cmd.Env = append(os.Environ(), goEnv...)
+		}
+		cmd.Stdout = os.Stdout
+		cmd.Stderr = os.Stderr
+		if err := cmd.Run(); err != nil {
+			return fmt.Errorf("%s command %q failed: %v", kind, c, err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,65 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strings"
+)
+
+// bitbucketProvider uses the messages of annotated tags as release notes,
+// Bitbucket having no releases nor archived repositories
+type bitbucketProvider struct {
+	host string
+	api  *apiClient
+}
+
+type bitbucketTags struct {
+	Values []struct {
+		Name    string `json:"name"`
+		Message string `json:"message"`
+		Links   struct {
+			HTML struct {
+				Href string `json:"href"`
+			} `json:"html"`
+		} `json:"links"`
+	} `json:"values"`
+}
+
+// newBitbucketProvider defaults the token to the BITBUCKET_TOKEN environment
+// variable
+func newBitbucketProvider(host, api, token string) *bitbucketProvider {
+	if token == "" {
+		token = os.Getenv("BITBUCKET_TOKEN")
+	}
+	if api == "" {
+		api = "https://api." + host + "/2.0"
+	}
+	return &bitbucketProvider{
+		host: host,
+		api:  newAPIClient(strings.TrimSuffix(api, "/"), token, bearer),
+	}
+}
+
+func (b *bitbucketProvider) releases(repo string) ([]release, error) {
+	var tags bitbucketTags
+	if err := b.api.get(fmt.Sprintf("/repositories/%s/refs/tags?pagelen=100&sort=-target.date", repo), &tags); err != nil {
+		return nil, err
+	}
+	releases := []release{}
+	for _, t := range tags.Values {
+		releases = append(releases, release{Tag: t.Name, Notes: t.Message, URL: t.Links.HTML.Href})
+	}
+	return releases, nil
+}
+
+func (b *bitbucketProvider) releasesURL(repo string) string {
+	return fmt.Sprintf("https://%s/%s/downloads/?tab=tags", b.host, repo)
+}
+
+func (b *bitbucketProvider) compareURL(repo, from, to string) string {
+	return fmt.Sprintf("https://%s/%s/branches/compare/%s%%0D%s", b.host, repo, to, from)
+}
+
+func (b *bitbucketProvider) archived(repo string) (bool, error) {
+	return false, nil
+}
@@ -0,0 +1,134 @@
+package main
+
+import (
+	"bufio"
+	"regexp"
+	"strings"
+
+	"github.com/Masterminds/semver/v3"
+)
+
+// maxSummaryItems caps the number of bullets kept per summary section
+const maxSummaryItems = 5
+
+// changelog gathers the release notes between the current and target
+// versions of a module
+type changelog struct {
+	URL         string
+	CompareURL  string
+	Archived    bool
+	Breaking    []string
+	Deprecated  []string
+	Features    []string
+	ReleaseURLs []string
+}
+
+// fetchChangelog summarizes the release notes published between the current
+// and target versions of the module, and tells whether its repository is
+// archived. It returns nil for modules without a known provider.
+func fetchChangelog(p *providers, m Module) (*changelog, error) {
+	pr, repo, prefix, ok := p.lookup(m.name)
+	if !ok {
+		return nil, nil
+	}
+	c := &changelog{
+		URL:        pr.releasesURL(repo),
+		CompareURL: pr.compareURL(repo, prefix+m.fromVersion, prefix+m.toVersion),
+	}
+	archived, err := cachedArchived(pr, repo, m.name)
+	if err != nil {
+		return c, err
+	}
+	c.Archived = archived
+	if m.from == nil || m.to == nil {
+		return c, nil
+	}
+	key := m.name + "@" + m.fromVersion + ".." + m.toVersion
+	var cached changelog
+	if metadata.load("changelogs", key, changelogTTL, &cached) {
+		c.Breaking, c.Deprecated, c.Features, c.ReleaseURLs = cached.Breaking, cached.Deprecated, cached.Features, cached.ReleaseURLs
+		return c, nil
+	}
+	releases, err := pr.releases(repo)
+	if err != nil {
+		return c, err
+	}
+	for _, r := range releases {
+		if !strings.HasPrefix(r.Tag, prefix) {
+			continue
+		}
+		v, err := semver.NewVersion(strings.TrimPrefix(r.Tag, prefix))
+		if err != nil || !v.GreaterThan(m.from) || v.GreaterThan(m.to) {
+			continue
+		}
+		c.ReleaseURLs = append(c.ReleaseURLs, r.URL)
+		c.summarize(r.Notes)
+	}
+	metadata.store("changelogs", key, c)
+	return c, nil
+}
+
+// cachedArchived tells whether the repository of the module is archived,
+// looked up again once a day
+func cachedArchived(pr provider, repo, path string) (bool, error) {
+	var archived bool
+	if metadata.load("archived", path, statusTTL, &archived) {
+		return archived, nil
+	}
+	archived, err := pr.archived(repo)
+	if err != nil {
+		return false, err
+	}
+	metadata.store("archived", path, archived)
+	return archived, nil
+}
+
+var (
+	headingLine = regexp.MustCompile(`^\s*#{1,6}\s*(.+?)\s*#*\s*$`)
+	bulletLine  = regexp.MustCompile(`^\s*[-*+]\s+(.+)$`)
+)
+
+// summarize collects the bullets of the release notes under headings about
+// breaking changes, deprecations and features, along with the bullets
+// explicitly flagged as breaking
+func (c *changelog) summarize(notes string) {
+	var section *[]string
+	scanner := bufio.NewScanner(strings.NewReader(notes))
+	for scanner.Scan() {
+		line := scanner.Text()
+		if m := headingLine.FindStringSubmatch(line); m != nil {
+			heading := strings.ToLower(m[1])
+			switch {
+			case strings.Contains(heading, "breaking"):
+				section = &c.Breaking
+			case strings.Contains(heading, "deprecat"):
+				section = &c.Deprecated
+			case strings.Contains(heading, "feature"), strings.Contains(heading, "added"),
+				strings.Contains(heading, "new"), strings.Contains(heading, "enhancement"):
+				section = &c.Features
+			default:
+				section = nil
+			}
+			continue
+		}
+		m := bulletLine.FindStringSubmatch(line)
+		if m == nil {
+			continue
+		}
+		item := strings.TrimSpace(m[1])
+		switch {
+		case section != nil:
+			appendItem(section, item)
+		case strings.Contains(strings.ToUpper(item), "BREAKING"):
+			appendItem(&c.Breaking, item)
+		case strings.Contains(strings.ToLower(item), "deprecat"):
+			appendItem(&c.Deprecated, item)
+		}
+	}
+}
+
+func appendItem(items *[]string, item string) {
+	if len(*items) < maxSummaryItems {
+		*items = append(*items, item)
+	}
+}
@@ -0,0 +1,115 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"strings"
+
+	"github.com/AlecAivazis/survey/v2"
+	"github.com/Masterminds/semver/v3"
+)
+
+// staleDirective is an exclude or replace directive of go.mod which no
+// longer has any effect
+type staleDirective struct {
+	// drop is the go mod edit flag removing the directive
+	drop   string
+	text   string
+	reason string
+}
+
+// staleDirectives finds the exclude directives of versions older than the
+// selected one, which minimal version selection never picks anyway, and the
+// replace directives of modules which are no longer in the module graph, or
+// at another version than the replaced one
+func staleDirectives(debug bool) ([]staleDirective, error) {
+	d, err := readDirectives("")
+	if err != nil {
+		return nil, err
+	}
+	if len(d.Exclude) == 0 && len(d.Replace) == 0 {
+		return nil, nil
+	}
+	list, err := goList("", debug, "list", "-mod=mod", "-m", "-json", "all")
+	if err != nil {
+		return nil, err
+	}
+	selected := map[string]string{}
+	for _, m := range list {
+		if !m.Main {
+			selected[m.Path] = m.Version
+		}
+	}
+	stale := []staleDirective{}
+	for _, e := range d.Exclude {
+		version, ok := selected[e.Path]
+		if !ok {
+			stale = append(stale, staleDirective{"-dropexclude=" + e.String(), "exclude " + e.Path + " " + e.Version, "the module is no longer in the module graph"})
+			continue
+		}
+		current, cerr := semver.NewVersion(version)
+		excluded, eerr := semver.NewVersion(e.Version)
+		if cerr == nil && eerr == nil && excluded.LessThan(current) {
+			stale = append(stale, staleDirective{"-dropexclude=" + e.String(), "exclude " + e.Path + " " + e.Version, "the selected version is " + version})
+		}
+	}
+	for _, r := range d.Replace {
+		text := "replace " + strings.Replace(r.Old.String(), "@", " ", 1) + " => " + strings.Replace(r.New.String(), "@", " ", 1)
+		version, ok := selected[r.Old.Path]
+		switch {
+		case !ok:
+			stale = append(stale, staleDirective{"-dropreplace=" + r.Old.String(), text, "the module is no longer in the module graph"})
+		case r.Old.Version != "" && r.Old.Version != version:
+			stale = append(stale, staleDirective{"-dropreplace=" + r.Old.String(), text, "the selected version is " + version})
+		}
+	}
+	return stale, nil
+}
+
+func cleanupCommand(args []string, debug bool) error {
+	fs := flag.NewFlagSet("cleanup", flag.ExitOnError)
+	remove := fs.Bool("remove", false, "Remove the stale directives without asking")
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+	stale, err := staleDirectives(debug)
+	if err != nil {
+		return err
+	}
+	if len(stale) == 0 {
+		fmt.Println("No stale exclude or replace directive in go.mod")
+		return nil
+	}
+	drops := []string{}
+	for _, s := range stale {
+		fmt.Printf("%s\n  %s\n", s.text, s.reason)
+		if !*remove {
+			confirm := false
+			prompt := &survey.Confirm{Message: fmt.Sprintf("Remove %s?", s.text)}
+			if err := ask(prompt, &confirm); err != nil {
+				return err
+			}
+			if !confirm {
+				continue
+			}
+		}
+		drops = append(drops, s.drop)
+	}
+	if len(drops) == 0 {
+		return nil
+	}
+	if err := checkWritable(""); err != nil {
+		return err
+	}
+	lock, err := acquireLock()
+	if err != nil {
+		return err
+	}
+	defer lock.release()
+	args = append([]string{"mod", "edit"}, drops...)
+	if out, err := goCommand(args...).CombinedOutput(); err != nil {
+		return fmt.Errorf("go %s: %v: %s", strings.Join(args, " "), err, strings.TrimSpace(string(out)))
+	}
+	fmt.Printf("Removed %d directive(s) from go.mod\n", len(drops))
+	return nil
+}
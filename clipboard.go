@@ -0,0 +1,64 @@
+package main
+
+import (
+	"bytes"
+	"encoding/base64"
+	"errors"
+	"os"
+	"os/exec"
+	"runtime"
+	"strings"
+)
+
+// clipboardCommands are the platform clipboards, the first one found being
+// used
+func clipboardCommands() [][]string {
+	switch runtime.GOOS {
+	case "darwin":
+		return [][]string{{"pbcopy"}}
+	case "windows":
+		return [][]string{{"clip"}}
+	}
+	commands := [][]string{}
+	if os.Getenv("WAYLAND_DISPLAY") != "" {
+		commands = append(commands, []string{"wl-copy"})
+	}
+	if os.Getenv("DISPLAY") != "" {
+		commands = append(commands, []string{"xclip", "-selection", "clipboard"}, []string{"xsel", "--clipboard", "--input"})
+	}
+	return commands
+}
+
+// osc52 is the escape sequence setting the clipboard of the terminal, which
+// tmux only forwards wrapped in a passthrough sequence
+func osc52(text []byte) string {
+	seq := "\x1b]52;c;" + base64.StdEncoding.EncodeToString(text) + "\a"
+	if os.Getenv("TMUX") != "" {
+		seq = "\x1bPtmux;" + strings.Replace(seq, "\x1b", "\x1b\x1b", -1) + "\x1b\\"
+	}
+	return seq
+}
+
+// copyToClipboard copies the text with the platform clipboard, or through
+// the terminal with OSC 52 over SSH or when none is available
+func copyToClipboard(text []byte) error {
+	if os.Getenv("SSH_TTY") == "" {
+		for _, command := range clipboardCommands() {
+			if _, err := exec.LookPath(command[0]); err != nil {
+				continue
+			}
+			cmd := exec.Command(command[0], command[1:]...)
+			cmd.Stdin = bytes.NewReader(text)
+			if err := cmd.Run(); err == nil {
+				return nil
+			}
+		}
+	}
+	tty, err := os.OpenFile("/dev/tty", os.O_WRONLY, 0)
+	if err != nil {
+		return errors.New("no clipboard nor terminal to copy to")
+	}
+	defer tty.Close()
+	_, err = tty.WriteString(osc52(text))
+	return err
+}
@@ -0,0 +1,132 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// batch is a set of updates committed together
+type batch struct {
+	name    string
+	modules []Module
+}
+
+// splitBatches isolates each major upgrade in its own batch, after the
+// batch of the other upgrades, so that risky changes get small diffs
+func splitBatches(modules []Module) []batch {
+	others := batch{name: "minor-patch-" + time.Now().Format("20060102")}
+	majors := []batch{}
+	for _, x := range modules {
+		if x.severity == SeverityMajor {
+			majors = append(majors, batch{
+				// The full path, as modules may share their last element
+				name:    escapePath(x.name) + "-" + x.toVersion,
+				modules: []Module{x},
+			})
+			continue
+		}
+		others.modules = append(others.modules, x)
+	}
+	batches := []batch{}
+	if len(others.modules) > 0 {
+		batches = append(batches, others)
+	}
+	return append(batches, majors...)
+}
+
+// title names the updates of the batch which were applied
+func (b batch) title(applied []Module) string {
+	if len(applied) == 1 {
+		return fmt.Sprintf("Update %s to %s", applied[0].name, applied[0].toVersion)
+	}
+	return fmt.Sprintf("Update %d modules", len(applied))
+}
+
+func (b batch) message(applied []Module) string {
+	lines := []string{b.title(applied), ""}
+	for _, x := range applied {
+		lines = append(lines, fmt.Sprintf("- %s %s -> %s (%s)", x.name, x.fromVersion, x.toVersion, x.severity))
+	}
+	return strings.Join(lines, "\n")
+}
+
+func git(args ...string) (string, error) {
+	out, err := exec.Command("git", args...).CombinedOutput()
+	if err != nil {
+		return "", fmt.Errorf("git %s: %v: %s", args[0], err, strings.TrimSpace(string(out)))
+	}
+	return strings.TrimSpace(string(out)), nil
+}
+
+// commitFiles lists the files changed by updating the module directories
+func commitFiles(modules []Module) []string {
+	files := []string{}
+	for _, dir := range moduleDirsOf(modules) {
+		candidates := []string{filepath.Join(dir, "go.mod"), filepath.Join(dir, "go.sum"), filepath.Join(dir, "vendor")}
+		if modFile != "" {
+			candidates[0], candidates[1] = modFile, sumFile(modFile)
+		}
+		for _, file := range candidates {
+			if _, err := os.Stat(file); err == nil {
+				files = append(files, file)
+			}
+		}
+	}
+	return files
+}
+
+// applyBatches applies the updates batch by batch, committing each one. With
+// pullRequests, each batch is applied on its own branch from the current one,
+// pushed and proposed with the GitHub CLI.
+func applyBatches(modules []Module, events *eventLog, failFast, pullRequests bool) ([]Module, error) {
+	base, err := git("rev-parse", "--abbrev-ref", "HEAD")
+	if err != nil {
+		return nil, err
+	}
+	all := []Module{}
+	for _, b := range splitBatches(modules) {
+		branch := "go-mod-upgrade/" + b.name
+		if pullRequests {
+			if _, err := git("checkout", "-b", branch, base); err != nil {
+				return all, err
+			}
+		}
+		applied, err := apply(b.modules, events, failFast)
+		all = append(all, applied...)
+		if err != nil {
+			return all, err
+		}
+		if len(applied) > 0 {
+			args := append([]string{"add", "--"}, commitFiles(applied)...)
+			if _, err := git(args...); err != nil {
+				return all, err
+			}
+			args = append([]string{"commit", "-m", b.message(applied), "--"}, commitFiles(applied)...)
+			if _, err := git(args...); err != nil {
+				return all, err
+			}
+			fmt.Printf("Committed: %s\n", b.title(applied))
+		}
+		if !pullRequests {
+			continue
+		}
+		if len(applied) > 0 {
+			if _, err := git("push", "-u", "origin", branch); err != nil {
+				return all, err
+			}
+			out, err := exec.Command("gh", "pr", "create", "--base", base, "--head", branch, "--title", b.title(applied), "--body", b.message(applied)).CombinedOutput()
+			if err != nil {
+				return all, fmt.Errorf("gh pr create: %v: %s", err, strings.TrimSpace(string(out)))
+			}
+			fmt.Printf("Opened %s", out)
+		}
+		if _, err := git("checkout", base); err != nil {
+			return all, err
+		}
+	}
+	return all, nil
+}
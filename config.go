@@ -0,0 +1,438 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/Masterminds/semver/v3"
+	"gopkg.in/yaml.v3"
+)
+
+// configName is the configuration file, looked up next to go.mod and in
+// its parent directories
+const configName = ".go-mod-upgrade.yaml"
+
+type config struct {
+	Ignore    []ignoreRule     `yaml:"ignore,omitempty"`
+	Groups    []group          `yaml:"groups,omitempty"`
+	Policy    *policyConfig    `yaml:"policy,omitempty"`
+	Providers []providerConfig `yaml:"providers,omitempty"`
+	// Allowlist is the file or URL of the approved versions
+	Allowlist string `yaml:"allowlist,omitempty"`
+	// UpdateCommand is the command template applying an update
+	UpdateCommand string `yaml:"update-command,omitempty"`
+	// PostUpdate are commands run after applying updates, e.g. Gazelle
+	PostUpdate []string `yaml:"post-update,omitempty"`
+	// Pins are managed by the pin command
+	Pins  []pin        `yaml:"pins,omitempty"`
+	Audit *auditConfig `yaml:"audit,omitempty"`
+	// RequireReasons makes reasons mandatory for ignores and pins
+	RequireReasons bool `yaml:"require-reasons,omitempty"`
+	// StepChecks are the commands checking each step with --step
+	StepChecks []string `yaml:"step-checks,omitempty"`
+	// Analyzer is the command whose findings --analyze compares before and
+	// after each upgrade
+	Analyzer string `yaml:"analyzer,omitempty"`
+	// Enrichment tunes the workers and rate limits of --enrich
+	Enrichment *enrichConfig `yaml:"enrichment,omitempty"`
+	// Theme is a preset of colors, which Colors overrides by severity
+	Theme  string            `yaml:"theme,omitempty"`
+	Colors map[string]string `yaml:"colors,omitempty"`
+	// FirstParty are the patterns of the modules of the own organization,
+	// detected from the main module path when empty
+	FirstParty []string `yaml:"first-party,omitempty"`
+	// SelectFirstParty preselects the first-party modules in the picker
+	SelectFirstParty bool `yaml:"select-first-party,omitempty"`
+	// DependencyTrack is where the SBOM written with --sbom is uploaded
+	DependencyTrack *dependencyTrackConfig `yaml:"dependency-track,omitempty"`
+	// Hook is the policy of --hook
+	Hook *hookConfig `yaml:"hook,omitempty"`
+	// Jira is where the jira command files tickets
+	Jira *jiraConfig `yaml:"jira,omitempty"`
+	// MajorIssues templates the issues opened with --major-issues
+	MajorIssues *issueConfig `yaml:"major-issues,omitempty"`
+	// Profiles are selected with --profile
+	Profiles map[string]profile `yaml:"profiles,omitempty"`
+}
+
+// ignoreRule hides the updates of the modules matching path, optionally only
+// for some target versions (semver constraints) or update types
+type ignoreRule struct {
+	Path        string     `yaml:"path"`
+	Versions    []string   `yaml:"versions,omitempty"`
+	UpdateTypes []Severity `yaml:"update-types,omitempty"`
+	// Until snoozes the module until a date (2006-01-02) instead of ignoring
+	// it forever
+	Until  string `yaml:"until,omitempty"`
+	Reason string `yaml:"reason,omitempty"`
+}
+
+// expired tells whether the snooze of the rule is over
+func (r ignoreRule) expired(now time.Time) bool {
+	if r.Until == "" {
+		return false
+	}
+	until, err := time.ParseInLocation("2006-01-02", r.Until, time.Local)
+	return err == nil && !now.Before(until)
+}
+
+// group gathers the modules matching one of the patterns in the picker
+type group struct {
+	Name     string   `yaml:"name"`
+	Patterns []string `yaml:"patterns"`
+}
+
+func configFile() (string, error) {
+	root, err := projectRoot()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(root, configName), nil
+}
+
+// configFiles returns the configuration files of the current module, the
+// outermost first: the ones of the parent directories, up to the root of the
+// repository or workspace, provide defaults to the one next to go.mod
+func configFiles() ([]string, error) {
+	root, err := projectRoot()
+	if err != nil {
+		return nil, err
+	}
+	files := []string{}
+	for dir := root; ; {
+		file := filepath.Join(dir, configName)
+		if _, err := os.Stat(file); err == nil {
+			files = append([]string{file}, files...)
+		}
+		if isTopLevel(dir) {
+			break
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			break
+		}
+		dir = parent
+	}
+	return files, nil
+}
+
+// isTopLevel tells whether dir is the root of a repository or workspace
+func isTopLevel(dir string) bool {
+	for _, name := range []string{".git", "go.work"} {
+		if _, err := os.Stat(filepath.Join(dir, name)); err == nil {
+			return true
+		}
+	}
+	return false
+}
+
+// loadConfig reads the configuration of the current module, which is empty
+// when there is no configuration file
+func loadConfig() (*config, error) {
+	files, err := configFiles()
+	if err != nil {
+		return nil, err
+	}
+	if len(files) == 0 {
+		return &config{}, nil
+	}
+	cfg, err := readConfig(files...)
+	if err != nil {
+		return nil, err
+	}
+	if profileName != "" {
+		p, err := cfg.profile(profileName)
+		if err != nil {
+			return nil, err
+		}
+		cfg.Ignore = append(cfg.Ignore, p.Ignore...)
+	}
+	return cfg, nil
+}
+
+// mergeNodes overrides the settings of base with the ones of override,
+// merging the mappings key by key and replacing the other values
+func mergeNodes(base, override *yaml.Node) *yaml.Node {
+	if base.Kind != yaml.MappingNode || override.Kind != yaml.MappingNode {
+		return override
+	}
+	merged := &yaml.Node{Kind: yaml.MappingNode, Tag: base.Tag, Content: append([]*yaml.Node{}, base.Content...)}
+	for i := 0; i+1 < len(override.Content); i += 2 {
+		key, value := override.Content[i], override.Content[i+1]
+		found := false
+		for j := 0; j+1 < len(merged.Content); j += 2 {
+			if merged.Content[j].Value == key.Value {
+				merged.Content[j+1] = mergeNodes(merged.Content[j+1], value)
+				found = true
+				break
+			}
+		}
+		if !found {
+			merged.Content = append(merged.Content, key, value)
+		}
+	}
+	return merged
+}
+
+// readConfig reads and checks the configuration files, each one overriding
+// the settings of the previous ones
+func readConfig(files ...string) (*config, error) {
+	merged := &yaml.Node{Kind: yaml.MappingNode}
+	for _, file := range files {
+		data, err := ioutil.ReadFile(file)
+		if err != nil {
+			return nil, err
+		}
+		var doc yaml.Node
+		if err := yaml.Unmarshal(data, &doc); err != nil {
+			return nil, fmt.Errorf("%s: %v", file, err)
+		}
+		if len(doc.Content) == 0 {
+			continue
+		}
+		if err := doc.Decode(&config{}); err != nil {
+			return nil, fmt.Errorf("%s: %v", file, err)
+		}
+		merged = mergeNodes(merged, doc.Content[0])
+	}
+	cfg := &config{}
+	if err := merged.Decode(cfg); err != nil {
+		return nil, err
+	}
+	file := strings.Join(files, ", ")
+	rules := cfg.Ignore
+	for _, p := range cfg.Profiles {
+		rules = append(rules[:len(rules):len(rules)], p.Ignore...)
+	}
+	for _, rule := range rules {
+		if _, err := time.Parse("2006-01-02", rule.Until); rule.Until != "" && err != nil {
+			return nil, fmt.Errorf("%s: invalid until date %q for %s, expected YYYY-MM-DD", file, rule.Until, rule.Path)
+		}
+		if cfg.RequireReasons && rule.Reason == "" {
+			return nil, fmt.Errorf("%s: the ignore rule of %s needs a reason", file, rule.Path)
+		}
+	}
+	for _, p := range cfg.Pins {
+		if cfg.RequireReasons && p.Reason == "" {
+			return nil, fmt.Errorf("%s: the pin of %s needs a reason", file, p.Path)
+		}
+	}
+	return cfg, nil
+}
+
+func (c *config) save(file string) error {
+	var buf bytes.Buffer
+	enc := yaml.NewEncoder(&buf)
+	enc.SetIndent(2)
+	if err := enc.Encode(c); err != nil {
+		return err
+	}
+	return ioutil.WriteFile(file, buf.Bytes(), 0644)
+}
+
+// filter removes the ignored modules and the ones denied by the policy, and
+// sorts the others by group, the first-party modules first
+func (c *config) filter(modules []Module, verbose bool) ([]Module, error) {
+	firstParty := c.firstPartyPatterns()
+	if c.Policy != nil {
+		if err := c.Policy.prefetch(modules); err != nil {
+			return nil, err
+		}
+	}
+	kept := []Module{}
+	for _, x := range modules {
+		if x.held != "" {
+			if verbose {
+				verbosef(x.name, "Skipping, %s", x.held)
+			}
+			explanations.hide(x.dir, x.name, "%s", x.held)
+			continue
+		}
+		if p := c.pinned(x); p != nil {
+			if verbose && p.Reason != "" {
+				verbosef(x.name, "Holding at %s: %s", p.Version, p.Reason)
+			} else if verbose {
+				verbosef(x.name, "Holding at %s", p.Version)
+			}
+			explanations.hide(x.dir, x.name, "pinned at %s by the configuration%s", p.Version, formatReason(p.Reason))
+			continue
+		}
+		if rule := c.ignoreRule(x); rule != nil {
+			if verbose && rule.Reason != "" {
+				verbosef(x.name, "Ignoring, from %s to %s: %s", x.fromVersion, x.toVersion, rule.Reason)
+			} else if verbose {
+				verbosef(x.name, "Ignoring, from %s to %s", x.fromVersion, x.toVersion)
+			}
+			if rule.Until != "" {
+				explanations.hide(x.dir, x.name, "snoozed until %s by the ignore rule %s, %s available%s", rule.Until, rule.Path, x.toVersion, formatReason(rule.Reason))
+			} else {
+				explanations.hide(x.dir, x.name, "ignored by the rule %s of the configuration, %s available%s", rule.Path, x.toVersion, formatReason(rule.Reason))
+			}
+			continue
+		}
+		if c.Policy != nil {
+			decision, err := c.Policy.evaluate(x)
+			if err != nil {
+				return nil, err
+			}
+			if decision == policyDeny {
+				if verbose {
+					verbosef(x.name, "Denied by the policy, from %s to %s", x.fromVersion, x.toVersion)
+				}
+				explanations.hide(x.dir, x.name, "upgrade from %s to %s denied by the policy", x.fromVersion, x.toVersion)
+				continue
+			}
+			x.review = decision == policyReview
+		}
+		x.snoozeExpired = c.snoozeExpired(x)
+		x.group = c.group(x)
+		if x.group == "" && matchesAny(firstParty, x.name) {
+			x.group = firstPartyGroup
+			x.preselected = c.SelectFirstParty
+		}
+		kept = append(kept, x)
+	}
+	// Grouped modules come first, in the order of the groups
+	rank := func(m Module) int {
+		if m.group == firstPartyGroup {
+			return -1
+		}
+		for i, g := range c.Groups {
+			if g.Name == m.group {
+				return i
+			}
+		}
+		return len(c.Groups)
+	}
+	sort.SliceStable(kept, func(i, j int) bool {
+		return rank(kept[i]) < rank(kept[j])
+	})
+	return kept, nil
+}
+
+// ignoreRule returns the rule ignoring the module, if any
+func (c *config) ignoreRule(m Module) *ignoreRule {
+	now := time.Now()
+	for i, rule := range c.Ignore {
+		if rule.matches(m) && !rule.expired(now) {
+			return &c.Ignore[i]
+		}
+	}
+	return nil
+}
+
+// heldBack annotates the modules held back by a pin or an ignore rule with
+// the reason, if any, along with the replaced modules
+func (c *config) heldBack(modules []Module) []Module {
+	held := []Module{}
+	for _, x := range modules {
+		if p := c.pinned(x); x.held == "" && p != nil {
+			x.held = "pinned at " + p.Version
+			if p.Reason != "" {
+				x.held += ": " + p.Reason
+			}
+		} else if r := c.ignoreRule(x); x.held == "" && r != nil {
+			x.held = "ignored"
+			if r.Until != "" {
+				x.held = "snoozed until " + r.Until
+			}
+			if r.Reason != "" {
+				x.held += ": " + r.Reason
+			}
+		}
+		if x.held != "" {
+			held = append(held, x)
+		}
+	}
+	return held
+}
+
+// snoozeExpired tells whether the module was snoozed by a rule which is over
+func (c *config) snoozeExpired(m Module) bool {
+	now := time.Now()
+	for _, rule := range c.Ignore {
+		if rule.matches(m) && rule.expired(now) {
+			return true
+		}
+	}
+	return false
+}
+
+func (r ignoreRule) matches(m Module) bool {
+	if !matchPattern(r.Path, m.name) {
+		return false
+	}
+	if len(r.UpdateTypes) > 0 && !containsSeverity(r.UpdateTypes, m.severity) {
+		return false
+	}
+	if len(r.Versions) == 0 {
+		return true
+	}
+	if m.to == nil {
+		return false
+	}
+	for _, v := range r.Versions {
+		constraint, err := semver.NewConstraint(v)
+		if err == nil && constraint.Check(m.to) {
+			return true
+		}
+	}
+	return false
+}
+
+func (c *config) group(m Module) string {
+	for _, g := range c.Groups {
+		for _, p := range g.Patterns {
+			if matchPattern(p, m.name) {
+				return g.Name
+			}
+		}
+	}
+	return ""
+}
+
+// matchesAny reports whether name matches one of the patterns
+func matchesAny(patterns []string, name string) bool {
+	for _, p := range patterns {
+		if matchPattern(p, name) {
+			return true
+		}
+	}
+	return false
+}
+
+// matchPattern reports whether name matches pattern, where * matches any
+// sequence of characters, including slashes
+func matchPattern(pattern, name string) bool {
+	parts := strings.Split(pattern, "*")
+	for i := range parts {
+		parts[i] = regexp.QuoteMeta(parts[i])
+	}
+	re, err := regexp.Compile("^" + strings.Join(parts, ".*") + "$")
+	return err == nil && re.MatchString(name)
+}
+
+func contains(list []string, s string) bool {
+	for _, x := range list {
+		if x == s {
+			return true
+		}
+	}
+	return false
+}
+
+func containsSeverity(list []Severity, s Severity) bool {
+	for _, x := range list {
+		if x == s {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,340 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"reflect"
+	"strings"
+
+	"github.com/Masterminds/semver/v3"
+	"gopkg.in/yaml.v3"
+)
+
+// starterConfig is written by config init, every setting commented out
+const starterConfig = `# Configuration of go-mod-upgrade, see
+# https://github.com/oligot/go-mod-upgrade#configuration
+
+# Hide the updates of matching modules, * matches any characters
+# ignore:
+#   - path: golang.org/x/*
+#   # Optionally only for some target versions or update types
+#   - path: github.com/fatih/color
+#     versions: [">= 2.0"]
+#     update-types: [major]
+#   # Or until a date (YYYY-MM-DD), with the reason shown in the held back list
+#   - path: github.com/aws/aws-sdk-go
+#     until: 2025-09-01
+#     reason: waiting for the v2 migration
+
+# Gather matching modules in the list
+# groups:
+#   - name: aws
+#     patterns: [github.com/aws/*]
+
+# Modules of the organization, shown first, detected from the main module path
+# first-party: [github.com/myorg/*]
+# select-first-party: true
+
+# Reject ignore rules and pins without a reason
+# require-reasons: true
+
+# Command deciding allow, deny or require-review for each upgrade given on stdin
+# policy:
+#   command: opa eval --stdin-input --format raw --data upgrade.rego data.upgrade.decision
+
+# File or URL of the approved versions
+# allowlist: https://example.com/go-approved.yaml
+
+# Command template applying an update
+# update-command: go get {{.Args}} {{.Path}}@{{.To}}
+
+# Commands run after applying updates, and checking each step with --step
+# post-update: [bazel run //:gazelle]
+# step-checks: [go build ./..., go test ./...]
+
+# Analyzer run before and after each upgrade with --analyze, go vet ./... by
+# default
+# analyzer: staticcheck ./...
+
+# Workers and requests per second by host of --enrich, 10 by default
+# enrichment:
+#   workers: 8
+#   rate-limits:
+#     github.com: 1
+
+# Audit trail of the applied updates
+# audit:
+#   file: upgrades-audit.jsonl
+#   commit: true
+
+# Tracking issues opened with --major-issues
+# major-issues:
+#   title: "Upgrade {{.Path}} to {{.To}}"
+#   labels: [dependencies]
+
+# Dependency-Track server receiving the SBOM written with --sbom, API key in
+# DT_API_KEY
+# dependency-track:
+#   url: https://dtrack.example.com
+#   project: my-service
+#   project-version: main
+
+# Policy of --hook: fail, warn or ignore each check
+# hook:
+#   vulnerable: fail
+#   stale: warn
+#   max-libyear: 1
+
+# Tickets filed by the jira command, credentials in JIRA_USER and JIRA_TOKEN
+# jira:
+#   url: https://example.atlassian.net
+#   project: OPS
+#   labels: [dependencies]
+#   categories: [security, major]
+
+# Self-hosted code hosts: github, gitlab, bitbucket or gitea
+# providers:
+#   - host: git.example.com
+#     type: gitlab
+#     token-env: EXAMPLE_GITLAB_TOKEN
+
+# Flags and ignore rules selected with --profile
+# profiles:
+#   nightly:
+#     flags:
+#       only-security: true
+#       quiet: true
+#   weekly:
+#     flags:
+#       pr: true
+#       max-updates: 10
+#     ignore:
+#       - path: golang.org/x/*
+#         update-types: [major]
+
+# Colors of the list: default, colorblind or monochrome, overridden by severity
+# theme: colorblind
+# colors:
+#   major: bold hi-red
+`
+
+// unknownKeys walks the YAML nodes along the type they decode to, and
+// reports the keys matching no field, with the closest known key
+func unknownKeys(node *yaml.Node, t reflect.Type) []string {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if node.Kind == yaml.DocumentNode {
+		problems := []string{}
+		for _, n := range node.Content {
+			problems = append(problems, unknownKeys(n, t)...)
+		}
+		return problems
+	}
+	problems := []string{}
+	switch {
+	case t.Kind() == reflect.Struct && node.Kind == yaml.MappingNode:
+		fields := map[string]reflect.Type{}
+		names := []string{}
+		for i := 0; i < t.NumField(); i++ {
+			name := strings.Split(t.Field(i).Tag.Get("yaml"), ",")[0]
+			if name != "" && name != "-" {
+				fields[name] = t.Field(i).Type
+				names = append(names, name)
+			}
+		}
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			key, value := node.Content[i], node.Content[i+1]
+			field, ok := fields[key.Value]
+			if !ok {
+				problem := fmt.Sprintf("line %d: unknown key %q", key.Line, key.Value)
+				if suggestion := closest(key.Value, names); suggestion != "" {
+					problem += fmt.Sprintf(", did you mean %q?", suggestion)
+				}
+				problems = append(problems, problem)
+				continue
+			}
+			problems = append(problems, unknownKeys(value, field)...)
+		}
+	case t.Kind() == reflect.Slice && node.Kind == yaml.SequenceNode:
+		for _, n := range node.Content {
+			problems = append(problems, unknownKeys(n, t.Elem())...)
+		}
+	case t.Kind() == reflect.Map && node.Kind == yaml.MappingNode:
+		for i := 1; i < len(node.Content); i += 2 {
+			problems = append(problems, unknownKeys(node.Content[i], t.Elem())...)
+		}
+	}
+	return problems
+}
+
+// closest returns the name nearest to the key, empty when none is close
+// enough to be a typo
+func closest(key string, names []string) string {
+	best, bestDistance := "", len(key)/2+1
+	for _, name := range names {
+		if d := editDistance(key, name); d < bestDistance {
+			best, bestDistance = name, d
+		}
+	}
+	return best
+}
+
+// editDistance is the Levenshtein distance between a and b
+func editDistance(a, b string) int {
+	previous := make([]int, len(b)+1)
+	for j := range previous {
+		previous[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		current := make([]int, len(b)+1)
+		current[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			current[j] = min3(previous[j]+1, current[j-1]+1, previous[j-1]+cost)
+		}
+		previous = current
+	}
+	return previous[len(b)]
+}
+
+func min3(a, b, c int) int {
+	if b < a {
+		a = b
+	}
+	if c < a {
+		a = c
+	}
+	return a
+}
+
+// validateConfig reports the problems of a configuration file: syntax
+// errors, unknown keys, and settings the tool would refuse
+func validateConfig(file string) ([]string, error) {
+	data, err := ioutil.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+	var root yaml.Node
+	if err := yaml.Unmarshal(data, &root); err != nil {
+		return []string{err.Error()}, nil
+	}
+	problems := unknownKeys(&root, reflect.TypeOf(config{}))
+	cfg, err := readConfig(file)
+	if err != nil {
+		return append(problems, strings.TrimPrefix(err.Error(), file+": ")), nil
+	}
+	for _, rule := range cfg.Ignore {
+		for _, v := range rule.Versions {
+			if _, err := semver.NewConstraint(v); err != nil {
+				problems = append(problems, fmt.Sprintf("invalid version constraint %q for %s: %v", v, rule.Path, err))
+			}
+		}
+	}
+	for name, p := range cfg.Profiles {
+		for key := range p.Flags {
+			if key == "profile" || flag.Lookup(key) == nil {
+				problems = append(problems, fmt.Sprintf("profile %s: unknown flag %q", name, key))
+			}
+		}
+	}
+	if err := applyTheme(cfg.Theme, cfg.Colors); err != nil {
+		problems = append(problems, err.Error())
+	}
+	if _, err := newProviders(cfg.Providers, ""); err != nil {
+		problems = append(problems, err.Error())
+	}
+	if cfg.Policy != nil && strings.TrimSpace(cfg.Policy.Command) == "" {
+		problems = append(problems, "the policy has no command")
+	}
+	if cfg.UpdateCommand != "" {
+		updateCommand = cfg.UpdateCommand
+		if _, err := updateArgs(updateTarget{Path: "example.com/m", To: "v1.0.0"}); err != nil {
+			problems = append(problems, err.Error())
+		}
+	}
+	return problems, nil
+}
+
+func configCommand(args []string) error {
+	usage := errors.New("usage: go-mod-upgrade config init [--force] | validate [file] | show [--effective]")
+	if len(args) == 0 {
+		return usage
+	}
+	switch args[0] {
+	case "init":
+		fs := flag.NewFlagSet("config init", flag.ExitOnError)
+		force := fs.Bool("force", false, "Overwrite an existing configuration")
+		if err := parseFlags(fs, args[1:]); err != nil {
+			return err
+		}
+		file, err := configFile()
+		if err != nil {
+			return err
+		}
+		if _, err := os.Stat(file); err == nil && !*force {
+			return fmt.Errorf("%s already exists, use --force to overwrite it", file)
+		}
+		if err := ioutil.WriteFile(file, []byte(starterConfig), 0644); err != nil {
+			return err
+		}
+		fmt.Printf("Wrote %s\n", file)
+		return nil
+	case "validate":
+		file := ""
+		if len(args) > 1 {
+			file = args[1]
+		} else {
+			var err error
+			if file, err = configFile(); err != nil {
+				return err
+			}
+		}
+		problems, err := validateConfig(file)
+		if err != nil {
+			return err
+		}
+		if len(problems) == 0 {
+			fmt.Printf("%s is valid\n", file)
+			return nil
+		}
+		for _, p := range problems {
+			fmt.Printf("%s: %s\n", file, p)
+		}
+		return fmt.Errorf("%d problem(s) found", len(problems))
+	case "show":
+		fs := flag.NewFlagSet("config show", flag.ExitOnError)
+		effective := fs.Bool("effective", false, "Show the configuration merged with the ones of the parent directories")
+		if err := parseFlags(fs, args[1:]); err != nil {
+			return err
+		}
+		files, err := configFiles()
+		if err != nil {
+			return err
+		}
+		if len(files) == 0 {
+			fmt.Println("No configuration file found")
+			return nil
+		}
+		if !*effective {
+			files = files[len(files)-1:]
+		}
+		cfg, err := readConfig(files...)
+		if err != nil {
+			return err
+		}
+		for _, file := range files {
+			fmt.Printf("# %s\n", file)
+		}
+		enc := yaml.NewEncoder(os.Stdout)
+		enc.SetIndent(2)
+		return enc.Encode(cfg)
+	}
+	return usage
+}
@@ -0,0 +1,160 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"runtime/debug"
+	"time"
+)
+
+// dependencySnapshot is a snapshot of the dependency submission API, see
+// https://docs.github.com/en/rest/dependency-graph/dependency-submission
+type dependencySnapshot struct {
+	Version   int                 `json:"version"`
+	SHA       string              `json:"sha"`
+	Ref       string              `json:"ref"`
+	Job       snapshotJob         `json:"job"`
+	Detector  snapshotDetector    `json:"detector"`
+	Scanned   time.Time           `json:"scanned"`
+	Manifests map[string]manifest `json:"manifests"`
+}
+
+type snapshotJob struct {
+	Correlator string `json:"correlator"`
+	ID         string `json:"id"`
+}
+
+type snapshotDetector struct {
+	Name    string `json:"name"`
+	Version string `json:"version"`
+	URL     string `json:"url"`
+}
+
+type manifest struct {
+	Name     string                        `json:"name"`
+	File     map[string]string             `json:"file"`
+	Resolved map[string]resolvedDependency `json:"resolved"`
+}
+
+type resolvedDependency struct {
+	PackageURL   string `json:"package_url"`
+	Relationship string `json:"relationship"`
+}
+
+var githubRemote = regexp.MustCompile(`github\.com[:/]([^/]+/[^/]+?)(\.git)?/?$`)
+
+// githubRepository returns the owner/repo of the repository, from
+// GITHUB_REPOSITORY in GitHub Actions or else from the origin remote
+func githubRepository() (string, error) {
+	if repo := os.Getenv("GITHUB_REPOSITORY"); repo != "" {
+		return repo, nil
+	}
+	remote, err := git("remote", "get-url", "origin")
+	if err != nil {
+		return "", err
+	}
+	m := githubRemote.FindStringSubmatch(remote)
+	if m == nil {
+		return "", fmt.Errorf("the origin remote %s is not a GitHub repository", remote)
+	}
+	return m[1], nil
+}
+
+// toolVersion is the version of the module the tool was built from
+func toolVersion() string {
+	if info, ok := debug.ReadBuildInfo(); ok {
+		return info.Main.Version
+	}
+	return "(devel)"
+}
+
+// buildSnapshot lists the build list of the module as the manifest of its
+// go.mod, relative to the root of the repository
+func buildSnapshot(debug bool) (*dependencySnapshot, error) {
+	list, err := goList("", debug, "list", "-mod=mod", "-json", "-m", "all")
+	if err != nil {
+		return nil, err
+	}
+	gomod, err := goModFile()
+	if err != nil {
+		return nil, err
+	}
+	top, err := git("rev-parse", "--show-toplevel")
+	if err != nil {
+		return nil, err
+	}
+	goMod, err := filepath.Rel(top, gomod)
+	if err != nil {
+		return nil, err
+	}
+	goMod = filepath.ToSlash(goMod)
+	sha := os.Getenv("GITHUB_SHA")
+	if sha == "" {
+		if sha, err = git("rev-parse", "HEAD"); err != nil {
+			return nil, err
+		}
+	}
+	ref := os.Getenv("GITHUB_REF")
+	if ref == "" {
+		if ref, err = git("symbolic-ref", "HEAD"); err != nil {
+			return nil, errors.New("HEAD is detached, set GITHUB_REF to the ref of the snapshot")
+		}
+	}
+	id := os.Getenv("GITHUB_RUN_ID")
+	if id == "" {
+		id = fmt.Sprint(time.Now().Unix())
+	}
+	resolved := map[string]resolvedDependency{}
+	for _, m := range list {
+		if m.Main {
+			continue
+		}
+		relationship := "direct"
+		if m.Indirect {
+			relationship = "indirect"
+		}
+		if m.Replace != nil && m.Replace.Version != "" {
+			m = *m.Replace
+		}
+		if m.Version == "" {
+			continue
+		}
+		resolved[m.Path] = resolvedDependency{PackageURL: purl(m.Path, m.Version), Relationship: relationship}
+	}
+	return &dependencySnapshot{
+		SHA:      sha,
+		Ref:      ref,
+		Job:      snapshotJob{Correlator: "go-mod-upgrade " + goMod, ID: id},
+		Detector: snapshotDetector{Name: "go-mod-upgrade", Version: toolVersion(), URL: "https://github.com/oligot/go-mod-upgrade"},
+		Scanned:  time.Now().UTC(),
+		Manifests: map[string]manifest{
+			goMod: {Name: goMod, File: map[string]string{"source_location": goMod}, Resolved: resolved},
+		},
+	}, nil
+}
+
+// submitDependencies pushes the dependencies of the module to the dependency
+// graph of its GitHub repository, for the alerts of the modules GitHub does
+// not detect itself
+func submitDependencies(token string, debug bool) error {
+	repo, err := githubRepository()
+	if err != nil {
+		return err
+	}
+	snapshot, err := buildSnapshot(debug)
+	if err != nil {
+		return err
+	}
+	gh := newGithubProvider("github.com", "", token)
+	var result struct {
+		Result string `json:"result"`
+	}
+	if err := gh.api.post(fmt.Sprintf("/repos/%s/dependency-graph/snapshots", repo), snapshot, &result); err != nil {
+		return fmt.Errorf("dependency submission: %w", err)
+	}
+	progress("Submitted the dependencies to the dependency graph of %s (%s)", repo, result.Result)
+	return nil
+}
@@ -0,0 +1,241 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"net/http"
+	"os"
+	"os/exec"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/fatih/color"
+	"golang.org/x/crypto/ssh/terminal"
+)
+
+// Outcome of a doctor check
+const (
+	checkOK   = "ok"
+	checkWarn = "warn"
+	checkFail = "fail"
+)
+
+// diagnosis is the result of a doctor check, with a fix when it isn't ok
+type diagnosis struct {
+	name   string
+	status string
+	detail string
+	fix    string
+}
+
+var goVersionPattern = regexp.MustCompile(`go(\d+)\.(\d+)`)
+
+func checkGo() diagnosis {
+	out, err := goCommand("version").Output()
+	if err != nil {
+		return diagnosis{"go", checkFail, err.Error(), "install Go from https://go.dev/dl and add it to your PATH"}
+	}
+	version := strings.TrimPrefix(strings.TrimSpace(string(out)), "go version ")
+	m := goVersionPattern.FindStringSubmatch(version)
+	if m == nil {
+		return diagnosis{"go", checkWarn, version, "the version couldn't be parsed, make sure `go` is the official toolchain"}
+	}
+	major, _ := strconv.Atoi(m[1])
+	minor, _ := strconv.Atoi(m[2])
+	if major == 1 && minor < 14 {
+		return diagnosis{"go", checkFail, version, "upgrade to Go 1.14 or later"}
+	}
+	return diagnosis{"go", checkOK, version, ""}
+}
+
+func checkProxies(goproxy string, client *http.Client) []diagnosis {
+	if goproxy == "" {
+		goproxy = "https://proxy.golang.org,direct"
+	}
+	results := []diagnosis{}
+	for _, proxy := range strings.FieldsFunc(goproxy, func(r rune) bool { return r == ',' || r == '|' }) {
+		name := "GOPROXY " + proxy
+		switch {
+		case proxy == "direct":
+			results = append(results, diagnosis{name, checkOK, "modules are fetched from version control", ""})
+		case proxy == "off":
+			results = append(results, diagnosis{name, checkWarn, "module downloads are disabled",
+				"use --offline to only look at the module cache, or `go env -w GOPROXY=https://proxy.golang.org,direct`"})
+		case strings.HasPrefix(proxy, "file://"):
+			if _, err := os.Stat(strings.TrimPrefix(proxy, "file://")); err != nil {
+				results = append(results, diagnosis{name, checkFail, err.Error(), "fix the directory in `go env GOPROXY`"})
+			} else {
+				results = append(results, diagnosis{name, checkOK, "directory found", ""})
+			}
+		default:
+			resp, err := client.Get(proxy)
+			if err != nil {
+				results = append(results, diagnosis{name, checkFail, err.Error(),
+					"check your network connection and HTTPS_PROXY, or set another proxy with `go env -w GOPROXY=...`"})
+				continue
+			}
+			resp.Body.Close()
+			if resp.StatusCode >= 500 {
+				results = append(results, diagnosis{name, checkFail, resp.Status, "the proxy is failing, retry later or set another one with `go env -w GOPROXY=...`"})
+			} else {
+				results = append(results, diagnosis{name, checkOK, "reachable", ""})
+			}
+		}
+	}
+	return results
+}
+
+func checkSumDB() diagnosis {
+	db, err := newSumDB()
+	if err != nil {
+		return diagnosis{"GOSUMDB", checkFail, err.Error(), "check `go env GOSUMDB`"}
+	}
+	if db.disabled {
+		return diagnosis{"GOSUMDB", checkWarn, "off, downloads aren't verified",
+			"list private modules in GOPRIVATE instead, then `go env -u GOSUMDB`"}
+	}
+	resp, err := db.client.Get(db.url + "/latest")
+	if err != nil {
+		return diagnosis{"GOSUMDB " + db.name, checkFail, err.Error(),
+			"check your network connection, private modules must be listed in GOPRIVATE or GONOSUMDB"}
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return diagnosis{"GOSUMDB " + db.name, checkFail, resp.Status, "check the URL in `go env GOSUMDB`"}
+	}
+	return diagnosis{"GOSUMDB " + db.name, checkOK, "reachable", ""}
+}
+
+// checkPrivate reports the GOPRIVATE patterns which match no requirement of
+// go.mod, often a typo leading to failing proxy or checksum lookups
+func checkPrivate(goprivate string) diagnosis {
+	patterns := []string{}
+	for _, p := range strings.Split(goprivate, ",") {
+		if p = strings.TrimSpace(p); p != "" {
+			patterns = append(patterns, p)
+		}
+	}
+	if len(patterns) == 0 {
+		return diagnosis{"GOPRIVATE", checkOK, "not set, every module is public", ""}
+	}
+	required, err := requirements("")
+	if err != nil {
+		return diagnosis{"GOPRIVATE", checkOK, goprivate, ""}
+	}
+	unused := []string{}
+	for _, p := range patterns {
+		used := false
+		for path := range required {
+			if matchesPrefix([]string{p}, path) {
+				used = true
+				break
+			}
+		}
+		if !used {
+			unused = append(unused, p)
+		}
+	}
+	if len(unused) > 0 {
+		return diagnosis{"GOPRIVATE", checkWarn, fmt.Sprintf("%s matches no requirement of go.mod", strings.Join(unused, ", ")),
+			"check the spelling, patterns match leading path elements, as in example.com/org/*"}
+	}
+	return diagnosis{"GOPRIVATE", checkOK, goprivate, ""}
+}
+
+// checkGit looks for git credentials, needed to fetch private modules
+// directly from their repositories
+func checkGit(private bool) diagnosis {
+	status := checkWarn
+	if private {
+		status = checkFail
+	}
+	if _, err := exec.LookPath("git"); err != nil {
+		return diagnosis{"git", status, "not found", "install git, it is needed to fetch modules directly from their repositories"}
+	}
+	if out, _ := exec.Command("git", "config", "--get", "credential.helper").Output(); len(out) > 0 {
+		return diagnosis{"git", checkOK, "credential helper " + strings.TrimSpace(string(out)), ""}
+	}
+	if out, _ := exec.Command("git", "config", "--get-regexp", `^url\..*\.insteadof$`).Output(); len(out) > 0 {
+		return diagnosis{"git", checkOK, "URL rewrites configured", ""}
+	}
+	netrc := netrcFile()
+	if _, err := os.Stat(netrc); err == nil {
+		return diagnosis{"git", checkOK, "credentials in " + netrc, ""}
+	}
+	if !private {
+		return diagnosis{"git", checkOK, "no credentials, only public modules can be fetched", ""}
+	}
+	return diagnosis{"git", checkWarn, "no credentials for the private modules",
+		`configure a credential helper, a .netrc entry, or git config --global url."git@example.com:".insteadOf https://example.com/`}
+}
+
+func checkTerminal() diagnosis {
+	fd := int(os.Stdout.Fd())
+	if !terminal.IsTerminal(fd) {
+		return diagnosis{"terminal", checkWarn, "standard output isn't a terminal",
+			"run the interactive list in a terminal, or use --plan and apply --from-plan in scripts"}
+	}
+	width, err := terminalWidth()
+	if err != nil {
+		return diagnosis{"terminal", checkWarn, err.Error(), "the width is unknown, the current versions won't be shown"}
+	}
+	detail := fmt.Sprintf("%d columns", width)
+	if color.NoColor {
+		detail += ", no colors"
+	}
+	if os.Getenv("TERM") == "dumb" {
+		return diagnosis{"terminal", checkWarn, detail + ", TERM=dumb", "use a terminal supporting cursor movements, the list redraws itself"}
+	}
+	if width < 80 {
+		return diagnosis{"terminal", checkWarn, detail, "widen the terminal to at least 80 columns to show the current versions"}
+	}
+	return diagnosis{"terminal", checkOK, detail, ""}
+}
+
+func doctorCommand(args []string) error {
+	fs := flag.NewFlagSet("doctor", flag.ExitOnError)
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+	results := []diagnosis{checkGo()}
+	if d := checkToolchain(); d != nil {
+		results = append(results, *d)
+	}
+	var env map[string]string
+	out, err := goCommand("env", "-json", "GOPROXY", "GOPRIVATE").Output()
+	if err == nil {
+		err = json.Unmarshal(out, &env)
+	}
+	if err != nil {
+		results = append(results, diagnosis{"go env", checkFail, err.Error(), "fix the settings reported by `go env`"})
+	} else {
+		client := &http.Client{Timeout: 10 * time.Second}
+		results = append(results, checkProxies(env["GOPROXY"], client)...)
+		results = append(results, checkSumDB(), checkPrivate(env["GOPRIVATE"]), checkGit(env["GOPRIVATE"] != ""))
+	}
+	results = append(results, checkTerminal())
+
+	failed := 0
+	for _, d := range results {
+		switch d.status {
+		case checkOK:
+			fmt.Fprintf(color.Output, "%s %s: %s\n", color.GreenString("✓"), d.name, d.detail)
+		case checkWarn:
+			fmt.Fprintf(color.Output, "%s %s: %s\n", color.YellowString("!"), d.name, d.detail)
+		default:
+			failed++
+			fmt.Fprintf(color.Output, "%s %s: %s\n", color.RedString("✗"), d.name, d.detail)
+		}
+		if d.fix != "" {
+			fmt.Printf("    Fix: %s\n", d.fix)
+		}
+	}
+	if failed > 0 {
+		return errors.New("doctor: some checks failed")
+	}
+	return nil
+}
@@ -0,0 +1,206 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"flag"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/AlecAivazis/survey/v2"
+)
+
+// duplicateMajor is a module required at several major versions, which are
+// different modules for the go command
+type duplicateMajor struct {
+	base string
+	// paths are sorted by major version
+	paths    []string
+	versions map[string]string
+	// why is the shortest import chain from the main module to each path
+	why map[string][]string
+}
+
+// majorOf splits a module path into the path without its major version
+// suffix and the major version, v1 when there is none
+func majorOf(path string) (string, int) {
+	if strings.HasPrefix(path, "gopkg.in/") {
+		if i := strings.LastIndex(path, ".v"); i > 0 {
+			if n, err := strconv.Atoi(path[i+2:]); err == nil {
+				return path[:i], n
+			}
+		}
+		return path, 1
+	}
+	if i := strings.LastIndex(path, "/"); i > 0 && majorSuffix.MatchString(path[i+1:]) {
+		n, _ := strconv.Atoi(path[i+2:])
+		return path[:i], n
+	}
+	return path, 1
+}
+
+// importChains runs go mod why on the module paths, returning the import
+// chain of each, empty when the main module doesn't import it
+func importChains(paths []string) (map[string][]string, error) {
+	args := append([]string{"mod", "why", "-m"}, paths...)
+	out, err := goCommand(args...).Output()
+	if err != nil {
+		return nil, newGoError(args, err)
+	}
+	chains := map[string][]string{}
+	current := ""
+	scanner := bufio.NewScanner(bytes.NewReader(out))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		switch {
+		case strings.HasPrefix(line, "# "):
+			current = strings.TrimPrefix(line, "# ")
+			chains[current] = []string{}
+		case line == "" || strings.HasPrefix(line, "("):
+		default:
+			chains[current] = append(chains[current], line)
+		}
+	}
+	return chains, scanner.Err()
+}
+
+// findDuplicateMajors lists the modules required at several major versions
+func findDuplicateMajors(debug bool) ([]duplicateMajor, error) {
+	list, err := goList("", debug, "list", "-mod=mod", "-json", "-m", "all")
+	if err != nil {
+		return nil, err
+	}
+	byBase := map[string]*duplicateMajor{}
+	for _, m := range list {
+		if m.Main {
+			continue
+		}
+		base, _ := majorOf(m.Path)
+		d, ok := byBase[base]
+		if !ok {
+			d = &duplicateMajor{base: base, versions: map[string]string{}}
+			byBase[base] = d
+		}
+		d.paths = append(d.paths, m.Path)
+		d.versions[m.Path] = m.Version
+	}
+	duplicates := []duplicateMajor{}
+	paths := []string{}
+	for _, d := range byBase {
+		if len(d.paths) < 2 {
+			continue
+		}
+		sort.Slice(d.paths, func(i, j int) bool {
+			_, a := majorOf(d.paths[i])
+			_, b := majorOf(d.paths[j])
+			return a < b
+		})
+		paths = append(paths, d.paths...)
+		duplicates = append(duplicates, *d)
+	}
+	sort.Slice(duplicates, func(i, j int) bool {
+		return duplicates[i].base < duplicates[j].base
+	})
+	if len(duplicates) == 0 {
+		return duplicates, nil
+	}
+	chains, err := importChains(paths)
+	if err != nil {
+		return nil, err
+	}
+	for i, d := range duplicates {
+		duplicates[i].why = map[string][]string{}
+		for _, path := range d.paths {
+			duplicates[i].why[path] = chains[path]
+		}
+	}
+	return duplicates, nil
+}
+
+func duplicatesCommand(args []string, debug bool) error {
+	fs := flag.NewFlagSet("duplicates", flag.ExitOnError)
+	migrate := fs.Bool("migrate", false, "Migrate the imports of the main module to the highest major version without asking")
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+	duplicates, err := findDuplicateMajors(debug)
+	if err != nil {
+		return err
+	}
+	if len(duplicates) == 0 {
+		fmt.Println("No module is required at several major versions")
+		return nil
+	}
+	type migration struct{ old, new string }
+	migrations := []migration{}
+	for _, d := range duplicates {
+		fmt.Println(d.base)
+		highest := d.paths[len(d.paths)-1]
+		for _, path := range d.paths {
+			_, major := majorOf(path)
+			fmt.Printf("  v%-3d %s %s\n", major, path, d.versions[path])
+			chain := d.why[path]
+			switch {
+			case len(chain) == 0:
+				fmt.Println("       not imported, only required in the module graph")
+			case len(chain) == 2:
+				fmt.Printf("       imported by %s\n", chain[0])
+			default:
+				fmt.Printf("       imported through %s\n", strings.Join(chain, " → "))
+			}
+			if path == highest || len(chain) == 0 {
+				continue
+			}
+			if len(chain) == 2 {
+				migrations = append(migrations, migration{path, highest})
+			} else {
+				fmt.Printf("       upgrading the dependency providing %s may drop it\n", chain[1])
+			}
+		}
+	}
+	if len(migrations) == 0 {
+		return nil
+	}
+	for _, m := range migrations {
+		if !*migrate {
+			confirm := false
+			prompt := &survey.Confirm{
+				Message: fmt.Sprintf("Migrate the imports of %s to %s?", m.old, m.new),
+			}
+			if err := ask(prompt, &confirm); err != nil {
+				return err
+			}
+			if !confirm {
+				continue
+			}
+		}
+		if err := checkWritable(""); err != nil {
+			return err
+		}
+		root, err := projectRoot()
+		if err != nil {
+			return err
+		}
+		lock, err := acquireLock()
+		if err != nil {
+			return err
+		}
+		files, err := rewriteImports(root, m.old, m.new)
+		if err != nil {
+			lock.release()
+			return err
+		}
+		printRewritten(root, files)
+		if out, err := goCommand("mod", "tidy").CombinedOutput(); err != nil {
+			lock.release()
+			return fmt.Errorf("go mod tidy: %v: %s", err, strings.TrimSpace(string(out)))
+		}
+		if out, err := goCommand("build", "./...").CombinedOutput(); err != nil {
+			fmt.Printf("The build fails after the migration, the API of %s differs:\n%s\n", m.new, strings.TrimSpace(string(out)))
+		}
+		lock.release()
+	}
+	return nil
+}
@@ -0,0 +1,303 @@
+package main
+
+import (
+	"fmt"
+	"net/url"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	defaultEnrichWorkers = 8
+	// defaultHostRate is the number of requests per second sent to a host
+	// without a configured limit
+	defaultHostRate = 10.0
+	// pendingValue stands for the columns still being fetched
+	pendingValue = "..."
+)
+
+// enrichColumns are the columns --enrich fills in
+var enrichColumns = []string{"released", "notes", "vulns", "license"}
+
+// enrichConfig tunes the fetches of --enrich
+type enrichConfig struct {
+	Workers int `yaml:"workers,omitempty"`
+	// RateLimits are the requests per second by host, e.g. github.com: 1
+	RateLimits map[string]float64 `yaml:"rate-limits,omitempty"`
+}
+
+// enrichTask fetches the value of a column of a module from a host
+type enrichTask struct {
+	index  int
+	column string
+	host   string
+	fetch  func() (string, error)
+}
+
+type enrichResult struct {
+	index  int
+	column string
+	value  string
+}
+
+// rateLimiter spaces the requests to each host, and gives up on the hosts
+// whose API rate limit is exceeded
+type rateLimiter struct {
+	mu        sync.Mutex
+	rates     map[string]float64
+	next      map[string]time.Time
+	exhausted map[string]bool
+}
+
+func newRateLimiter(rates map[string]float64) *rateLimiter {
+	return &rateLimiter{rates: rates, next: map[string]time.Time{}, exhausted: map[string]bool{}}
+}
+
+// wait blocks until a request may be sent to the host, returning false
+// when the host is exhausted
+func (l *rateLimiter) wait(host string) bool {
+	l.mu.Lock()
+	if l.exhausted[host] {
+		l.mu.Unlock()
+		return false
+	}
+	rate, ok := l.rates[host]
+	if !ok || rate <= 0 {
+		rate = defaultHostRate
+	}
+	now := time.Now()
+	slot := l.next[host]
+	if slot.Before(now) {
+		slot = now
+	}
+	l.next[host] = slot.Add(time.Duration(float64(time.Second) / rate))
+	l.mu.Unlock()
+	time.Sleep(time.Until(slot))
+	return true
+}
+
+func (l *rateLimiter) exhaust(host string) {
+	l.mu.Lock()
+	l.exhausted[host] = true
+	l.mu.Unlock()
+}
+
+// enricher fetches the release dates, release notes, fixed vulnerabilities
+// and licenses of the updates in the background, while the picker is shown
+type enricher struct {
+	workers int
+	limiter *rateLimiter
+	hosts   *providers
+	vulns   *vulnDB
+	proxy   string
+	// cancelled stops the fetches once the selection is made
+	cancelled chan struct{}
+
+	mu sync.Mutex
+	// skipped counts the fetches skipped by host once its rate limit is
+	// exceeded, failed the other errors by column
+	skipped map[string]int
+	failed  map[string]int
+}
+
+func newEnricher(cfg *enrichConfig, hosts *providers) *enricher {
+	e := &enricher{
+		workers:   defaultEnrichWorkers,
+		hosts:     hosts,
+		vulns:     newVulnDB(),
+		proxy:     proxyHost(),
+		cancelled: make(chan struct{}),
+		skipped:   map[string]int{},
+		failed:    map[string]int{},
+	}
+	rates := map[string]float64{}
+	if cfg != nil {
+		if cfg.Workers > 0 {
+			e.workers = cfg.Workers
+		}
+		rates = cfg.RateLimits
+	}
+	e.limiter = newRateLimiter(rates)
+	return e
+}
+
+// proxyHost returns the host of the first proxy of GOPROXY, empty when the
+// modules are fetched directly
+func proxyHost() string {
+	out, err := goCommand("env", "GOPROXY").Output()
+	if err != nil {
+		return ""
+	}
+	first := strings.FieldsFunc(strings.TrimSpace(string(out)), func(r rune) bool { return r == ',' || r == '|' })
+	if len(first) == 0 {
+		return ""
+	}
+	u, err := url.Parse(first[0])
+	if err != nil {
+		return ""
+	}
+	return u.Host
+}
+
+// hostOf returns the host of the module path
+func hostOf(path string) string {
+	return strings.SplitN(path, "/", 2)[0]
+}
+
+// tasks returns the fetches of the columns of each module
+func (e *enricher) tasks(modules []Module) []enrichTask {
+	vulnHost := ""
+	if u, err := url.Parse(e.vulns.url); err == nil {
+		vulnHost = u.Host
+	}
+	tasks := []enrichTask{}
+	for i, x := range modules {
+		x := x
+		proxy := e.proxy
+		if proxy == "" {
+			proxy = hostOf(x.name)
+		}
+		tasks = append(tasks,
+			enrichTask{i, "released", proxy, func() (string, error) { return releaseDate(x) }},
+			enrichTask{i, "notes", hostOf(x.name), func() (string, error) { return e.notes(x) }},
+			enrichTask{i, "vulns", vulnHost, func() (string, error) { return e.fixedVulns(x) }},
+			enrichTask{i, "license", proxy, func() (string, error) { return moduleLicense(x.name, x.toVersion) }},
+		)
+	}
+	return tasks
+}
+
+// start fans the fetches out to the workers, and returns their results,
+// closed once they are all done. Failed fetches show as ?, the ones of an
+// exhausted host stay empty.
+func (e *enricher) start(modules []Module) <-chan enrichResult {
+	tasks := e.tasks(modules)
+	queue := make(chan enrichTask)
+	results := make(chan enrichResult, len(tasks))
+	var wg sync.WaitGroup
+	for w := 0; w < e.workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for t := range queue {
+				results <- enrichResult{t.index, t.column, e.run(t)}
+			}
+		}()
+	}
+	go func() {
+		for _, t := range tasks {
+			queue <- t
+		}
+		close(queue)
+		wg.Wait()
+		close(results)
+	}()
+	return results
+}
+
+// cancel skips the fetches not started yet
+func (e *enricher) cancel() {
+	close(e.cancelled)
+}
+
+func (e *enricher) run(t enrichTask) string {
+	select {
+	case <-e.cancelled:
+		return ""
+	default:
+	}
+	if !e.limiter.wait(t.host) {
+		e.count(e.skipped, t.host)
+		return ""
+	}
+	value, err := t.fetch()
+	if err == errRateLimited {
+		e.limiter.exhaust(t.host)
+		e.count(e.skipped, t.host)
+		return ""
+	} else if err != nil {
+		e.count(e.failed, t.column)
+		return "?"
+	}
+	return value
+}
+
+func (e *enricher) count(counts map[string]int, key string) {
+	e.mu.Lock()
+	counts[key]++
+	e.mu.Unlock()
+}
+
+// report prints what couldn't be fetched
+func (e *enricher) report() {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	for _, host := range sortedKeys(e.skipped) {
+		fmt.Println(tr("API rate limit of %s exceeded, skipped %d lookups", host, e.skipped[host]))
+	}
+	for _, column := range sortedKeys(e.failed) {
+		fmt.Println(tr("Failed to fetch %d %s values", e.failed[column], column))
+	}
+}
+
+func sortedKeys(counts map[string]int) []string {
+	keys := []string{}
+	for k := range counts {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// releaseDate returns the day the target version was published
+func releaseDate(m Module) (string, error) {
+	key := m.name + "@" + m.toVersion
+	var date string
+	if metadata.load("released", key, never, &date) {
+		return date, nil
+	}
+	list, err := goList(m.dir, false, "list", "-m", "-json", key)
+	if err != nil {
+		return "", err
+	}
+	if len(list) == 0 || list[0].Time == nil {
+		return "", nil
+	}
+	date = list[0].Time.Format("2006-01-02")
+	metadata.store("released", key, date)
+	return date, nil
+}
+
+// notes summarizes the release notes of the update
+func (e *enricher) notes(m Module) (string, error) {
+	c, err := fetchChangelog(e.hosts, m)
+	if err != nil || c == nil {
+		return "", err
+	}
+	parts := []string{}
+	if c.Archived {
+		parts = append(parts, "archived")
+	}
+	if len(c.Breaking) > 0 {
+		parts = append(parts, fmt.Sprintf("%d breaking", len(c.Breaking)))
+	}
+	if len(c.Deprecated) > 0 {
+		parts = append(parts, fmt.Sprintf("%d deprecated", len(c.Deprecated)))
+	}
+	return strings.Join(parts, ", "), nil
+}
+
+// fixedVulns counts the known vulnerabilities of the current version which
+// the target one fixes
+func (e *enricher) fixedVulns(m Module) (string, error) {
+	if m.fromVersion == "" {
+		return "", nil
+	}
+	n, err := e.vulns.fixedBy(m.name, m.fromVersion, m.toVersion)
+	if err != nil || n == 0 {
+		return "", err
+	}
+	return fmt.Sprintf("fixes %d", n), nil
+}
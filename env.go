@@ -0,0 +1,55 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// envPrefix starts the environment variables setting the flags, which the
+// command line overrides, and which override the configuration
+const envPrefix = "GO_MOD_UPGRADE_"
+
+// envAliases name the environment variables of the one letter flags
+var envAliases = map[string]string{
+	"p": "page-size",
+	"v": "verbose",
+	"r": "recursive",
+}
+
+// envVariable returns the environment variable of a flag, as
+// GO_MOD_UPGRADE_MAX_UPDATES for --max-updates, prefixed with the subcommand
+// for its flags, as GO_MOD_UPGRADE_LIST_JSON for list --json
+func envVariable(command, name string) string {
+	if alias, ok := envAliases[name]; ok && command == "" {
+		name = alias
+	}
+	if command != "" {
+		name = command + "_" + name
+	}
+	return envPrefix + strings.ToUpper(strings.NewReplacer("-", "_", " ", "_").Replace(name))
+}
+
+// setFlagsFromEnv sets the flags of fs from their environment variables
+func setFlagsFromEnv(fs *flag.FlagSet, command string) error {
+	var err error
+	fs.VisitAll(func(f *flag.Flag) {
+		name := envVariable(command, f.Name)
+		if value, ok := os.LookupEnv(name); ok && err == nil {
+			if serr := fs.Set(f.Name, value); serr != nil {
+				err = fmt.Errorf("%s: %v", name, serr)
+			}
+		}
+	})
+	return err
+}
+
+// parseFlags parses the flags of a subcommand, defaulting to their
+// environment variables
+func parseFlags(fs *flag.FlagSet, args []string) error {
+	if err := setFlagsFromEnv(fs, fs.Name()); err != nil {
+		return err
+	}
+	return fs.Parse(args)
+}
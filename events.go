@@ -0,0 +1,71 @@
+package main
+
+import (
+	"encoding/json"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// event is a single line of the --events NDJSON stream.
+type event struct {
+	SchemaVersion string    `json:"schemaVersion"`
+	Event         string    `json:"event"`
+	Time          time.Time `json:"time"`
+	Module        string    `json:"module,omitempty"`
+	From          string    `json:"from,omitempty"`
+	To            string    `json:"to,omitempty"`
+	Severity      string    `json:"severity,omitempty"`
+	Dir           string    `json:"dir,omitempty"`
+	Error         string    `json:"error,omitempty"`
+}
+
+type eventLog struct {
+	// mu serializes the events of concurrent discoveries
+	mu  sync.Mutex
+	enc *json.Encoder
+}
+
+// openEvents opens the events stream, target being either a file
+// descriptor number (e.g. 3) or a file path.
+func openEvents(target string) (*eventLog, error) {
+	if target == "" {
+		return nil, nil
+	}
+	if fd, err := strconv.Atoi(target); err == nil {
+		return &eventLog{enc: json.NewEncoder(os.NewFile(uintptr(fd), "events"))}, nil
+	}
+	f, err := os.OpenFile(target, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return nil, err
+	}
+	return &eventLog{enc: json.NewEncoder(f)}, nil
+}
+
+func (l *eventLog) emit(e event) {
+	if l == nil {
+		return
+	}
+	e.SchemaVersion = schemaVersion
+	e.Time = time.Now()
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	// Events are best effort, a broken pipe must not stop the upgrade
+	_ = l.enc.Encode(e)
+}
+
+func (l *eventLog) moduleEvent(name string, module Module, err error) {
+	e := event{
+		Event:    name,
+		Module:   module.name,
+		From:     module.fromVersion,
+		To:       module.toVersion,
+		Severity: module.severity.String(),
+		Dir:      module.dir,
+	}
+	if err != nil {
+		e.Error = err.Error()
+	}
+	l.emit(e)
+}
@@ -0,0 +1,113 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/Masterminds/semver/v3"
+)
+
+// moduleVersion is a module path with an optional version, as in go mod
+// edit -json
+type moduleVersion struct {
+	Path    string
+	Version string
+}
+
+func (m moduleVersion) String() string {
+	if m.Version == "" {
+		return m.Path
+	}
+	return m.Path + "@" + m.Version
+}
+
+// directives are the exclude and replace directives of a go.mod file
+type directives struct {
+	Exclude []moduleVersion
+	Replace []struct {
+		Old moduleVersion
+		New moduleVersion
+	}
+}
+
+// readDirectives returns the directives of the go.mod file of dir
+func readDirectives(dir string) (*directives, error) {
+	args := []string{"mod", "edit", "-json"}
+	out, err := goCommandIn(dir, args...).Output()
+	if err != nil {
+		return nil, newGoError(args, err)
+	}
+	d := &directives{}
+	if err := json.Unmarshal(out, d); err != nil {
+		return nil, fmt.Errorf("%w: %v", errParse, err)
+	}
+	return d, nil
+}
+
+// goModExcludes returns the versions excluded by the go.mod file of dir, by
+// module path
+func goModExcludes(dir string) (map[string][]string, error) {
+	d, err := readDirectives(dir)
+	if err != nil {
+		return nil, err
+	}
+	excludes := map[string][]string{}
+	for _, e := range d.Exclude {
+		excludes[e.Path] = append(excludes[e.Path], e.Version)
+	}
+	return excludes, nil
+}
+
+// newerExcluded returns the excluded versions newer than the version, the
+// highest first. The go command never upgrades to them.
+func newerExcluded(excluded []string, version string) []string {
+	current, err := semver.NewVersion(version)
+	if err != nil {
+		return nil
+	}
+	newer := []*semver.Version{}
+	raw := map[*semver.Version]string{}
+	for _, v := range excluded {
+		parsed, err := semver.NewVersion(v)
+		if err == nil && parsed.GreaterThan(current) {
+			newer = append(newer, parsed)
+			raw[parsed] = v
+		}
+	}
+	sort.Slice(newer, func(i, j int) bool {
+		return newer[i].GreaterThan(newer[j])
+	})
+	versions := []string{}
+	for _, v := range newer {
+		versions = append(versions, raw[v])
+	}
+	return versions
+}
+
+// formatExcluded describes the versions skipped because they are excluded
+func formatExcluded(versions []string) string {
+	return strings.Join(versions, ", ") + " excluded by go.mod"
+}
+
+// excludeTarget adds an exclude directive of the target version of the
+// module to go.mod, in the directory of each member of an aggregated row
+func excludeTarget(m Module) error {
+	members := m.members
+	if members == nil {
+		members = []Module{m}
+	}
+	for _, x := range members {
+		args := []string{"mod", "edit", "-exclude=" + x.name + "@" + x.toVersion}
+		if out, err := goCommandIn(x.dir, args...).CombinedOutput(); err != nil {
+			return fmt.Errorf("go %s: %v: %s", strings.Join(args, " "), err, strings.TrimSpace(string(out)))
+		}
+		if x.dir != "" {
+			fmt.Println(tr("Excluded %s@%s in the go.mod of %s", x.name, x.toVersion, x.dir))
+		} else {
+			fmt.Println(tr("Excluded %s@%s in go.mod", x.name, x.toVersion))
+		}
+	}
+	return nil
+}
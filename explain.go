@@ -0,0 +1,159 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/Masterminds/semver/v3"
+)
+
+// explanation is why a module is shown in the picker or hidden
+type explanation struct {
+	dir    string
+	path   string
+	shown  bool
+	reason string
+}
+
+// explainLog records the decisions about each module of the build list with
+// --explain, the discovery of the workspace members running concurrently
+type explainLog struct {
+	mu      sync.Mutex
+	entries map[string]*explanation
+}
+
+// explanations is nil unless --explain is given
+var explanations *explainLog
+
+func newExplainLog() *explainLog {
+	return &explainLog{entries: map[string]*explanation{}}
+}
+
+// hide records why the module of dir is hidden, replacing what was recorded
+// by the earlier steps
+func (l *explainLog) hide(dir, path, format string, args ...interface{}) {
+	if l == nil {
+		return
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.entries[dir+"\x00"+path] = &explanation{dir: dir, path: path, reason: tr(format, args...)}
+}
+
+// upToDate records the modules without update, telling the newer versions
+// the go command doesn't offer apart
+func (l *explainLog) upToDate(dir string, modules []goModule, debug bool) {
+	if l == nil || len(modules) == 0 {
+		return
+	}
+	args := []string{"list", "-e", "-mod=mod", "-json", "-m", "-versions"}
+	for _, m := range modules {
+		args = append(args, m.Path)
+	}
+	versions := map[string][]string{}
+	// Without the versions, the modules are only known to be up to date
+	if list, err := goList(dir, debug, args...); err == nil {
+		for _, m := range list {
+			versions[m.Path] = m.Versions
+		}
+	}
+	for _, m := range modules {
+		l.hide(dir, m.Path, "%s", upToDateReason(m.Version, versions[m.Path]))
+	}
+}
+
+// upToDateReason explains why there is no update from the version, among
+// the known versions of the module
+func upToDateReason(current string, versions []string) string {
+	from, err := semver.NewVersion(current)
+	if err != nil {
+		return tr("up to date")
+	}
+	prereleases := []string{}
+	releases := []string{}
+	for _, v := range versions {
+		version, err := semver.NewVersion(v)
+		if err != nil || !version.GreaterThan(from) {
+			continue
+		}
+		if version.Prerelease() != "" {
+			prereleases = append(prereleases, v)
+		} else {
+			releases = append(releases, v)
+		}
+	}
+	switch {
+	case len(releases) > 0:
+		return tr("up to date, newer versions retracted: %s", strings.Join(releases, ", "))
+	case len(prereleases) > 0:
+		return tr("up to date, prerelease %s filtered out, prereleases are only offered from a prerelease", prereleases[len(prereleases)-1])
+	}
+	return tr("up to date")
+}
+
+// formatReason appends the reason given in the configuration, if any
+func formatReason(reason string) string {
+	if reason == "" {
+		return ""
+	}
+	return ": " + reason
+}
+
+// print lists the explanations, the modules left being the shown ones
+func (l *explainLog) print(modules []Module) {
+	if l == nil {
+		return
+	}
+	for _, x := range modules {
+		members := x.members
+		if len(members) == 0 {
+			members = []Module{x}
+		}
+		for _, m := range members {
+			reason := tr("%s upgrade from %s to %s", m.severity, m.fromVersion, m.toVersion)
+			if x.review {
+				reason += tr(", review required by the policy")
+			}
+			if x.snoozeExpired {
+				reason += tr(", snooze expired")
+			}
+			if c := x.columns[testDepsColumn]; c != "" {
+				reason += ", " + c
+			}
+			l.entries[m.dir+"\x00"+m.name] = &explanation{dir: m.dir, path: m.name, shown: true, reason: reason}
+		}
+	}
+	entries := []*explanation{}
+	maxPath := 0
+	for _, e := range l.entries {
+		entries = append(entries, e)
+		name := e.path
+		if e.dir != "" {
+			name += " (" + e.dir + ")"
+		}
+		maxPath = max(maxPath, len(name))
+	}
+	sort.Slice(entries, func(i, j int) bool {
+		if entries[i].shown != entries[j].shown {
+			return entries[i].shown
+		}
+		if entries[i].path != entries[j].path {
+			return entries[i].path < entries[j].path
+		}
+		return entries[i].dir < entries[j].dir
+	})
+	fmt.Println(tr("Why each module is shown or hidden:"))
+	for _, e := range entries {
+		name := e.path
+		if e.dir != "" {
+			name += " (" + e.dir + ")"
+		}
+		state := tr("hidden")
+		if e.shown {
+			state = tr("shown")
+		}
+		fmt.Printf("  %s %s: %s\n", padRight(name, maxPath), state, e.reason)
+	}
+}
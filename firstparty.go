@@ -0,0 +1,41 @@
+package main
+
+import (
+	"strings"
+)
+
+// firstPartyGroup is the group of the modules of the own organization,
+// shown before the others
+const firstPartyGroup = "internal"
+
+// orgPattern returns the pattern matching the modules of the organization
+// of the module path, as github.com/org/* for github.com/org/repo
+func orgPattern(path string) string {
+	parts := strings.Split(path, "/")
+	if len(parts) > 2 {
+		parts = parts[:2]
+	} else {
+		parts = parts[:1]
+	}
+	return strings.Join(parts, "/") + "/*"
+}
+
+// firstPartyPatterns returns the configured patterns of the first-party
+// modules, or else the organization of the main modules
+func (c *config) firstPartyPatterns() []string {
+	if len(c.FirstParty) > 0 {
+		return c.FirstParty
+	}
+	out, err := goCommand("list", "-m").Output()
+	if err != nil {
+		return nil
+	}
+	patterns := []string{}
+	for _, path := range strings.Fields(string(out)) {
+		// Without a domain, like example/m, there is no organization
+		if strings.Contains(strings.Split(path, "/")[0], ".") {
+			patterns = append(patterns, orgPattern(path))
+		}
+	}
+	return patterns
+}
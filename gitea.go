@@ -0,0 +1,59 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strings"
+)
+
+// giteaProvider also works for Forgejo, which shares the Gitea API
+type giteaProvider struct {
+	host string
+	api  *apiClient
+}
+
+// newGiteaProvider defaults the token to the GITEA_TOKEN environment
+// variable
+func newGiteaProvider(host, api, token string) *giteaProvider {
+	if token == "" {
+		token = os.Getenv("GITEA_TOKEN")
+	}
+	if api == "" {
+		api = "https://" + host + "/api/v1"
+	}
+	return &giteaProvider{
+		host: host,
+		api:  newAPIClient(strings.TrimSuffix(api, "/"), token, func(t string) string { return "token " + t }),
+	}
+}
+
+func (g *giteaProvider) releases(repo string) ([]release, error) {
+	// Gitea releases have the same shape as GitHub ones
+	var list []githubRelease
+	if err := g.api.get(fmt.Sprintf("/repos/%s/releases?limit=50", repo), &list); err != nil {
+		return nil, err
+	}
+	releases := []release{}
+	for _, r := range list {
+		if !r.Draft {
+			releases = append(releases, release{Tag: r.TagName, Notes: r.Body, URL: r.HTMLURL})
+		}
+	}
+	return releases, nil
+}
+
+func (g *giteaProvider) releasesURL(repo string) string {
+	return fmt.Sprintf("https://%s/%s/releases", g.host, repo)
+}
+
+func (g *giteaProvider) compareURL(repo, from, to string) string {
+	return fmt.Sprintf("https://%s/%s/compare/%s...%s", g.host, repo, from, to)
+}
+
+func (g *giteaProvider) archived(repo string) (bool, error) {
+	var r struct {
+		Archived bool `json:"archived"`
+	}
+	err := g.api.get("/repos/"+repo, &r)
+	return r.Archived, err
+}
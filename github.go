@@ -0,0 +1,74 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strings"
+)
+
+type githubProvider struct {
+	host string
+	api  *apiClient
+}
+
+type githubRelease struct {
+	TagName string `json:"tag_name"`
+	Body    string `json:"body"`
+	HTMLURL string `json:"html_url"`
+	Draft   bool   `json:"draft"`
+}
+
+// newGithubProvider defaults the token to the GITHUB_TOKEN and GH_TOKEN
+// environment variables, and the API of github.com to GITHUB_API_URL
+func newGithubProvider(host, api, token string) *githubProvider {
+	if token == "" {
+		token = os.Getenv("GITHUB_TOKEN")
+	}
+	if token == "" {
+		token = os.Getenv("GH_TOKEN")
+	}
+	if api == "" && host == "github.com" {
+		api = os.Getenv("GITHUB_API_URL")
+	}
+	if api == "" {
+		if host == "github.com" {
+			api = "https://api.github.com"
+		} else {
+			api = "https://" + host + "/api/v3"
+		}
+	}
+	return &githubProvider{
+		host: host,
+		api:  newAPIClient(strings.TrimSuffix(api, "/"), token, bearer),
+	}
+}
+
+func (g *githubProvider) releases(repo string) ([]release, error) {
+	var list []githubRelease
+	if err := g.api.get(fmt.Sprintf("/repos/%s/releases?per_page=100", repo), &list); err != nil {
+		return nil, err
+	}
+	releases := []release{}
+	for _, r := range list {
+		if !r.Draft {
+			releases = append(releases, release{Tag: r.TagName, Notes: r.Body, URL: r.HTMLURL})
+		}
+	}
+	return releases, nil
+}
+
+func (g *githubProvider) releasesURL(repo string) string {
+	return fmt.Sprintf("https://%s/%s/releases", g.host, repo)
+}
+
+func (g *githubProvider) compareURL(repo, from, to string) string {
+	return fmt.Sprintf("https://%s/%s/compare/%s...%s", g.host, repo, from, to)
+}
+
+func (g *githubProvider) archived(repo string) (bool, error) {
+	var r struct {
+		Archived bool `json:"archived"`
+	}
+	err := g.api.get("/repos/"+repo, &r)
+	return r.Archived, err
+}
@@ -0,0 +1,67 @@
+package main
+
+import (
+	"fmt"
+	"net/url"
+	"os"
+	"strings"
+)
+
+type gitlabProvider struct {
+	host string
+	api  *apiClient
+}
+
+type gitlabRelease struct {
+	TagName     string `json:"tag_name"`
+	Description string `json:"description"`
+	Links       struct {
+		Self string `json:"self"`
+	} `json:"_links"`
+}
+
+// newGitlabProvider defaults the token to the GITLAB_TOKEN environment
+// variable
+func newGitlabProvider(host, api, token string) *gitlabProvider {
+	if token == "" {
+		token = os.Getenv("GITLAB_TOKEN")
+	}
+	if api == "" {
+		api = "https://" + host + "/api/v4"
+	}
+	c := newAPIClient(strings.TrimSuffix(api, "/"), token, func(t string) string { return t })
+	c.authHeader = "PRIVATE-TOKEN"
+	return &gitlabProvider{host: host, api: c}
+}
+
+func (g *gitlabProvider) project(repo string) string {
+	return "/projects/" + url.PathEscape(repo)
+}
+
+func (g *gitlabProvider) releases(repo string) ([]release, error) {
+	var list []gitlabRelease
+	if err := g.api.get(g.project(repo)+"/releases?per_page=100", &list); err != nil {
+		return nil, err
+	}
+	releases := []release{}
+	for _, r := range list {
+		releases = append(releases, release{Tag: r.TagName, Notes: r.Description, URL: r.Links.Self})
+	}
+	return releases, nil
+}
+
+func (g *gitlabProvider) releasesURL(repo string) string {
+	return fmt.Sprintf("https://%s/%s/-/releases", g.host, repo)
+}
+
+func (g *gitlabProvider) compareURL(repo, from, to string) string {
+	return fmt.Sprintf("https://%s/%s/-/compare/%s...%s", g.host, repo, from, to)
+}
+
+func (g *gitlabProvider) archived(repo string) (bool, error) {
+	var p struct {
+		Archived bool `json:"archived"`
+	}
+	err := g.api.get(g.project(repo), &p)
+	return p.Archived, err
+}
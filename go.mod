@@ -6,5 +6,8 @@ require (
 	github.com/AlecAivazis/survey/v2 v2.0.5
 	github.com/Masterminds/semver/v3 v3.0.3
 	github.com/fatih/color v1.9.0
+	github.com/kballard/go-shellquote v0.0.0-20180428030007-95032a82bc51
 	golang.org/x/crypto v0.0.0-20190530122614-20be4c3c3ed5
+	golang.org/x/sys v0.0.0-20191026070338-33540a1f6037
+	gopkg.in/yaml.v3 v3.0.1
 )
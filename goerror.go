@@ -0,0 +1,144 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os/exec"
+	"strings"
+)
+
+// The errors of the go commands and updates, matched with errors.Is by the
+// command line only: they are not part of any importable API
+var (
+	// errNoGoMod is returned outside of a module
+	errNoGoMod = errors.New("not in a module, go.mod file not found")
+	// errProxyUnreachable matches the go commands which couldn't reach the
+	// module proxy
+	errProxyUnreachable = errors.New("the module proxy could not be reached")
+	// errParse wraps the errors of parsing the output of a go command
+	errParse = errors.New("couldn't parse the output of the go command")
+	// errUpdateFailed matches the updateError of any module
+	errUpdateFailed = errors.New("update failed")
+)
+
+// updateError is the failed update of a module, its message being the one of
+// the update command
+type updateError struct {
+	path string
+	from string
+	to   string
+	dir  string
+	err  error
+}
+
+func (e *updateError) Error() string {
+	return e.err.Error()
+}
+
+func (e *updateError) Unwrap() error {
+	return e.err
+}
+
+func (e *updateError) Is(target error) bool {
+	return target == errUpdateFailed
+}
+
+// goError is a failed go command along with what it printed on stderr
+type goError struct {
+	args   []string
+	stderr string
+	err    error
+}
+
+func newGoError(args []string, err error) error {
+	e := &goError{args: args, err: err}
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) {
+		e.stderr = strings.TrimSpace(string(exitErr.Stderr))
+	}
+	return e
+}
+
+func (e *goError) Error() string {
+	var b strings.Builder
+	b.WriteString(tr("go %s failed: %v", strings.Join(e.args, " "), e.err))
+	if e.stderr != "" {
+		fmt.Fprintf(&b, "\n%s", e.stderr)
+	}
+	if s := e.suggestion(); s != "" {
+		b.WriteString("\n\n" + tr("Hint: %s", translate(s)))
+	}
+	return b.String()
+}
+
+func (e *goError) Unwrap() error {
+	return e.err
+}
+
+// Is matches the sentinel errors by the output of the go command
+func (e *goError) Is(target error) bool {
+	switch target {
+	case errNoGoMod:
+		return containsAny(e.stderr, noGoModPatterns)
+	case errProxyUnreachable:
+		return containsAny(e.stderr, unreachablePatterns)
+	}
+	return false
+}
+
+var (
+	noGoModPatterns     = []string{"go.mod file not found", "cannot find main module", "not using modules"}
+	unreachablePatterns = []string{"dial tcp", "no such host", "i/o timeout", "connection refused", "TLS handshake timeout"}
+)
+
+func containsAny(s string, patterns []string) bool {
+	for _, p := range patterns {
+		if strings.Contains(s, p) {
+			return true
+		}
+	}
+	return false
+}
+
+var goErrorHints = []struct {
+	patterns []string
+	hint     string
+}{
+	{
+		noGoModPatterns,
+		"run go-mod-upgrade from a directory containing a go.mod file, or create one with `go mod init`",
+	},
+	{
+		[]string{"module lookup disabled by GOPROXY=off"},
+		"module downloads are disabled, use --offline to only look at the local module cache",
+	},
+	{
+		[]string{"invalid proxy URL", "GOPROXY list is not the empty string", "invalid GOPROXY"},
+		"check the GOPROXY setting with `go env GOPROXY`",
+	},
+	{
+		unreachablePatterns,
+		"the module proxy could not be reached, check your network connection and `go env GOPROXY`",
+	},
+	{
+		[]string{"reading file://"},
+		"the local module cache lacks some modules of the build, run `go mod download` once with network access to fill it",
+	},
+	{
+		[]string{"410 Gone", "404 Not Found", "terminal prompts disabled", "could not read Username"},
+		"private modules must be listed in GOPRIVATE (e.g. `go env -w GOPRIVATE=github.com/myorg/*`) with git credentials configured",
+	},
+	{
+		[]string{"SECURITY ERROR", "checksum mismatch", "verifying module"},
+		"go.sum verification failed, try `go clean -modcache` and make sure GONOSUMDB covers private modules",
+	},
+}
+
+func (e *goError) suggestion() string {
+	for _, h := range goErrorHints {
+		if containsAny(e.stderr, h.patterns) {
+			return h.hint
+		}
+	}
+	return ""
+}
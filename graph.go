@@ -0,0 +1,199 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+)
+
+// graphNode is a module of the exported graph
+type graphNode struct {
+	id         string
+	path       string
+	version    string
+	update     string
+	vulnerable bool
+}
+
+func (n graphNode) label() string {
+	label := n.path
+	if n.version != "" {
+		label += " " + n.version
+	}
+	if n.update != "" {
+		label += " → " + n.update
+	}
+	return label
+}
+
+// restrictEdges restricts the requirements of the module graph, as parent
+// paths by required path, to the paths leading to the nodes kept by lead
+func restrictEdges(nodes map[string]*graphNode, requiredBy map[string][]string, lead func(*graphNode) bool) map[string][]string {
+	kept := map[string]bool{}
+	queue := []string{}
+	for path, n := range nodes {
+		if lead(n) {
+			kept[path] = true
+			queue = append(queue, path)
+		}
+	}
+	for len(queue) > 0 {
+		path := queue[0]
+		queue = queue[1:]
+		for _, parent := range requiredBy[path] {
+			if !kept[parent] {
+				kept[parent] = true
+				queue = append(queue, parent)
+			}
+		}
+	}
+	edges := map[string][]string{}
+	for child, parents := range requiredBy {
+		if !kept[child] {
+			continue
+		}
+		for _, parent := range parents {
+			if kept[parent] {
+				edges[child] = append(edges[child], parent)
+			}
+		}
+	}
+	return edges
+}
+
+// writeDot writes the graph in the Graphviz format
+func writeDot(w io.Writer, nodes []*graphNode, edges [][2]*graphNode) {
+	fmt.Fprintln(w, "digraph modules {")
+	fmt.Fprintln(w, "  rankdir=LR;")
+	fmt.Fprintln(w, "  node [shape=box];")
+	for _, n := range nodes {
+		style := ""
+		switch {
+		case n.vulnerable:
+			style = `, style=filled, fillcolor="#fca5a5"`
+		case n.update != "":
+			style = `, style=filled, fillcolor="#fde68a"`
+		}
+		fmt.Fprintf(w, "  %s [label=%q%s];\n", n.id, n.label(), style)
+	}
+	for _, e := range edges {
+		fmt.Fprintf(w, "  %s -> %s;\n", e[0].id, e[1].id)
+	}
+	fmt.Fprintln(w, "}")
+}
+
+// writeMermaid writes the graph as a Mermaid flowchart
+func writeMermaid(w io.Writer, nodes []*graphNode, edges [][2]*graphNode) {
+	fmt.Fprintln(w, "graph LR")
+	fmt.Fprintln(w, "  classDef outdated fill:#fde68a")
+	fmt.Fprintln(w, "  classDef vulnerable fill:#fca5a5")
+	for _, n := range nodes {
+		fmt.Fprintf(w, "  %s[\"%s\"]\n", n.id, strings.Replace(n.label(), `"`, "#quot;", -1))
+		switch {
+		case n.vulnerable:
+			fmt.Fprintf(w, "  class %s vulnerable\n", n.id)
+		case n.update != "":
+			fmt.Fprintf(w, "  class %s outdated\n", n.id)
+		}
+	}
+	for _, e := range edges {
+		fmt.Fprintf(w, "  %s --> %s\n", e[0].id, e[1].id)
+	}
+}
+
+func graphCommand(args []string, debug bool) error {
+	fs := flag.NewFlagSet("graph", flag.ExitOnError)
+	format := fs.String("format", "dot", "Output format: dot or mermaid")
+	only := fs.String("only", "", "Restrict the graph to the paths leading to the outdated or vulnerable modules")
+	vulns := fs.Bool("vulns", false, "Highlight the modules with known vulnerabilities")
+	outputFile := fs.String("output-file", "", "Write the graph to a file, replaced atomically, or - for stdout")
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+	write := writeDot
+	switch *format {
+	case "dot":
+	case "mermaid":
+		write = writeMermaid
+	default:
+		return fmt.Errorf("graph: unknown format %q, expected dot or mermaid", *format)
+	}
+	switch *only {
+	case "", "outdated":
+	case "vulnerable":
+		*vulns = true
+	default:
+		return fmt.Errorf("graph: unknown restriction %q, expected outdated or vulnerable", *only)
+	}
+	list, err := goList("", debug, "list", "-mod=mod", "-u", "-json", "-m", "all")
+	if err != nil {
+		return err
+	}
+	selected := map[string]string{}
+	nodes := map[string]*graphNode{}
+	for _, m := range list {
+		n := &graphNode{path: m.Path, version: m.Version}
+		if m.Main {
+			nodes["main"] = n
+			continue
+		}
+		if m.Update != nil {
+			n.update = m.Update.Version
+		}
+		selected[m.Path] = m.Version
+		nodes[m.Path] = n
+	}
+	if *vulns {
+		fixes, unfixed, err := securityUpdates(debug)
+		if err != nil {
+			return err
+		}
+		for _, x := range fixes {
+			if n, ok := nodes[x.name]; ok {
+				n.vulnerable = true
+			}
+		}
+		for path := range unfixed {
+			if n, ok := nodes[path]; ok {
+				n.vulnerable = true
+			}
+		}
+	}
+	requiredBy, err := moduleGraph("", selected)
+	if err != nil {
+		return err
+	}
+	switch *only {
+	case "outdated":
+		requiredBy = restrictEdges(nodes, requiredBy, func(n *graphNode) bool { return n.update != "" })
+	case "vulnerable":
+		requiredBy = restrictEdges(nodes, requiredBy, func(n *graphNode) bool { return n.vulnerable })
+	}
+	paths := []string{"main"}
+	for path := range requiredBy {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths[1:])
+	ordered := []*graphNode{}
+	for _, path := range paths {
+		if n, ok := nodes[path]; ok && n.id == "" {
+			n.id = fmt.Sprintf("n%d", len(ordered))
+			ordered = append(ordered, n)
+		}
+	}
+	edges := [][2]*graphNode{}
+	for _, path := range paths[1:] {
+		parents := requiredBy[path]
+		sort.Strings(parents)
+		for _, parent := range parents {
+			if p, ok := nodes[parent]; ok && p.id != "" && nodes[path] != nil {
+				edges = append(edges, [2]*graphNode{p, nodes[path]})
+			}
+		}
+	}
+	out := newOutput(*outputFile)
+	write(out, ordered, edges)
+	return out.close()
+}
@@ -0,0 +1,171 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// Exit codes of --hook, the vulnerable modules taking precedence
+const (
+	hookVulnerable = 2
+	hookStale      = 3
+)
+
+// hookConfig is the policy of --hook. Each check either fails the hook,
+// warns or is ignored.
+type hookConfig struct {
+	// Vulnerable defaults to fail
+	Vulnerable string `yaml:"vulnerable,omitempty"`
+	// Stale defaults to warn
+	Stale string `yaml:"stale,omitempty"`
+	// MaxLibyear is how far behind its latest version a direct dependency
+	// may lag before being stale, in years, 1 by default
+	MaxLibyear float64 `yaml:"max-libyear,omitempty"`
+	// CacheTTL is how long the findings of unchanged go.mod and go.sum
+	// files are reused, as a Go duration, 24h by default
+	CacheTTL string `yaml:"cache-ttl,omitempty"`
+}
+
+// hookFindings are the findings of a go.mod and go.sum, cached until they
+// change or the cache expires
+type hookFindings struct {
+	Key        string        `json:"key"`
+	Time       time.Time     `json:"time"`
+	Vulnerable []string      `json:"vulnerable"`
+	Stale      []moduleStats `json:"stale"`
+}
+
+func (c *hookConfig) withDefaults() (hookConfig, time.Duration, error) {
+	h := hookConfig{Vulnerable: "fail", Stale: "warn", MaxLibyear: 1, CacheTTL: "24h"}
+	if c != nil {
+		if c.Vulnerable != "" {
+			h.Vulnerable = c.Vulnerable
+		}
+		if c.Stale != "" {
+			h.Stale = c.Stale
+		}
+		if c.MaxLibyear > 0 {
+			h.MaxLibyear = c.MaxLibyear
+		}
+		if c.CacheTTL != "" {
+			h.CacheTTL = c.CacheTTL
+		}
+	}
+	for _, action := range []string{h.Vulnerable, h.Stale} {
+		if action != "fail" && action != "warn" && action != "ignore" {
+			return h, 0, fmt.Errorf("hook: unknown action %q, expected fail, warn or ignore", action)
+		}
+	}
+	ttl, err := time.ParseDuration(h.CacheTTL)
+	if err != nil {
+		return h, 0, fmt.Errorf("hook: cache-ttl: %v", err)
+	}
+	return h, ttl, nil
+}
+
+// hookKey identifies the content of the go.mod and go.sum files
+func hookKey() (string, error) {
+	gomod, err := goModFile()
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.New()
+	for _, file := range []string{gomod, sumFile(gomod)} {
+		data, err := ioutil.ReadFile(file)
+		if err != nil && !os.IsNotExist(err) {
+			return "", err
+		}
+		sum.Write(data)
+	}
+	return fmt.Sprintf("%x", sum.Sum(nil)), nil
+}
+
+// findings checks the vulnerable and the outdated modules, unless the
+// cached findings are still valid
+func findings(ttl time.Duration, debug bool) (*hookFindings, error) {
+	key, err := hookKey()
+	if err != nil {
+		return nil, err
+	}
+	file, err := stateFile("hook")
+	if err != nil {
+		return nil, err
+	}
+	var cached hookFindings
+	if data, err := ioutil.ReadFile(file); err == nil && json.Unmarshal(data, &cached) == nil {
+		if cached.Key == key && time.Since(cached.Time) < ttl {
+			return &cached, nil
+		}
+	}
+	f := &hookFindings{Key: key, Time: time.Now().UTC(), Vulnerable: []string{}, Stale: []moduleStats{}}
+	fixes, unfixed, err := securityUpdates(debug)
+	if err != nil {
+		return nil, err
+	}
+	for _, x := range fixes {
+		ids := []string{}
+		for _, v := range x.vulns {
+			ids = append(ids, v.ID)
+		}
+		f.Vulnerable = append(f.Vulnerable, fmt.Sprintf("%s %s is vulnerable to %s, fixed in %s", x.name, x.fromVersion, strings.Join(ids, ", "), x.toVersion))
+	}
+	for path, vulns := range unfixed {
+		for _, v := range vulns {
+			f.Vulnerable = append(f.Vulnerable, fmt.Sprintf("%s is vulnerable to %s, without a fix yet", path, v.ID))
+		}
+	}
+	s, err := computeStats(debug)
+	if err != nil {
+		return nil, err
+	}
+	f.Stale = s.Modules
+	if err := os.MkdirAll(filepath.Dir(file), 0755); err != nil {
+		return nil, err
+	}
+	data, err := json.Marshal(f)
+	if err != nil {
+		return nil, err
+	}
+	return f, ioutil.WriteFile(file, data, 0644)
+}
+
+// runHook checks the module for a pre-commit hook, without asking anything,
+// and returns the exit code of the policy
+func runHook(c *hookConfig, debug bool) (int, error) {
+	policy, ttl, err := c.withDefaults()
+	if err != nil {
+		return 0, err
+	}
+	f, err := findings(ttl, debug)
+	if err != nil {
+		return 0, err
+	}
+	code := 0
+	if policy.Vulnerable != "ignore" && len(f.Vulnerable) > 0 {
+		for _, line := range f.Vulnerable {
+			fmt.Printf("go.mod: %s\n", line)
+		}
+		if policy.Vulnerable == "fail" {
+			code = hookVulnerable
+		}
+	}
+	if policy.Stale != "ignore" {
+		stale := 0
+		for _, m := range f.Stale {
+			if m.Libyear > policy.MaxLibyear {
+				fmt.Printf("go.mod: %s %s is %.1f years behind %s\n", m.Path, m.From, m.Libyear, m.To)
+				stale++
+			}
+		}
+		if stale > 0 && policy.Stale == "fail" && code == 0 {
+			code = hookStale
+		}
+	}
+	return code, nil
+}
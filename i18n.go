@@ -0,0 +1,202 @@
+package main
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// messages translates the English messages, which are their own keys so
+// that a missing translation falls back to English
+var messages map[string]string
+
+var catalogs = map[string]map[string]string{
+	"fr": {
+		"Choose which modules to update": "Choisissez les modules à mettre à jour",
+		" (offline, possibly stale)":     " (hors ligne, peut-être périmé)",
+		"arrows to move, space to select, → all, ← none, tab to invert, type to filter": "flèches pour bouger, espace pour sélectionner, → tous, ← aucun, tab pour inverser, tapez pour filtrer",
+		"ctrl-o held back":                                  "ctrl-o retenus",
+		"for help":                                          "pour l'aide",
+		"move":                                              "bouger",
+		"select or unselect the module":                     "sélectionner ou désélectionner le module",
+		"select the matching modules":                       "sélectionner les modules filtrés",
+		"unselect the matching modules":                     "désélectionner les modules filtrés",
+		"select the matching patch updates":                 "sélectionner les mises à jour de correctif filtrées",
+		"select the matching minor updates":                 "sélectionner les mises à jour mineures filtrées",
+		"unselect the matching major upgrades":              "désélectionner les mises à jour majeures filtrées",
+		"invert the selection of the matching modules":      "inverser la sélection des modules filtrés",
+		"filter the modules, backspace and ctrl-w to erase": "filtrer les modules, retour arrière et ctrl-w pour effacer",
+		"show or hide the held back modules":                "afficher ou cacher les modules retenus",
+		"exclude the target version in go.mod, or cancel":   "exclure la version cible dans go.mod, ou annuler",
+		"show or hide what requires the module":             "afficher ou cacher ce qui requiert le module",
+		"Excluded %s@%s in go.mod":                          "%s@%s exclu dans go.mod",
+		"Excluded %s@%s in the go.mod of %s":                "%s@%s exclu dans le go.mod de %s",
+		"update the selected modules":                       "mettre à jour les modules sélectionnés",
+		"quit":                                              "quitter",
+		"%d held back":                                      "%d retenus",
+		"Bye":                                               "Au revoir",
+		"Discovering modules...":                            "Recherche des modules...",
+		"Held back:":                                        "Retenus :",
+		"Holding at %s":                                     "Retenu en %s",
+		"Holding at %s: %s":                                 "Retenu en %s : %s",
+		"Interrupted, stopping the updates, press Ctrl-C again to quit right away":                                                 "Interrompu, arrêt des mises à jour, appuyez à nouveau sur Ctrl-C pour quitter immédiatement",
+		"Interrupted after %d update(s), not applied: %s":                                                                          "Interrompu après %d mise(s) à jour, non appliquées : %s",
+		"Run with --resume to apply the remaining updates":                                                                         "Relancez avec --resume pour appliquer les mises à jour restantes",
+		"All modules are up to date":                                                                                               "Tous les modules sont à jour",
+		"Run with --offline to look for upgrades in the local module cache":                                                        "Relancez avec --offline pour chercher les mises à jour dans le cache local des modules",
+		"Submitted the dependencies to the dependency graph of %s (%s)":                                                            "Dépendances envoyées au graphe de dépendances de %s (%s)",
+		"Using go %s from %s, pinned in %s":                                                                                        "Utilisation de go %s depuis %s, fixé dans %s",
+		"%s provides go %s but go %s is in use, run go-mod-upgrade in nix develop to resolve the upgrades with the same toolchain": "%s fournit go %s mais go %s est utilisé, lancez go-mod-upgrade dans nix develop pour résoudre les mises à jour avec la même chaîne d'outils",
+		"Go %s is pinned in %s but go %s is in use and %s isn't installed, the upgrades may resolve differently than in CI":        "Go %s est fixé dans %s mais go %s est utilisé et %s n'est pas installé, les mises à jour peuvent être résolues différemment qu'en CI",
+		"Modules to update, e.g. 1,3-5, patch, minor, all or none, empty for the marked ones: ":                                    "Modules à mettre à jour, par ex. 1,3-5, patch, minor, all ou none, vide pour ceux marqués : ",
+		"not selected":                       "non sélectionné",
+		"selected":                           "sélectionné",
+		"item %d of %d, %s":                  "élément %d sur %d, %s",
+		"%d of %d modules selected":          "%d modules sélectionnés sur %d",
+		"to":                                 "vers",
+		"Error while copying the summary %v": "Erreur lors de la copie du résumé %v",
+		"Copied the summary of the updates to the clipboard": "Résumé des mises à jour copié dans le presse-papiers",
+		"Building for %s...":                               "Compilation pour %s...",
+		"Running %s before and after each upgrade...":      "Exécution de %s avant et après chaque mise à jour...",
+		"Why each module is shown or hidden:":              "Pourquoi chaque module est affiché ou masqué :",
+		"shown":                                            "affiché",
+		"hidden":                                           "masqué",
+		"%s upgrade from %s to %s":                         "mise à jour %s de %s vers %s",
+		", review required by the policy":                  ", revue exigée par la politique",
+		", snooze expired":                                 ", report expiré",
+		"indirect dependency, see go-mod-upgrade indirect": "dépendance indirecte, voir go-mod-upgrade indirect",
+		"up to date":                                       "à jour",
+		"up to date, newer versions retracted: %s":         "à jour, versions plus récentes retirées : %s",
+		"up to date, prerelease %s filtered out, prereleases are only offered from a prerelease": "à jour, préversion %s écartée, les préversions ne sont proposées qu'à partir d'une préversion",
+		"pinned at %s by the configuration%s":                                                    "épinglé en %s par la configuration%s",
+		"snoozed until %s by the ignore rule %s, %s available%s":                                 "reporté jusqu'au %s par la règle d'exclusion %s, %s disponible%s",
+		"ignored by the rule %s of the configuration, %s available%s":                            "ignoré par la règle %s de la configuration, %s disponible%s",
+		"upgrade from %s to %s denied by the policy":                                             "mise à jour de %s vers %s refusée par la politique",
+		"used by the code, hidden by --test-deps only":                                           "utilisé par le code, masqué par --test-deps only",
+		"only used by tests, hidden by --test-deps exclude":                                      "utilisé seulement par les tests, masqué par --test-deps exclude",
+		"%s not in the allowlist":                                                                "%s absent de la liste d'autorisation",
+		"major upgrade tracked by the issue %q":                                                  "mise à jour majeure suivie par le ticket %q",
+		"The workspace modules declare different Go versions:":                                   "Les modules de l'espace de travail déclarent des versions de Go différentes :",
+		"Align every module to %s?":                                                              "Aligner tous les modules sur %s ?",
+		"Aligned %s to %s":                                                                       "%s aligné sur %s",
+		"Using %s in %s, which requires go %s":                                                   "Utilisation de %s dans %s, qui requiert go %s",
+		"Migrating %s to %s@%s...":                                                               "Migration de %s vers %s@%s...",
+		"Migrating %s to %s@%s in %s...":                                                         "Migration de %s vers %s@%s dans %s...",
+		"Skipping the enrichment in offline mode":                                                "Enrichissement ignoré en mode hors ligne",
+		"API rate limit of %s exceeded, skipped %d lookups":                                      "Limite de requêtes de l'API de %s dépassée, %d recherches ignorées",
+		"Failed to fetch %d %s values":                                                           "Échec de la récupération de %d valeurs %s",
+		"Uploaded the SBOM to Dependency-Track (%s)":                                             "SBOM envoyé à Dependency-Track (%s)",
+		"Updating %s to version %s...":                                                           "Mise à jour de %s vers la version %s...",
+		"Updating %s in %s to version %s...":                                                     "Mise à jour de %s dans %s vers la version %s...",
+		"%s is already at version %s or later":                                                   "%s est déjà en version %s ou ultérieure",
+		"Error while updating %s: %v":                                                            "Erreur lors de la mise à jour de %s : %v",
+		"Error while updating %s, %s failure":                                                    "Erreur lors de la mise à jour de %s, échec %s",
+		"%d update(s) failed (%s), the go output is in %s":                                       "%d mise(s) à jour en échec (%s), la sortie de go est dans %s",
+		"Error while saving progress %v":                                                         "Erreur lors de l'enregistrement de la progression %v",
+		"Error while removing progress %v":                                                       "Erreur lors de la suppression de la progression %v",
+		"Error while saving selection %v":                                                        "Erreur lors de l'enregistrement de la sélection %v",
+		"Error while getting terminal size %v":                                                   "Erreur lors de la lecture de la taille du terminal %v",
+		"Stopping at the first failure":                                                          "Arrêt au premier échec",
+		"Skipping %s, limited to %d updates":                                                     "%s est ignoré, limité à %d mises à jour",
+		"A previous update session was interrupted, run with --resume to continue it":            "Une session de mise à jour a été interrompue, relancez avec --resume pour la continuer",
+		"Offline mode: upgrades come from the local module cache and may be stale":               "Mode hors ligne : les mises à jour viennent du cache local des modules et peuvent être périmées",
+		"Looking up the latest versions in the repositories, bypassing the module proxy":         "Recherche des dernières versions dans les dépôts, sans passer par le proxy de modules",
+		"Module downloads are disabled by GOPROXY=off, switching to the offline mode: upgrades come from the local module cache, and the checksum database, provenance and fresh release lookups are unavailable": "Les téléchargements de modules sont désactivés par GOPROXY=off, passage en mode hors ligne : les mises à jour viennent du cache local des modules, et les vérifications de la base de sommes de contrôle, de provenance et des dernières versions sont indisponibles",
+		"the local module cache lacks some modules of the build, run `go mod download` once with network access to fill it":                                                                                       "il manque des modules de la compilation dans le cache local, lancez `go mod download` une fois avec un accès réseau pour le remplir",
+		"Proposed %d upgrade(s) in %s, to apply once approved with go-mod-upgrade apply --from-plan %s":                                                                                                           "%d mise(s) à jour proposée(s) dans %s, à appliquer une fois approuvée(s) avec go-mod-upgrade apply --from-plan %s",
+		"go %s failed: %v": "go %s a échoué : %v",
+		"Hint: %s":         "Conseil : %s",
+		"run go-mod-upgrade from a directory containing a go.mod file, or create one with `go mod init`":                              "lancez go-mod-upgrade depuis un répertoire contenant un fichier go.mod, ou créez-en un avec `go mod init`",
+		"module downloads are disabled, use --offline to only look at the local module cache":                                         "les téléchargements de modules sont désactivés, utilisez --offline pour ne regarder que le cache local des modules",
+		"check the GOPROXY setting with `go env GOPROXY`":                                                                             "vérifiez le paramètre GOPROXY avec `go env GOPROXY`",
+		"the module proxy could not be reached, check your network connection and `go env GOPROXY`":                                   "le proxy de modules est injoignable, vérifiez votre connexion réseau et `go env GOPROXY`",
+		"private modules must be listed in GOPRIVATE (e.g. `go env -w GOPRIVATE=github.com/myorg/*`) with git credentials configured": "les modules privés doivent être listés dans GOPRIVATE (p.ex. `go env -w GOPRIVATE=github.com/myorg/*`) avec des identifiants git configurés",
+		"go.sum verification failed, try `go clean -modcache` and make sure GONOSUMDB covers private modules":                         "la vérification de go.sum a échoué, essayez `go clean -modcache` et vérifiez que GONOSUMDB couvre les modules privés",
+		"Error while looking up the vulnerabilities %v":                                                                               "Erreur lors de la recherche des vulnérabilités %v",
+		"Skipping the vulnerability lookups of the security priority in offline mode":                                                 "Recherche des vulnérabilités de la priorité security ignorée en mode hors ligne",
+		"Skipping the vulnerability lookups of the policy in offline mode":                                                            "Recherche des vulnérabilités de la politique ignorée en mode hors ligne",
+		"Ignoring the go version %s of %s, which can't be parsed":                                                                     "Version de go %s de %s ignorée, car illisible",
+		"latest patch %s":             "dernier correctif %s",
+		"latest patch of each module": "dernier correctif de chaque module",
+		"latest %s (%s)":              "dernière %s (%s)",
+		"Upgrade %s from %s to":       "Mettre à jour %s de %s vers",
+		"Warning: checksum verification is disabled (GOSUMDB=off)":                 "Attention : la vérification des sommes de contrôle est désactivée (GOSUMDB=off)",
+		"Warning: %s is excluded from checksum verification (GONOSUMDB/GOPRIVATE)": "Attention : %s est exclu de la vérification des sommes de contrôle (GONOSUMDB/GOPRIVATE)",
+		"Warning: %s %s is not in %s":                                              "Attention : %s %s n'est pas dans %s",
+		"Warning: could not look up %d versions in %s":                             "Attention : impossible de rechercher %d versions dans %s",
+		"Warning: %s %s has no provenance attestation":                             "Attention : %s %s n'a pas d'attestation de provenance",
+		"Warning: %s %s has a provenance attestation that failed to verify":        "Attention : %s %s a une attestation de provenance dont la vérification a échoué",
+		"Major upgrade of":                     "Mise à jour majeure de",
+		"  Changelog unavailable: %v":          "  Notes de version indisponibles : %v",
+		"  API changes unavailable: %v":        "  Changements d'API indisponibles : %v",
+		"  No exported identifier removed":     "  Aucun identifiant exporté supprimé",
+		"  %d exported identifier(s) removed:": "  %d identifiant(s) exporté(s) supprimé(s) :",
+		"    and %d more":                      "    et %d de plus",
+		"Include the upgrade of %s to %s?":     "Inclure la mise à jour de %s en %s ?",
+		"  The repository is archived":         "  Le dépôt est archivé",
+		"Breaking changes":                     "Changements incompatibles",
+		"Deprecations":                         "Dépréciations",
+		"Notable features":                     "Nouveautés notables",
+		"  Release notes: %s\n  Compare: %s":   "  Notes de version : %s\n  Comparer : %s",
+	},
+}
+
+// translate returns the message in the current language
+func translate(message string) string {
+	if translated, ok := messages[message]; ok {
+		return translated
+	}
+	return message
+}
+
+// tr formats the message in the current language
+func tr(format string, args ...interface{}) string {
+	return fmt.Sprintf(translate(format), args...)
+}
+
+// detectLanguage returns the language of the locale environment variables,
+// as fr for fr_BE.UTF-8
+func detectLanguage() string {
+	for _, name := range []string{"LC_ALL", "LC_MESSAGES", "LANG"} {
+		fields := strings.FieldsFunc(os.Getenv(name), func(r rune) bool { return r == '_' || r == '.' || r == '@' || r == '-' })
+		if len(fields) > 0 {
+			return strings.ToLower(fields[0])
+		}
+	}
+	return "en"
+}
+
+// setLanguage selects the built-in messages of the language, completed by
+// the translations of <user config dir>/go-mod-upgrade/<lang>.yaml, which
+// maps the English messages to the translated ones
+func setLanguage(lang string) error {
+	if lang == "" {
+		lang = detectLanguage()
+	}
+	messages = map[string]string{}
+	for k, v := range catalogs[lang] {
+		messages[k] = v
+	}
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return nil
+	}
+	data, err := ioutil.ReadFile(filepath.Join(dir, "go-mod-upgrade", lang+".yaml"))
+	if os.IsNotExist(err) {
+		return nil
+	} else if err != nil {
+		return err
+	}
+	custom := map[string]string{}
+	if err := yaml.Unmarshal(data, &custom); err != nil {
+		return fmt.Errorf("messages for %s: %v", lang, err)
+	}
+	for k, v := range custom {
+		messages[k] = v
+	}
+	return nil
+}
@@ -0,0 +1,206 @@
+package main
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// impactCopy is a copy of the project in a temporary directory, where the
+// upgrades are tried one at a time to measure their impact on the build
+type impactCopy struct {
+	dir string
+	pkg string
+	// timed builds use an empty build cache, so that the build time
+	// includes the compilation of every dependency
+	timed bool
+	// baseline is the build of each module directory before upgrades
+	baseline map[string]buildResult
+}
+
+// buildResult is the binary size and the build time of a package, or their
+// change caused by an upgrade
+type buildResult struct {
+	size     int64
+	duration time.Duration
+}
+
+// copyTree copies the files of src to dst, skipping version control
+// directories
+func copyTree(src, dst string) error {
+	return filepath.Walk(src, func(file string, fi os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(src, file)
+		if err != nil {
+			return err
+		}
+		target := filepath.Join(dst, rel)
+		switch {
+		case fi.IsDir() && (fi.Name() == ".git" || fi.Name() == ".hg" || fi.Name() == ".svn"):
+			return filepath.SkipDir
+		case fi.IsDir():
+			return os.MkdirAll(target, 0755)
+		case !fi.Mode().IsRegular():
+			return nil
+		}
+		data, err := ioutil.ReadFile(file)
+		if err != nil {
+			return err
+		}
+		return ioutil.WriteFile(target, data, fi.Mode())
+	})
+}
+
+func newImpactCopy(pkg string, timed bool) (*impactCopy, error) {
+	root, err := projectRoot()
+	if err != nil {
+		return nil, err
+	}
+	dir, err := ioutil.TempDir("", "go-mod-upgrade-impact")
+	if err != nil {
+		return nil, err
+	}
+	if err := copyTree(root, dir); err != nil {
+		os.RemoveAll(dir)
+		return nil, err
+	}
+	return &impactCopy{dir: dir, pkg: pkg, timed: timed, baseline: map[string]buildResult{}}, nil
+}
+
+func (c *impactCopy) remove() {
+	os.RemoveAll(c.dir)
+}
+
+// build builds the package in the module directory, measuring the binary
+// size unless the package is a pattern like ./..., and the build time when
+// timed. Vendoring is ignored, as the upgrades aren't vendored in the copy.
+func (c *impactCopy) build(dir string) (buildResult, error) {
+	args := []string{"build", "-mod=mod"}
+	out := filepath.Join(c.dir, ".go-mod-upgrade-impact")
+	sized := !strings.Contains(c.pkg, "...")
+	if sized {
+		args = append(args, "-o", out)
+		defer os.Remove(out)
+	}
+	args = append(args, c.pkg)
+	cmd := goCommandIn(filepath.Join(c.dir, dir), args...)
+	if c.timed {
+		cache, err := ioutil.TempDir("", "go-mod-upgrade-cache")
+		if err != nil {
+			return buildResult{}, err
+		}
+		defer os.RemoveAll(cache)
+		cmd.Env = append(append(os.Environ(), goEnv...), "GOCACHE="+cache)
+	}
+	start := time.Now()
+	if output, err := cmd.CombinedOutput(); err != nil {
+		return buildResult{}, fmt.Errorf("go %s: %v: %s", strings.Join(args, " "), err, strings.TrimSpace(string(output)))
+	}
+	r := buildResult{duration: time.Since(start)}
+	if sized {
+		fi, err := os.Stat(out)
+		if err != nil {
+			return buildResult{}, err
+		}
+		r.size = fi.Size()
+	}
+	return r, nil
+}
+
+// measure returns the change of the build caused by the upgrade, going
+// back to the original go.mod and go.sum afterwards
+func (c *impactCopy) measure(m Module) (buildResult, error) {
+	dir := filepath.Join(c.dir, m.dir)
+	if _, ok := c.baseline[m.dir]; !ok {
+		r, err := c.build(m.dir)
+		if err != nil {
+			return buildResult{}, err
+		}
+		c.baseline[m.dir] = r
+	}
+	saved := map[string][]byte{}
+	for _, name := range []string{"go.mod", "go.sum"} {
+		data, err := ioutil.ReadFile(filepath.Join(dir, name))
+		if err != nil && !os.IsNotExist(err) {
+			return buildResult{}, err
+		}
+		saved[name] = data
+	}
+	defer func() {
+		for name, data := range saved {
+			_ = ioutil.WriteFile(filepath.Join(dir, name), data, 0644)
+		}
+	}()
+	if output, err := goCommandIn(dir, "get", m.name+"@"+m.toVersion).CombinedOutput(); err != nil {
+		return buildResult{}, fmt.Errorf("go get: %v: %s", err, strings.TrimSpace(string(output)))
+	}
+	r, err := c.build(m.dir)
+	if err != nil {
+		return buildResult{}, err
+	}
+	base := c.baseline[m.dir]
+	return buildResult{r.size - base.size, r.duration - base.duration}, nil
+}
+
+// formatSize formats a size change in bytes, as +1.5 KB
+func formatSize(delta int64) string {
+	sign := "+"
+	if delta < 0 {
+		sign = "-"
+		delta = -delta
+	}
+	switch {
+	case delta >= 1<<20:
+		return fmt.Sprintf("%s%.1f MB", sign, float64(delta)/(1<<20))
+	case delta >= 1<<10:
+		return fmt.Sprintf("%s%.1f KB", sign, float64(delta)/(1<<10))
+	}
+	return fmt.Sprintf("%s%d B", sign, delta)
+}
+
+// formatDuration formats a build time change, as +1.2s
+func formatDuration(delta time.Duration) string {
+	if delta < 0 {
+		return fmt.Sprintf("%.1fs", delta.Seconds())
+	}
+	return fmt.Sprintf("+%.1fs", delta.Seconds())
+}
+
+// measureImpact builds the package before and after each upgrade in a copy
+// of the project, recording the binary size change of each module, and the
+// build time change when timed
+func measureImpact(modules []Module, pkg string, timed bool) error {
+	c, err := newImpactCopy(pkg, timed)
+	if err != nil {
+		return err
+	}
+	defer c.remove()
+	fmt.Printf("Building %s before and after each upgrade\n", pkg)
+	maxName := 0
+	for _, x := range modules {
+		maxName = max(maxName, len(x.name))
+	}
+	for i, x := range modules {
+		delta, err := c.measure(x)
+		if err != nil {
+			fmt.Printf("Error while measuring %s: %v\n", x.name, err)
+			continue
+		}
+		line := "  " + padRight(x.name, maxName)
+		if !strings.Contains(pkg, "...") {
+			modules[i].sizeDelta = &delta.size
+			line += " " + formatSize(delta.size)
+		}
+		if timed {
+			modules[i].buildTimeDelta = &delta.duration
+			line += " " + formatDuration(delta.duration)
+		}
+		fmt.Println(line)
+	}
+	return nil
+}
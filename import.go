@@ -0,0 +1,241 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"unicode"
+
+	"gopkg.in/yaml.v3"
+)
+
+// importCandidates are the Renovate and Dependabot configuration files
+// looked up when none is given to the import command
+var importCandidates = []string{
+	"renovate.json",
+	".github/renovate.json",
+	".gitlab/renovate.json",
+	".renovaterc",
+	".renovaterc.json",
+	".github/dependabot.yml",
+	".github/dependabot.yaml",
+}
+
+type renovateConfig struct {
+	IgnoreDeps   []string `json:"ignoreDeps"`
+	Schedule     []string `json:"schedule"`
+	PackageRules []struct {
+		MatchManagers        []string `json:"matchManagers"`
+		MatchPackageNames    []string `json:"matchPackageNames"`
+		MatchPackagePrefixes []string `json:"matchPackagePrefixes"`
+		MatchPackagePatterns []string `json:"matchPackagePatterns"`
+		MatchUpdateTypes     []string `json:"matchUpdateTypes"`
+		AllowedVersions      string   `json:"allowedVersions"`
+		Enabled              *bool    `json:"enabled"`
+		GroupName            string   `json:"groupName"`
+		Schedule             []string `json:"schedule"`
+	} `json:"packageRules"`
+}
+
+type dependabotConfig struct {
+	Updates []struct {
+		PackageEcosystem string `yaml:"package-ecosystem"`
+		Directory        string `yaml:"directory"`
+		Schedule         struct {
+			Interval string `yaml:"interval"`
+			Day      string `yaml:"day"`
+			Time     string `yaml:"time"`
+		} `yaml:"schedule"`
+		Ignore []struct {
+			DependencyName string   `yaml:"dependency-name"`
+			Versions       []string `yaml:"versions"`
+			UpdateTypes    []string `yaml:"update-types"`
+		} `yaml:"ignore"`
+		Groups map[string]struct {
+			Patterns []string `yaml:"patterns"`
+		} `yaml:"groups"`
+	} `yaml:"updates"`
+}
+
+func importCommand(args []string) error {
+	fs := flag.NewFlagSet("import", flag.ExitOnError)
+	force := fs.Bool("force", false, "Overwrite an existing configuration file")
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+	source := fs.Arg(0)
+	if source == "" {
+		for _, candidate := range importCandidates {
+			if _, err := os.Stat(candidate); err == nil {
+				source = candidate
+				break
+			}
+		}
+		if source == "" {
+			return errors.New("no Renovate or Dependabot configuration found, give its path to import")
+		}
+	}
+	target, err := configFile()
+	if err != nil {
+		return err
+	}
+	if _, err := os.Stat(target); err == nil && !*force {
+		return fmt.Errorf("%s already exists, use --force to overwrite it", target)
+	}
+	data, err := ioutil.ReadFile(source)
+	if err != nil {
+		return err
+	}
+	var cfg *config
+	var warnings []string
+	switch filepath.Ext(source) {
+	case ".yml", ".yaml":
+		cfg, warnings, err = importDependabot(data)
+	default:
+		cfg, warnings, err = importRenovate(data)
+	}
+	if err != nil {
+		return fmt.Errorf("%s: %v", source, err)
+	}
+	for _, w := range warnings {
+		fmt.Printf("Warning: %s\n", w)
+	}
+	if err := cfg.save(target); err != nil {
+		return err
+	}
+	fmt.Printf("Imported %s into %s\n", source, target)
+	return nil
+}
+
+func importDependabot(data []byte) (*config, []string, error) {
+	var d dependabotConfig
+	if err := yaml.Unmarshal(data, &d); err != nil {
+		return nil, nil, err
+	}
+	cfg := &config{}
+	warnings := []string{}
+	for _, u := range d.Updates {
+		if u.PackageEcosystem != "gomod" {
+			continue
+		}
+		if u.Schedule.Interval != "" {
+			warnings = append(warnings, fmt.Sprintf("the %s schedule of %s can't be imported, run go-mod-upgrade from your CI scheduler instead", u.Schedule.Interval, u.Directory))
+		}
+		for _, i := range u.Ignore {
+			rule := ignoreRule{Path: i.DependencyName, Versions: i.Versions}
+			for _, t := range i.UpdateTypes {
+				s, err := parseSeverity(strings.TrimPrefix(t, "version-update:semver-"))
+				if err != nil {
+					warnings = append(warnings, fmt.Sprintf("the update type %q can't be imported", t))
+					continue
+				}
+				rule.UpdateTypes = append(rule.UpdateTypes, s)
+			}
+			cfg.Ignore = append(cfg.Ignore, rule)
+		}
+		for name, g := range u.Groups {
+			cfg.Groups = append(cfg.Groups, group{Name: name, Patterns: g.Patterns})
+		}
+	}
+	return cfg, warnings, nil
+}
+
+func importRenovate(data []byte) (*config, []string, error) {
+	var r renovateConfig
+	if err := json.Unmarshal(data, &r); err != nil {
+		return nil, nil, err
+	}
+	cfg := &config{}
+	warnings := []string{}
+	if len(r.Schedule) > 0 {
+		warnings = append(warnings, fmt.Sprintf("the schedule %q can't be imported, run go-mod-upgrade from your CI scheduler instead", strings.Join(r.Schedule, ", ")))
+	}
+	for _, dep := range r.IgnoreDeps {
+		cfg.Ignore = append(cfg.Ignore, ignoreRule{Path: dep})
+	}
+	for _, rule := range r.PackageRules {
+		if len(rule.MatchManagers) > 0 && !contains(rule.MatchManagers, "gomod") {
+			continue
+		}
+		patterns := append([]string{}, rule.MatchPackageNames...)
+		for _, prefix := range rule.MatchPackagePrefixes {
+			patterns = append(patterns, prefix+"*")
+		}
+		for _, re := range rule.MatchPackagePatterns {
+			if p, ok := regexpToPattern(re); ok {
+				patterns = append(patterns, p)
+			} else {
+				warnings = append(warnings, fmt.Sprintf("the package pattern %q can't be imported", re))
+			}
+		}
+		if len(rule.Schedule) > 0 {
+			warnings = append(warnings, fmt.Sprintf("the schedule %q of %s can't be imported", strings.Join(rule.Schedule, ", "), strings.Join(patterns, ", ")))
+		}
+		if rule.Enabled != nil && !*rule.Enabled {
+			updateTypes := []Severity{}
+			for _, t := range rule.MatchUpdateTypes {
+				s, err := parseSeverity(t)
+				if err != nil {
+					warnings = append(warnings, fmt.Sprintf("the update type %q can't be imported", t))
+					continue
+				}
+				updateTypes = append(updateTypes, s)
+			}
+			for _, p := range patterns {
+				cfg.Ignore = append(cfg.Ignore, ignoreRule{Path: p, UpdateTypes: updateTypes})
+			}
+		}
+		if rule.AllowedVersions != "" {
+			warnings = append(warnings, fmt.Sprintf("the allowed versions %q of %s can't be imported, add ignore rules for the other versions", rule.AllowedVersions, strings.Join(patterns, ", ")))
+		}
+		if rule.GroupName != "" {
+			cfg.Groups = append(cfg.Groups, group{Name: rule.GroupName, Patterns: patterns})
+		}
+	}
+	return cfg, warnings, nil
+}
+
+var simplePattern = regexp.MustCompile(`^(\^?)((?:[\w./-]|\\.)*?)(\.\*)?(\$?)$`)
+
+// regexpToPattern converts simple regular expressions like ^github\.com/aws/
+// into patterns
+func regexpToPattern(re string) (string, bool) {
+	m := simplePattern.FindStringSubmatch(re)
+	if m == nil {
+		return "", false
+	}
+	pattern, ok := unescapePunctuation(m[2])
+	if !ok {
+		return "", false
+	}
+	if m[1] == "" {
+		pattern = "*" + pattern
+	}
+	if m[3] != "" || m[4] == "" {
+		pattern += "*"
+	}
+	return pattern, true
+}
+
+// unescapePunctuation unescapes the escaped punctuation characters, failing
+// on the other escapes, such as the \d or \w character classes, and on \*
+// which would turn into a wildcard
+func unescapePunctuation(s string) (string, bool) {
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		if s[i] == '\\' {
+			i++
+			if i == len(s) || s[i] == '*' || !unicode.IsPunct(rune(s[i])) && !unicode.IsSymbol(rune(s[i])) {
+				return "", false
+			}
+		}
+		b.WriteByte(s[i])
+	}
+	return b.String(), true
+}
@@ -0,0 +1,231 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/AlecAivazis/survey/v2"
+	"github.com/Masterminds/semver/v3"
+	"github.com/fatih/color"
+)
+
+// parentChain is a direct dependency pulling an indirect one, through the
+// chain of modules between them
+type parentChain struct {
+	parent goModule
+	chain  []string
+}
+
+// moduleGraph returns the requirements of go mod graph in dir by required
+// module path, keeping the ones of the selected versions, with main for the
+// main module
+func moduleGraph(dir string, selected map[string]string) (map[string][]string, error) {
+	args := []string{"mod", "graph"}
+	out, err := goCommandIn(dir, args...).Output()
+	if err != nil {
+		return nil, newGoError(args, err)
+	}
+	requiredBy := map[string][]string{}
+	scanner := bufio.NewScanner(bytes.NewReader(out))
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) != 2 {
+			continue
+		}
+		parent := "main"
+		if i := strings.Index(fields[0], "@"); i > 0 {
+			if selected[fields[0][:i]] != fields[0][i+1:] {
+				continue
+			}
+			parent = fields[0][:i]
+		}
+		child := strings.SplitN(fields[1], "@", 2)[0]
+		requiredBy[child] = append(requiredBy[child], parent)
+	}
+	return requiredBy, scanner.Err()
+}
+
+// directParents walks the module graph up from the module to the direct
+// dependencies requiring it, with the shortest chain to each
+func directParents(path string, requiredBy map[string][]string, direct map[string]goModule) []parentChain {
+	parents := []parentChain{}
+	seen := map[string]bool{path: true}
+	queue := [][]string{{path}}
+	for len(queue) > 0 {
+		chain := queue[0]
+		queue = queue[1:]
+		for _, parent := range requiredBy[chain[0]] {
+			if parent == "main" || seen[parent] {
+				continue
+			}
+			seen[parent] = true
+			next := append([]string{parent}, chain...)
+			if d, ok := direct[parent]; ok {
+				parents = append(parents, parentChain{d, next})
+				continue
+			}
+			queue = append(queue, next)
+		}
+	}
+	return parents
+}
+
+// requiredVersion returns the version of the module required by the go.mod
+// file of the other module at version, empty when it doesn't require it
+func requiredVersion(other, version, path string) (string, error) {
+	args := []string{"mod", "download", "-json", other + "@" + version}
+	out, err := goCommand(args...).Output()
+	if err != nil {
+		return "", newGoError(args, err)
+	}
+	var download struct{ GoMod string }
+	if err := json.Unmarshal(out, &download); err != nil {
+		return "", err
+	}
+	args = []string{"mod", "edit", "-json", download.GoMod}
+	if out, err = goCommand(args...).Output(); err != nil {
+		return "", newGoError(args, err)
+	}
+	var gomod struct{ Require []moduleVersion }
+	if err := json.Unmarshal(out, &gomod); err != nil {
+		return "", err
+	}
+	for _, r := range gomod.Require {
+		if r.Path == path {
+			return r.Version, nil
+		}
+	}
+	return "", nil
+}
+
+func indirectCommand(args []string, debug bool) error {
+	fs := flag.NewFlagSet("indirect", flag.ExitOnError)
+	vulnerable := fs.Bool("vulnerable", false, "Look at the vulnerable indirect dependencies, to their fixed version, instead of the outdated ones")
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+	list, err := goList("", debug, "list", "-mod=mod", "-u", "-json", "-m", "all")
+	if err != nil {
+		return err
+	}
+	selected := map[string]string{}
+	direct := map[string]goModule{}
+	targets := []Module{}
+	for _, m := range list {
+		if m.Main {
+			continue
+		}
+		selected[m.Path] = m.Version
+		if !m.Indirect {
+			direct[m.Path] = m
+		} else if m.Update != nil && !*vulnerable {
+			targets = append(targets, newModule(m.Path, m.Version, m.Update.Version))
+		}
+	}
+	if *vulnerable {
+		fmt.Println("Looking for vulnerable modules...")
+		fixes, _, err := securityUpdates(debug)
+		if err != nil {
+			return err
+		}
+		for _, x := range fixes {
+			if _, ok := direct[x.name]; !ok {
+				targets = append(targets, x)
+			}
+		}
+	}
+	if fs.NArg() > 0 {
+		wanted := map[string]bool{}
+		for _, path := range fs.Args() {
+			wanted[path] = true
+		}
+		kept := []Module{}
+		for _, x := range targets {
+			if wanted[x.name] {
+				kept = append(kept, x)
+			}
+		}
+		targets = kept
+	}
+	if len(targets) == 0 {
+		fmt.Println("No indirect dependency to upgrade")
+		return nil
+	}
+	sort.Slice(targets, func(i, j int) bool {
+		return targets[i].name < targets[j].name
+	})
+	requiredBy, err := moduleGraph("", selected)
+	if err != nil {
+		return err
+	}
+	const skip = "Skip"
+	chosen := []Module{}
+	// A parent may pull several of the modules
+	picked := map[string]bool{}
+	for _, x := range targets {
+		fmt.Fprintf(color.Output, "\n%s %s -> %s\n", formatName(x, len(x.name)), displayVersion(x.from, x.fromVersion), formatTo(x))
+		for _, v := range x.vulns {
+			fmt.Printf("  %s: %s\n", v.ID, v.Summary)
+		}
+		options := []string{fmt.Sprintf("go get %s@%s", x.name, x.toVersion)}
+		upgrades := map[string]Module{}
+		for _, p := range directParents(x.name, requiredBy, direct) {
+			fmt.Printf("  pulled by %s %s", p.parent.Path, p.parent.Version)
+			if len(p.chain) > 2 {
+				fmt.Printf(" through %s", strings.Join(p.chain[1:len(p.chain)-1], " → "))
+			}
+			fmt.Println()
+			if p.parent.Update == nil {
+				fmt.Println("    already at its latest version")
+				continue
+			}
+			latest := p.parent.Update.Version
+			required, err := requiredVersion(p.parent.Path, latest, x.name)
+			switch {
+			case err != nil:
+				fmt.Printf("    Error while reading the requirements of %s %s: %v\n", p.parent.Path, latest, err)
+				continue
+			case required == "":
+				fmt.Printf("    %s doesn't require it directly, its effect is unknown\n", latest)
+			default:
+				r, rerr := semver.NewVersion(required)
+				if rerr == nil && x.to != nil && r.LessThan(x.to) {
+					fmt.Printf("    %s requires %s, not enough\n", latest, required)
+				} else {
+					fmt.Printf("    %s requires %s\n", latest, required)
+				}
+			}
+			option := fmt.Sprintf("upgrade %s to %s", p.parent.Path, latest)
+			options = append(options, option)
+			upgrades[option] = newModule(p.parent.Path, p.parent.Version, latest)
+		}
+		answer := ""
+		prompt := &survey.Select{
+			Message: fmt.Sprintf("Fix %s with", x.name),
+			Options: append(options, skip),
+		}
+		if err := ask(prompt, &answer); err != nil {
+			return err
+		}
+		if m, ok := upgrades[answer]; ok && !picked[m.name] {
+			picked[m.name] = true
+			chosen = append(chosen, m)
+		} else if !ok && answer != skip {
+			chosen = append(chosen, x)
+		}
+	}
+	if len(chosen) == 0 {
+		return nil
+	}
+	applied, err := apply(chosen, nil, false)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("Applied %d of %d upgrade(s)\n", len(applied), len(chosen))
+	return nil
+}
@@ -0,0 +1,141 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"flag"
+	"fmt"
+	"strings"
+
+	"github.com/fatih/color"
+)
+
+// importers lists the packages of the main module importing a package of
+// the module, in their code or their tests
+func importers(modulePath string) ([]string, error) {
+	args := []string{"list", "-f", "{{.ImportPath}} {{join .Imports \" \"}} {{join .TestImports \" \"}} {{join .XTestImports \" \"}}", "./..."}
+	out, err := goCommand(args...).Output()
+	if err != nil {
+		return nil, newGoError(args, err)
+	}
+	pkgs := []string{}
+	scanner := bufio.NewScanner(bytes.NewReader(out))
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) == 0 {
+			continue
+		}
+		for _, imp := range fields[1:] {
+			if imp == modulePath || strings.HasPrefix(imp, modulePath+"/") {
+				pkgs = append(pkgs, fields[0])
+				break
+			}
+		}
+	}
+	return pkgs, scanner.Err()
+}
+
+func infoCommand(args []string, debug bool) error {
+	fs := flag.NewFlagSet("info", flag.ExitOnError)
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+	if fs.NArg() != 1 {
+		return errors.New("info: expected a module path")
+	}
+	path := fs.Arg(0)
+	list, err := goList("", debug, "list", "-mod=mod", "-m", "-u", "-retracted", "-json", path)
+	if err != nil {
+		return err
+	}
+	if len(list) == 0 {
+		return fmt.Errorf("info: %s is not required", path)
+	}
+	current := list[0]
+	m := newModule(current.Path, current.Version, current.Version)
+	if current.Update != nil {
+		m = newModule(current.Path, current.Version, current.Update.Version)
+	}
+	fmt.Fprintln(color.Output, color.New(color.Bold).Sprint(current.Path))
+	printVersion := func(label string, v *goModule) {
+		date := ""
+		if v.Time != nil {
+			date = v.Time.Format("2006-01-02")
+		}
+		fmt.Printf("  %-11s %s %s\n", label, padRight(v.Version, len(m.toVersion)), date)
+	}
+	printVersion("Current", &current)
+	if current.Update != nil {
+		printVersion("Latest", current.Update)
+		fmt.Printf("  %-11s %s\n", "Update", m.severity)
+	} else {
+		fmt.Printf("  %-11s %s\n", "Latest", "up to date")
+	}
+	if len(current.Retracted) > 0 {
+		fmt.Fprintf(color.Output, "  %-11s %s\n", "Retracted", color.RedString("the current version is retracted: %s", strings.Join(current.Retracted, "; ")))
+	}
+	if current.Deprecated != "" {
+		fmt.Fprintf(color.Output, "  %-11s %s\n", "Deprecated", color.YellowString(current.Deprecated))
+	}
+	if current.Update != nil {
+		versions, err := newerVersions(m, debug)
+		if err != nil {
+			return err
+		}
+		queries := []string{"list", "-mod=mod", "-m", "-retracted", "-json"}
+		for _, v := range versions {
+			queries = append(queries, path+"@"+v)
+		}
+		if len(versions) > 0 {
+			newer, err := goList("", debug, queries...)
+			if err != nil {
+				return err
+			}
+			fmt.Println("  Newer versions")
+			for _, v := range newer {
+				date := ""
+				if v.Time != nil {
+					date = v.Time.Format("2006-01-02")
+				}
+				line := fmt.Sprintf("    %s %s", padRight(v.Version, len(m.toVersion)), date)
+				if len(v.Retracted) > 0 {
+					line += color.RedString(" retracted: %s", strings.Join(v.Retracted, "; "))
+				}
+				fmt.Fprintln(color.Output, line)
+			}
+		}
+	}
+	cfg, err := loadConfig()
+	if err != nil {
+		return err
+	}
+	hosts, err := newProviders(cfg.Providers, "")
+	if err != nil {
+		return err
+	}
+	if pr, repo, prefix, ok := hosts.lookup(path); ok {
+		fmt.Printf("  %-11s %s\n", "Releases", pr.releasesURL(repo))
+		if current.Update != nil {
+			fmt.Printf("  %-11s %s\n", "Compare", pr.compareURL(repo, prefix+m.fromVersion, prefix+m.toVersion))
+		}
+	}
+	if r, err := loadRequirements("", debug); err != nil {
+		return err
+	} else if tree := r.tree(path); len(tree) > 0 {
+		fmt.Printf("  %-11s\n", "Required by")
+		for _, line := range tree {
+			fmt.Printf("    %s\n", line)
+		}
+	}
+	pkgs, err := importers(path)
+	if err != nil {
+		return err
+	}
+	if len(pkgs) == 0 {
+		fmt.Printf("  %-11s %s\n", "Imported", "not imported directly, only required by dependencies")
+	} else {
+		fmt.Printf("  %-11s %s\n", "Imported by", strings.Join(pkgs, ", "))
+	}
+	return nil
+}
@@ -0,0 +1,52 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"os/signal"
+	"strings"
+	"sync/atomic"
+)
+
+// interruptWatch catches Ctrl-C during the updates. The go command in flight
+// gets the interrupt as well and aborts before writing go.mod, and the
+// updates stop there, leaving the journal for --resume. A second Ctrl-C
+// terminates the tool right away.
+type interruptWatch struct {
+	signals     chan os.Signal
+	interrupted int32
+}
+
+func watchInterrupt() *interruptWatch {
+	w := &interruptWatch{signals: make(chan os.Signal, 1)}
+	signal.Notify(w.signals, os.Interrupt)
+	go func() {
+		if _, ok := <-w.signals; !ok {
+			return
+		}
+		atomic.StoreInt32(&w.interrupted, 1)
+		signal.Stop(w.signals)
+		fmt.Println()
+		fmt.Println(tr("Interrupted, stopping the updates, press Ctrl-C again to quit right away"))
+	}()
+	return w
+}
+
+func (w *interruptWatch) stop() {
+	signal.Stop(w.signals)
+	close(w.signals)
+}
+
+func (w *interruptWatch) isInterrupted() bool {
+	return atomic.LoadInt32(&w.interrupted) == 1
+}
+
+// reportInterrupted tells what was and wasn't applied when interrupted
+func reportInterrupted(applied, remaining []Module) {
+	names := []string{}
+	for _, x := range remaining {
+		names = append(names, x.name+"@"+x.toVersion)
+	}
+	fmt.Println(tr("Interrupted after %d update(s), not applied: %s", len(applied), strings.Join(names, ", ")))
+	fmt.Println(tr("Run with --resume to apply the remaining updates"))
+}
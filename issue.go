@@ -0,0 +1,143 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os/exec"
+	"strings"
+	"text/template"
+)
+
+const (
+	defaultIssueTitle = "Upgrade {{.Path}} to {{.To}}"
+	defaultIssueBody  = `{{.Path}} has a new major version, {{.From}} → {{.To}}.
+{{if .ReleaseNotes}}
+Release notes: {{.ReleaseNotes}}
+Compare: {{.Compare}}
+{{end}}{{if .Breaking}}
+## Breaking changes
+{{range .Breaking}}
+- {{.}}{{end}}
+{{end}}
+## API changes
+
+{{if .APIError}}Unavailable: {{.APIError}}{{else if .Removed}}{{len .Removed}} exported identifier(s) removed:
+{{range .Removed}}
+- ` + "`{{.}}`" + `{{end}}{{else}}No exported identifier removed.{{end}}
+`
+)
+
+// issueConfig templates the tracking issues of the major upgrades
+type issueConfig struct {
+	Title  string   `yaml:"title,omitempty"`
+	Body   string   `yaml:"body,omitempty"`
+	Labels []string `yaml:"labels,omitempty"`
+}
+
+// issueData is given to the issue templates
+type issueData struct {
+	Path         string
+	From         string
+	To           string
+	ReleaseNotes string
+	Compare      string
+	Breaking     []string
+	Removed      []string
+	APIError     string
+}
+
+func renderIssue(name, text string, data issueData) (string, error) {
+	t, err := template.New(name).Option("missingkey=error").Parse(text)
+	if err != nil {
+		return "", fmt.Errorf("issue %s: %v", name, err)
+	}
+	var buf bytes.Buffer
+	if err := t.Execute(&buf, data); err != nil {
+		return "", fmt.Errorf("issue %s: %v", name, err)
+	}
+	return buf.String(), nil
+}
+
+// openIssues returns the titles of the open issues of the repository
+func openIssues() (map[string]bool, error) {
+	out, err := exec.Command("gh", "issue", "list", "--state", "open", "--limit", "1000", "--json", "title").Output()
+	if err != nil {
+		return nil, fmt.Errorf("gh issue list: %v", err)
+	}
+	var issues []struct{ Title string }
+	if err := json.Unmarshal(out, &issues); err != nil {
+		return nil, err
+	}
+	titles := map[string]bool{}
+	for _, i := range issues {
+		titles[i.Title] = true
+	}
+	return titles, nil
+}
+
+// openMajorIssues opens a tracking issue with the GitHub CLI for each major
+// upgrade, with its changelog and API removals, unless an open issue has the
+// same title. The other upgrades are returned.
+func openMajorIssues(modules []Module, c *issueConfig, hosts *providers, offline bool) ([]Module, error) {
+	if c == nil {
+		c = &issueConfig{}
+	}
+	title, body := c.Title, c.Body
+	if title == "" {
+		title = defaultIssueTitle
+	}
+	if body == "" {
+		body = defaultIssueBody
+	}
+	rest := []Module{}
+	var existing map[string]bool
+	for _, x := range modules {
+		if x.severity != SeverityMajor {
+			rest = append(rest, x)
+			continue
+		}
+		if existing == nil {
+			var err error
+			if existing, err = openIssues(); err != nil {
+				return nil, err
+			}
+		}
+		data := issueData{Path: x.name, From: x.fromVersion, To: x.toVersion}
+		t, err := renderIssue("title", title, data)
+		if err != nil {
+			return nil, err
+		}
+		t = strings.TrimSpace(t)
+		explanations.hide(x.dir, x.name, "major upgrade tracked by the issue %q", t)
+		if existing[t] {
+			fmt.Printf("Issue %q is already open\n", t)
+			continue
+		}
+		if !offline {
+			if cl, err := fetchChangelog(hosts, x); err != nil {
+				fmt.Printf("Changelog of %s unavailable: %v\n", x.name, err)
+			} else if cl != nil {
+				data.ReleaseNotes, data.Compare, data.Breaking = cl.URL, cl.CompareURL, cl.Breaking
+			}
+		}
+		if data.Removed, err = apiRemovals(x); err != nil {
+			data.APIError = err.Error()
+		}
+		b, err := renderIssue("body", body, data)
+		if err != nil {
+			return nil, err
+		}
+		args := []string{"issue", "create", "--title", t, "--body", b}
+		for _, l := range c.Labels {
+			args = append(args, "--label", l)
+		}
+		out, err := exec.Command("gh", args...).CombinedOutput()
+		if err != nil {
+			return nil, fmt.Errorf("gh issue create: %v: %s", err, strings.TrimSpace(string(out)))
+		}
+		existing[t] = true
+		fmt.Printf("Opened %s", out)
+	}
+	return rest, nil
+}
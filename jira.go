@@ -0,0 +1,210 @@
+package main
+
+import (
+	"encoding/base64"
+	"errors"
+	"flag"
+	"fmt"
+	"net/url"
+	"os"
+	"sort"
+	"strings"
+)
+
+// jiraConfig tells where to file the tickets of the pending upgrades
+type jiraConfig struct {
+	URL     string `yaml:"url"`
+	Project string `yaml:"project"`
+	// IssueType defaults to Task
+	IssueType string   `yaml:"issue-type,omitempty"`
+	Labels    []string `yaml:"labels,omitempty"`
+	// Categories are the upgrades filed: security, major, minor, patch or
+	// prerelease, security and major by default
+	Categories []string `yaml:"categories,omitempty"`
+	// UserEnv and TokenEnv name the environment variables of the
+	// credentials, JIRA_USER and JIRA_TOKEN by default. Without a user, the
+	// token is a personal access token.
+	UserEnv  string `yaml:"user-env,omitempty"`
+	TokenEnv string `yaml:"token-env,omitempty"`
+}
+
+// jiraTicket is a pending upgrade to file
+type jiraTicket struct {
+	summary     string
+	description string
+}
+
+func (c *jiraConfig) client() *apiClient {
+	userEnv, tokenEnv := c.UserEnv, c.TokenEnv
+	if userEnv == "" {
+		userEnv = "JIRA_USER"
+	}
+	if tokenEnv == "" {
+		tokenEnv = "JIRA_TOKEN"
+	}
+	user, token := os.Getenv(userEnv), os.Getenv(tokenEnv)
+	base := strings.TrimSuffix(c.URL, "/") + "/rest/api/2"
+	if user == "" {
+		return newAPIClient(base, token, bearer)
+	}
+	return newAPIClient(base, user+":"+token, func(credentials string) string {
+		return "Basic " + base64.StdEncoding.EncodeToString([]byte(credentials))
+	})
+}
+
+// openTickets returns the summaries of the tickets of the project which
+// are not done
+func openTickets(api *apiClient, project string) (map[string]bool, error) {
+	jql := fmt.Sprintf("project = %q AND statusCategory != Done", project)
+	summaries := map[string]bool{}
+	for start := 0; ; {
+		var result struct {
+			Total  int
+			Issues []struct {
+				Fields struct{ Summary string }
+			}
+		}
+		path := fmt.Sprintf("/search?jql=%s&fields=summary&maxResults=100&startAt=%d", url.QueryEscape(jql), start)
+		if err := api.get(path, &result); err != nil {
+			return nil, err
+		}
+		for _, i := range result.Issues {
+			summaries[i.Fields.Summary] = true
+		}
+		start += len(result.Issues)
+		if len(result.Issues) == 0 || start >= result.Total {
+			return summaries, nil
+		}
+	}
+}
+
+// jiraTickets returns the tickets of the pending upgrades in the categories,
+// a security fix taking precedence over the upgrade of the same module
+func jiraTickets(categories []string, verbose, debug bool) ([]jiraTicket, error) {
+	wanted := map[string]bool{}
+	for _, c := range categories {
+		if c != "security" {
+			if _, err := parseSeverity(c); err != nil {
+				return nil, fmt.Errorf("jira: unknown category %q, expected security or an update type", c)
+			}
+		}
+		wanted[c] = true
+	}
+	tickets := []jiraTicket{}
+	filed := map[string]bool{}
+	if wanted["security"] {
+		fixes, _, err := securityUpdates(debug)
+		if err != nil {
+			return nil, err
+		}
+		for _, x := range fixes {
+			ids := []string{}
+			var details strings.Builder
+			for _, v := range x.vulns {
+				ids = append(ids, v.ID)
+				fmt.Fprintf(&details, "* %s: %s\n", v.ID, v.Summary)
+			}
+			tickets = append(tickets, jiraTicket{
+				summary:     fmt.Sprintf("Upgrade %s to %s to fix %s", x.name, x.toVersion, strings.Join(ids, ", ")),
+				description: fmt.Sprintf("%s %s is vulnerable, %s fixes:\n\n%s", x.name, x.fromVersion, x.toVersion, details.String()),
+			})
+			filed[x.name] = true
+		}
+	}
+	cfg, err := loadConfig()
+	if err != nil {
+		return nil, err
+	}
+	modules, err := discover(verbose, debug, nil)
+	if err != nil {
+		return nil, err
+	}
+	if modules, err = cfg.filter(modules, verbose); err != nil {
+		return nil, err
+	}
+	for _, x := range modules {
+		if filed[x.name] || !wanted[x.severity.String()] {
+			continue
+		}
+		tickets = append(tickets, jiraTicket{
+			summary:     fmt.Sprintf("Upgrade %s to %s", x.name, x.toVersion),
+			description: fmt.Sprintf("%s has a %s upgrade, %s → %s.", x.name, x.severity, x.fromVersion, x.toVersion),
+		})
+	}
+	sort.SliceStable(tickets, func(i, j int) bool {
+		return tickets[i].summary < tickets[j].summary
+	})
+	return tickets, nil
+}
+
+// jiraCommand files a Jira ticket for each pending upgrade of the configured
+// categories, unless a ticket of the project which is not done has the same
+// summary
+func jiraCommand(args []string, verbose, debug bool) error {
+	fs := flag.NewFlagSet("jira", flag.ExitOnError)
+	dryRun := fs.Bool("dry-run", false, "Show the tickets without filing them")
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+	cfg, err := loadConfig()
+	if err != nil {
+		return err
+	}
+	c := cfg.Jira
+	if c == nil || c.URL == "" || c.Project == "" {
+		return errors.New("jira: configure the url and project of the jira section")
+	}
+	categories := c.Categories
+	if len(categories) == 0 {
+		categories = []string{"security", "major"}
+	}
+	tickets, err := jiraTickets(categories, verbose, debug)
+	if err != nil {
+		return err
+	}
+	api := c.client()
+	open, err := openTickets(api, c.Project)
+	if err != nil {
+		return err
+	}
+	issueType := c.IssueType
+	if issueType == "" {
+		issueType = "Task"
+	}
+	labels := c.Labels
+	if labels == nil {
+		labels = []string{}
+	}
+	filed := 0
+	for _, t := range tickets {
+		if open[t.summary] {
+			fmt.Printf("Already filed: %s\n", t.summary)
+			continue
+		}
+		if *dryRun {
+			fmt.Printf("Would file: %s\n", t.summary)
+			continue
+		}
+		var created struct{ Key string }
+		err := api.post("/issue", map[string]interface{}{
+			"fields": map[string]interface{}{
+				"project":     map[string]string{"key": c.Project},
+				"issuetype":   map[string]string{"name": issueType},
+				"summary":     t.summary,
+				"description": t.description,
+				"labels":      labels,
+			},
+		}, &created)
+		if err != nil {
+			return err
+		}
+		fmt.Printf("Filed %s: %s\n", created.Key, t.summary)
+		filed++
+	}
+	if len(tickets) == 0 {
+		fmt.Println("No pending upgrade to file")
+	} else if !*dryRun {
+		fmt.Printf("Filed %d ticket(s)\n", filed)
+	}
+	return nil
+}
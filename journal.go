@@ -0,0 +1,133 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+)
+
+// journal records the progress of an update session, so that an interrupted
+// session can be continued with --resume.
+type journal struct {
+	Modules []journalEntry `json:"modules"`
+}
+
+type journalEntry struct {
+	Path string `json:"path"`
+	From string `json:"from"`
+	To   string `json:"to"`
+	Done bool   `json:"done"`
+	Dir  string `json:"dir,omitempty"`
+}
+
+func newJournal(modules []Module) *journal {
+	j := &journal{}
+	for _, x := range modules {
+		j.Modules = append(j.Modules, journalEntry{
+			Path: x.name,
+			From: x.fromVersion,
+			To:   x.toVersion,
+			Dir:  x.dir,
+		})
+	}
+	return j
+}
+
+func loadJournal() (*journal, error) {
+	file, err := stateFile("journals")
+	if err != nil {
+		return nil, err
+	}
+	data, err := ioutil.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+	j := &journal{}
+	if err := json.Unmarshal(data, j); err != nil {
+		return nil, err
+	}
+	return j, nil
+}
+
+// remaining returns the modules which have not been updated yet
+func (j *journal) remaining() []Module {
+	modules := []Module{}
+	for _, e := range j.Modules {
+		if !e.Done {
+			m := newModule(e.Path, e.From, e.To)
+			m.dir = e.Dir
+			modules = append(modules, m)
+		}
+	}
+	return modules
+}
+
+func (j *journal) done(m Module) error {
+	if j == nil {
+		return nil
+	}
+	for i := range j.Modules {
+		if j.Modules[i].Path == m.name && j.Modules[i].Dir == m.dir {
+			j.Modules[i].Done = true
+		}
+	}
+	return j.save()
+}
+
+func (j *journal) save() error {
+	file, err := stateFile("journals")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(file), 0755); err != nil {
+		return err
+	}
+	data, err := json.Marshal(j)
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(file, data, 0644)
+}
+
+// remove deletes the journal once the session is complete
+func (j *journal) remove() error {
+	file, err := stateFile("journals")
+	if err != nil {
+		return err
+	}
+	err = os.Remove(file)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	return err
+}
+
+// resumeUpdate continues the update session recorded in the journal,
+// returning the updates applied
+func resumeUpdate(events *eventLog, failFast bool, allowed *allowlist) ([]Module, error) {
+	lock, err := acquireLock()
+	if err != nil {
+		return nil, err
+	}
+	defer lock.release()
+	j, err := loadJournal()
+	if os.IsNotExist(err) {
+		return nil, errors.New("no interrupted update session to resume")
+	} else if err != nil {
+		return nil, err
+	}
+	modules := j.remaining()
+	fmt.Printf("Resuming update session, %d module(s) remaining\n", len(modules))
+	modules = allowed.enforce(modules)
+	applied, ok := update(modules, events, j, failFast)
+	if err := revendorAll(applied); err != nil {
+		return applied, err
+	}
+	if !ok {
+		return applied, nil
+	}
+	return applied, j.remove()
+}
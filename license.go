@@ -0,0 +1,91 @@
+package main
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+)
+
+// licenseMarkers identify the common licenses by phrases of their text, the
+// first match winning
+var licenseMarkers = []struct {
+	id      string
+	phrases []string
+}{
+	{"AGPL-3.0", []string{"GNU AFFERO GENERAL PUBLIC LICENSE"}},
+	{"LGPL-3.0", []string{"GNU LESSER GENERAL PUBLIC LICENSE", "Version 3"}},
+	{"LGPL-2.1", []string{"GNU LESSER GENERAL PUBLIC LICENSE"}},
+	{"GPL-3.0", []string{"GNU GENERAL PUBLIC LICENSE", "Version 3"}},
+	{"GPL-2.0", []string{"GNU GENERAL PUBLIC LICENSE"}},
+	{"MPL-2.0", []string{"Mozilla Public License", "2.0"}},
+	{"Apache-2.0", []string{"Apache License", "Version 2.0"}},
+	{"BSD-3-Clause", []string{"Redistribution and use in source and binary forms", "Neither the name"}},
+	{"BSD-2-Clause", []string{"Redistribution and use in source and binary forms"}},
+	{"ISC", []string{"Permission to use, copy, modify, and/or distribute this software for any purpose"}},
+	{"MIT", []string{"Permission is hereby granted, free of charge"}},
+	{"Unlicense", []string{"This is free and unencumbered software released into the public domain"}},
+}
+
+// detectLicense names the license of the module extracted in dir, unknown
+// when its text isn't recognized and none without a license file
+func detectLicense(dir string) string {
+	files, err := ioutil.ReadDir(dir)
+	if err != nil {
+		return "none"
+	}
+	found := false
+	for _, f := range files {
+		name := strings.ToUpper(f.Name())
+		if f.IsDir() || !(strings.HasPrefix(name, "LICENSE") || strings.HasPrefix(name, "LICENCE") || strings.HasPrefix(name, "COPYING")) {
+			continue
+		}
+		found = true
+		data, err := ioutil.ReadFile(filepath.Join(dir, f.Name()))
+		if err != nil {
+			continue
+		}
+		// Licenses are often rewrapped
+		text := strings.Join(strings.Fields(string(data)), " ")
+		for _, l := range licenseMarkers {
+			matched := true
+			for _, p := range l.phrases {
+				if !strings.Contains(text, p) {
+					matched = false
+					break
+				}
+			}
+			if matched {
+				return l.id
+			}
+		}
+	}
+	if !found {
+		return "none"
+	}
+	return "unknown"
+}
+
+// moduleLicense downloads the version of the module to name its license,
+// which never changes for a version
+func moduleLicense(path, version string) (string, error) {
+	key := path + "@" + version
+	var license string
+	if metadata.load("licenses", key, never, &license) {
+		return license, nil
+	}
+	args := []string{"mod", "download", "-json", key}
+	out, err := goCommand(args...).Output()
+	if err != nil {
+		return "", newGoError(args, err)
+	}
+	var download struct {
+		Dir string
+	}
+	if err := json.Unmarshal(out, &download); err != nil {
+		return "", err
+	}
+	license = detectLicense(download.Dir)
+	metadata.store("licenses", key, license)
+	return license, nil
+}
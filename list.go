@@ -0,0 +1,135 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/Masterminds/semver/v3"
+)
+
+// newerVersions returns the versions of the module after the current one,
+// up to the target, in ascending order as reported by go list -versions
+func newerVersions(m Module, debug bool) ([]string, error) {
+	list, err := goList(m.dir, debug, "list", "-m", "-versions", "-json", m.name)
+	if err != nil {
+		return nil, err
+	}
+	versions := []string{}
+	if len(list) == 0 || m.from == nil || m.to == nil {
+		return versions, nil
+	}
+	for _, v := range list[0].Versions {
+		parsed, err := semver.NewVersion(v)
+		if err != nil {
+			continue
+		}
+		if parsed.GreaterThan(m.from) && !parsed.GreaterThan(m.to) {
+			versions = append(versions, v)
+		}
+	}
+	return versions, nil
+}
+
+func listCommand(args []string, verbose, debug bool) error {
+	fs := flag.NewFlagSet("list", flag.ExitOnError)
+	asJSON := fs.Bool("json", false, "Output the upgrades as JSON")
+	withVersions := fs.Bool("versions", false, "Include every available version between the current and the target one")
+	fs.BoolVar(&patchTargets, "patch", false, "Include the latest patch of the current minor version, as go get -u=patch")
+	outputFile := fs.String("output-file", "", "Write the upgrades to a file, replaced atomically, or - for stdout")
+	format := fs.String("format", "text", "Output format: text, json, gomod-diff for the diff of go.mod the upgrades would produce, or transitive for the other modules they would add, remove or move")
+	withSum := fs.Bool("sum", false, "Include the diff of go.sum in the gomod-diff format")
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+	switch *format {
+	case "text":
+	case "json":
+		*asJSON = true
+	case "gomod-diff", "transitive":
+	default:
+		return fmt.Errorf("unknown format %q, expected text, json, gomod-diff or transitive", *format)
+	}
+	out := newOutput(*outputFile)
+	cfg, err := loadConfig()
+	if err != nil {
+		return err
+	}
+	modules, err := discover(verbose, debug, nil)
+	if err != nil {
+		return err
+	}
+	modules, err = cfg.filter(modules, verbose)
+	if err != nil {
+		return err
+	}
+	if *withVersions {
+		for i, x := range modules {
+			if modules[i].versions, err = newerVersions(x, debug); err != nil {
+				return err
+			}
+		}
+	}
+	if *format == "gomod-diff" {
+		diff, err := previewDiff(modules, *withSum)
+		if err != nil {
+			return err
+		}
+		if _, err := io.WriteString(out, diff); err != nil {
+			return err
+		}
+		return out.close()
+	}
+	if *format == "transitive" {
+		changes, err := transitiveChanges(modules, debug)
+		if err != nil {
+			return err
+		}
+		for _, c := range changes {
+			switch {
+			case c.From == "":
+				line := "+ " + c.Path + " " + c.To
+				if len(c.Via) > 0 {
+					line += " (required by " + strings.Join(c.Via, ", ") + ")"
+				}
+				fmt.Fprintln(out, line)
+			case c.To == "":
+				fmt.Fprintf(out, "- %s %s\n", c.Path, c.From)
+			default:
+				fmt.Fprintf(out, "~ %s %s -> %s\n", c.Path, c.From, c.To)
+			}
+		}
+		return out.close()
+	}
+	if *asJSON {
+		enc := json.NewEncoder(out)
+		enc.SetIndent("", "  ")
+		if err := enc.Encode(struct {
+			SchemaVersion string   `json:"schemaVersion"`
+			Modules       []Module `json:"modules"`
+		}{schemaVersion, modules}); err != nil {
+			return err
+		}
+		return out.close()
+	}
+	maxName := 0
+	for _, x := range modules {
+		maxName = max(maxName, len(x.name))
+	}
+	for _, x := range modules {
+		line := fmt.Sprintf("%s %s -> %s", formatName(x, maxName), x.fromVersion, x.toVersion)
+		if x.patch != "" {
+			line += " (patch " + x.patch + ")"
+		}
+		if len(x.excluded) > 0 {
+			line += " (" + formatExcluded(x.excluded) + ")"
+		}
+		fmt.Fprintln(out, line)
+		if len(x.versions) > 0 {
+			fmt.Fprintf(out, "  %s\n", strings.Join(x.versions, " "))
+		}
+	}
+	return out.close()
+}
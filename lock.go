@@ -0,0 +1,103 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/user"
+	"path/filepath"
+	"time"
+)
+
+const lockName = ".go-mod-upgrade.lock"
+
+// lockInfo is stored in the lock file to tell who holds the lock
+type lockInfo struct {
+	PID      int       `json:"pid"`
+	User     string    `json:"user"`
+	Hostname string    `json:"hostname"`
+	Started  time.Time `json:"started"`
+}
+
+func (l lockInfo) String() string {
+	return fmt.Sprintf("process %d of %s@%s since %s", l.PID, l.User, l.Hostname, l.Started.Format(time.RFC3339))
+}
+
+type moduleLock struct {
+	file string
+}
+
+// acquireLock creates the lock file next to go.mod, so that two invocations
+// can't rewrite go.mod and go.sum at the same time. A lock left behind by a
+// process which no longer runs on this host is taken over.
+func acquireLock() (*moduleLock, error) {
+	root, err := projectRoot()
+	if err != nil {
+		return nil, err
+	}
+	file := filepath.Join(root, lockName)
+	hostname, _ := os.Hostname()
+	username := ""
+	if u, err := user.Current(); err == nil {
+		username = u.Username
+	}
+	info := lockInfo{
+		PID:      os.Getpid(),
+		User:     username,
+		Hostname: hostname,
+		Started:  time.Now(),
+	}
+	data, err := json.Marshal(info)
+	if err != nil {
+		return nil, err
+	}
+	for attempt := 0; attempt < 2; attempt++ {
+		f, err := os.OpenFile(file, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0644)
+		if os.IsExist(err) {
+			holder, rerr := readLock(file)
+			if rerr == nil && holder.Hostname == hostname && !processExists(holder.PID) {
+				if err := os.Remove(file); err != nil {
+					return nil, err
+				}
+				continue
+			}
+			if rerr != nil {
+				return nil, fmt.Errorf("%s is locked by another go-mod-upgrade, remove %s if no other instance is running", root, file)
+			}
+			return nil, fmt.Errorf("%s is locked by %s, remove %s if that process is gone", root, holder, file)
+		}
+		if err != nil {
+			return nil, err
+		}
+		if _, err := f.Write(data); err != nil {
+			f.Close()
+			os.Remove(file)
+			return nil, err
+		}
+		if err := f.Close(); err != nil {
+			return nil, err
+		}
+		return &moduleLock{file: file}, nil
+	}
+	return nil, fmt.Errorf("could not acquire lock %s", file)
+}
+
+func readLock(file string) (lockInfo, error) {
+	var info lockInfo
+	data, err := ioutil.ReadFile(file)
+	if err != nil {
+		return info, err
+	}
+	err = json.Unmarshal(data, &info)
+	return info, err
+}
+
+func (l *moduleLock) release() {
+	if l == nil {
+		return
+	}
+	if err := os.Remove(l.file); err != nil && !os.IsNotExist(err) {
+		fmt.Printf("Error while removing lock %v\n", err)
+	}
+}
@@ -2,19 +2,24 @@ package main
 
 import (
 	"bytes"
+	"encoding/json"
+	"errors"
 	"flag"
 	"fmt"
 	"log"
 	"os"
 	"os/exec"
-	"regexp"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"strconv"
 	"strings"
+	"time"
 
-	"github.com/AlecAivazis/survey/v2"
 	term "github.com/AlecAivazis/survey/v2/terminal"
 	"github.com/Masterminds/semver/v3"
 	"github.com/fatih/color"
-	"golang.org/x/crypto/ssh/terminal"
+	"github.com/kballard/go-shellquote"
 )
 
 func max(x, y int) int {
@@ -31,32 +36,63 @@ func padRight(str string, length int) string {
 	return str + strings.Repeat(" ", length-len(str))
 }
 
-func formatName(module Module, length int) string {
-	c := color.New(color.FgWhite).SprintFunc()
-	from := module.from
-	to := module.to
-	if from.Minor() != to.Minor() {
-		c = color.New(color.FgYellow).SprintFunc()
-	}
-	if from.Patch() != to.Patch() {
-		c = color.New(color.FgGreen).SprintFunc()
+// maxNameWidth is the width of the module names in the picker, longer ones
+// being truncated in the middle
+const maxNameWidth = 48
+
+// truncateName shortens the path to width, keeping its host and its last
+// elements, as gopkg.in/...v2/yaml
+func truncateName(path string, width int) string {
+	if len(path) <= width {
+		return path
 	}
-	if from.Prerelease() != to.Prerelease() {
-		c = color.New(color.FgRed).SprintFunc()
+	head := width / 3
+	return path[:head] + "..." + path[len(path)-(width-head-3):]
+}
+
+var severityColors = themes["default"].severities
+
+// targetColor highlights the changed parts of the target version
+var targetColor = themes["default"].target
+
+func formatName(module Module, length int) string {
+	if accessible {
+		return module.name + severityLabel(module)
 	}
+	c := color.New(severityColors[module.severity]...).SprintFunc()
 	return c(padRight(module.name, length))
 }
 
-func formatFrom(from *semver.Version, length int) string {
+// displayVersion returns the version without its v prefix, or the raw
+// version when it is not semver
+func displayVersion(v *semver.Version, raw string) string {
+	if v == nil {
+		return raw
+	}
+	return v.String()
+}
+
+func formatFrom(module Module, length int) string {
 	c := color.New(color.FgBlue).SprintFunc()
-	return c(padRight(from.String(), length))
+	return c(padRight(displayVersion(module.from, module.fromVersion), length))
+}
+
+func formatGroup(group string, length int) string {
+	c := color.New(color.FgMagenta).SprintFunc()
+	if group == "" {
+		return strings.Repeat(" ", length+3)
+	}
+	return c(padRight("["+group+"]", length+2)) + " "
 }
 
 func formatTo(module Module) string {
-	green := color.New(color.FgGreen).SprintFunc()
+	green := color.New(targetColor...).SprintFunc()
 	var buf bytes.Buffer
 	from := module.from
 	to := module.to
+	if from == nil || to == nil {
+		return green(module.toVersion)
+	}
 	same := true
 	fmt.Fprintf(&buf, "%d.", to.Major())
 	if from.Minor() == to.Minor() {
@@ -79,102 +115,484 @@ func formatTo(module Module) string {
 		}
 	}
 	if to.Metadata() != "" {
-		fmt.Fprintf(&buf, "%s%s", green("+"), green(to.Metadata()))
+		if from.Metadata() == to.Metadata() {
+			fmt.Fprintf(&buf, "+%s", to.Metadata())
+		} else {
+			fmt.Fprintf(&buf, "%s%s", green("+"), green(to.Metadata()))
+		}
 	}
 	return buf.String()
 }
 
 type Module struct {
-	name string
-	from *semver.Version
-	to   *semver.Version
+	name        string
+	fromVersion string
+	toVersion   string
+	// from and to are nil when the versions are not semver
+	from     *semver.Version
+	to       *semver.Version
+	severity Severity
+	fromTime time.Time
+	toTime   time.Time
+	group    string
+	review   bool
+	vulns    []vulnerability
+	// minimum is set when to is the minimum version needed, which an
+	// earlier update may already have reached
+	minimum bool
+	// columns are added by plugins
+	columns map[string]string
+	// checksum is the checksum database status of the target version
+	checksum string
+	// provenance is the attestation status of the target version
+	provenance string
+	// dir is the directory of the module requiring it in recursive mode
+	dir string
+	// versions lists the available versions after the current one, up to
+	// the target, with list --versions
+	versions []string
+	// sizeDelta is the change of the binary size caused by the upgrade, with
+	// --size
+	sizeDelta *int64
+	// buildTimeDelta is the change of the build time, with --build-time
+	buildTimeDelta *time.Duration
+	// analysis is the change of the analyzer findings, with --analyze
+	analysis *analysis
+	// held tells why the module is held back, by a pin, an ignore rule or a
+	// replace directive
+	held string
+	// excluded are the versions newer than the target which go.mod excludes
+	excluded []string
+	// preselected modules are selected in the picker from the start
+	preselected bool
+	// patch is the latest patch of the current minor version, when looked
+	// up and before the target
+	patch string
+	// snoozeExpired is set when an ignore rule snoozed the module until a
+	// date which is over
+	snoozeExpired bool
+	// members are the updates of the same dependency across the workspace
+	// modules, collapsed into this one
+	members []Module
+}
+
+// newModule parses the versions tolerantly, an update between versions which
+// are not semver gets the non-semver severity but can still be applied
+func newModule(name, fromVersion, toVersion string) Module {
+	from, err := semver.NewVersion(fromVersion)
+	if err != nil {
+		from = nil
+	}
+	to, err := semver.NewVersion(toVersion)
+	if err != nil {
+		to = nil
+	}
+	return Module{
+		name:        name,
+		fromVersion: fromVersion,
+		toVersion:   toVersion,
+		from:        from,
+		to:          to,
+		severity:    severity(from, to),
+	}
+}
+
+func (m Module) MarshalJSON() ([]byte, error) {
+	var buildTime *float64
+	if m.buildTimeDelta != nil {
+		seconds := m.buildTimeDelta.Seconds()
+		buildTime = &seconds
+	}
+	var fixed, introduced []string
+	if m.analysis != nil {
+		fixed, introduced = m.analysis.fixed, m.analysis.introduced
+	}
+	return json.Marshal(struct {
+		Path       string            `json:"path"`
+		From       string            `json:"from"`
+		To         string            `json:"to"`
+		Severity   Severity          `json:"severity"`
+		Review     bool              `json:"review,omitempty"`
+		Columns    map[string]string `json:"columns,omitempty"`
+		Checksum   string            `json:"checksum,omitempty"`
+		Provenance string            `json:"provenance,omitempty"`
+		Dir        string            `json:"dir,omitempty"`
+		Versions   []string          `json:"versions,omitempty"`
+		Patch      string            `json:"patch,omitempty"`
+		Excluded   []string          `json:"excluded,omitempty"`
+		SizeDelta  *int64            `json:"size_delta,omitempty"`
+		BuildTime  *float64          `json:"build_time_delta_seconds,omitempty"`
+		Fixed      []string          `json:"fixed_findings,omitempty"`
+		Introduced []string          `json:"introduced_findings,omitempty"`
+	}{m.name, m.fromVersion, m.toVersion, m.severity, m.review, m.columns, m.checksum, m.provenance, m.dir, m.versions, m.patch, m.excluded, m.sizeDelta, buildTime, fixed, introduced})
+}
+
+// goModule is a module as reported by go list -m -json
+type goModule struct {
+	Path     string
+	Version  string
+	Time     *time.Time
+	Update   *goModule
+	Replace  *goModule
+	Versions []string
+	// Retracted and Deprecated are only reported with -retracted and -u
+	Retracted  []string
+	Deprecated string
+	Main       bool
+	Indirect   bool
+}
+
+// goList runs go list with args, which must include -m -json, and decodes
+// the modules it reports
+func goList(dir string, debug bool, args ...string) ([]goModule, error) {
+	list, err := goCommandIn(dir, args...).Output()
+	if debug {
+		fmt.Fprintf(os.Stderr, "go %s\n%s", strings.Join(args, " "), list)
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) {
+			fmt.Fprintf(os.Stderr, "%s", exitErr.Stderr)
+		}
+	}
+	if err != nil {
+		return nil, newGoError(args, err)
+	}
+	modules := []goModule{}
+	dec := json.NewDecoder(bytes.NewReader(list))
+	for dec.More() {
+		var m goModule
+		if err := dec.Decode(&m); err != nil {
+			return nil, fmt.Errorf("%w: %v", errParse, err)
+		}
+		modules = append(modules, m)
+	}
+	return modules, nil
+}
+
+func discover(verbose, debug bool, events *eventLog) ([]Module, error) {
+	events.emit(event{Event: "discovery_started"})
+	return discoverIn("", verbose, debug, events)
 }
 
-func discover(verbose bool) ([]Module, error) {
-	fmt.Println("Discovering modules...")
-	args := []string{
-		"list",
-		"-u",
-		"-mod=mod",
-		"-f",
-		"'{{if (and (not (or .Main .Indirect)) .Update)}}{{.Path}}: {{.Version}} -> {{.Update.Version}}{{end}}'",
-		"-m",
-		"all",
+// discoverIn discovers the updates of the module in dir
+func discoverIn(dir string, verbose, debug bool, events *eventLog) ([]Module, error) {
+	list, err := goList(dir, debug, "list", "-u", "-mod=mod", "-json", "-m", "all")
+	if err != nil {
+		return nil, err
 	}
-	list, err := exec.Command("go", args...).Output()
+	excludes, err := goModExcludes(dir)
 	if err != nil {
 		return nil, err
 	}
-	split := strings.Split(string(list), "\n")
 	modules := []Module{}
-	re := regexp.MustCompile(`'(.+): (.+) -> (.+)'`)
-	for _, x := range split {
-		if x != "''" && x != "" {
-			matched := re.FindStringSubmatch(x)
-			if len(matched) < 4 {
-				return nil, fmt.Errorf("Couldn't parse module %s", x)
-			}
-			name, from, to := matched[1], matched[2], matched[3]
-			if verbose {
-				fmt.Printf("Found module %s, from %s to %s\n", name, from, to)
-			}
-			fromversion, err := semver.NewVersion(from)
-			if err != nil {
-				return nil, err
+	upToDate := []goModule{}
+	for _, m := range list {
+		if m.Main {
+			continue
+		}
+		if m.Indirect {
+			explanations.hide(dir, m.Path, "indirect dependency, see go-mod-upgrade indirect")
+			continue
+		}
+		if m.Update == nil {
+			// The go command skips the excluded versions, which hold the
+			// module back when they are the only newer ones
+			if newer := newerExcluded(excludes[m.Path], m.Version); len(newer) > 0 {
+				d := newModule(m.Path, m.Version, newer[0])
+				d.dir = dir
+				d.held = formatExcluded(newer)
+				modules = append(modules, d)
+			} else {
+				upToDate = append(upToDate, m)
 			}
-			toversion, err := semver.NewVersion(to)
-			if err != nil {
-				return nil, err
+			continue
+		}
+		if verbose && dir != "" {
+			verbosef(m.Path, "Found in %s, from %s to %s", dir, m.Version, m.Update.Version)
+		} else if verbose {
+			verbosef(m.Path, "Found, from %s to %s", m.Version, m.Update.Version)
+		}
+		d := newModule(m.Path, m.Version, m.Update.Version)
+		d.dir = dir
+		d.excluded = newerExcluded(excludes[m.Path], m.Update.Version)
+		if m.Replace != nil {
+			d.held = "replaced by " + m.Replace.Path
+			if m.Replace.Version != "" {
+				d.held += "@" + m.Replace.Version
 			}
-			d := Module{
-				name: name,
-				from: fromversion,
-				to:   toversion,
+		}
+		if verbose && d.severity == SeverityNonSemver {
+			verbosef(m.Path, "Not using semver, versions can't be compared")
+		}
+		if m.Time != nil {
+			d.fromTime = *m.Time
+		}
+		if m.Update.Time != nil {
+			d.toTime = *m.Update.Time
+		}
+		events.moduleEvent("module_found", d, nil)
+		modules = append(modules, d)
+	}
+	explanations.upToDate(dir, upToDate, debug)
+	if patchTargets && len(modules) > 0 {
+		paths := []string{}
+		for _, x := range modules {
+			paths = append(paths, x.name)
+		}
+		patches, err := patchUpdates(dir, debug, paths)
+		if err != nil {
+			return nil, err
+		}
+		for i, x := range modules {
+			if p, ok := patches[x.name]; ok && p != x.toVersion {
+				modules[i].patch = p
 			}
-			modules = append(modules, d)
 		}
 	}
 	return modules, nil
 }
 
-func choose(modules []Module, pageSize int) []Module {
-	maxName := 0
-	maxFrom := 0
-	maxTo := 0
-	for _, x := range modules {
-		maxName = max(maxName, len(x.name))
-		maxFrom = max(maxFrom, len(x.from.String()))
-		maxTo = max(maxTo, len(x.to.String()))
-	}
-	fd := int(os.Stdout.Fd())
-	termWidth, _, err := terminal.GetSize(fd)
+// terminalWidth returns the width of the console, or COLUMNS as set by
+// shells which don't expose one
+func terminalWidth() (int, error) {
+	width, err := consoleWidth()
 	if err != nil {
-		fmt.Printf("Error while getting terminal size %v\n", err)
+		if columns, cerr := strconv.Atoi(os.Getenv("COLUMNS")); cerr == nil && columns > 0 {
+			return columns, nil
+		}
+	}
+	return width, err
+}
+
+func choose(modules, held []Module, pageSize int, offline bool, enrich *enricher) []Module {
+	termWidth, err := terminalWidth()
+	if err != nil && !plain {
+		fmt.Println(tr("Error while getting terminal size %v", err))
+	}
+	var results <-chan enrichResult
+	if enrich != nil {
+		for i := range modules {
+			if modules[i].columns == nil {
+				modules[i].columns = map[string]string{}
+			}
+			for _, c := range enrichColumns {
+				modules[i].columns[c] = pendingValue
+			}
+		}
+		results = enrich.start(modules)
+		// The plain mode has nothing to refresh, its list is only printed once
+		if plain {
+			for r := range results {
+				modules[r.index].columns[r.column] = r.value
+			}
+		}
+	}
+	// format returns the options and their search texts, again as the
+	// enriched columns come in
+	format := func() ([]string, []string) {
+		maxName := 0
+		maxFrom := 0
+		maxTo := 0
+		maxGroup := 0
+		maxColumns := map[string]int{}
+		maxDir := 0
+		for _, x := range modules {
+			maxGroup = max(maxGroup, len(x.group))
+			maxDir = max(maxDir, len(x.dir))
+			for c, v := range x.columns {
+				maxColumns[c] = max(maxColumns[c], len(v))
+			}
+			maxName = max(maxName, len(x.name))
+			maxFrom = max(maxFrom, len(displayVersion(x.from, x.fromVersion)))
+			maxTo = max(maxTo, len(displayVersion(x.to, x.toVersion)))
+		}
+		columns := []string{}
+		for c := range maxColumns {
+			columns = append(columns, c)
+		}
+		sort.Strings(columns)
+		// The details show the full paths, the plain mode has none
+		if !plain && maxName > maxNameWidth {
+			maxName = maxNameWidth
+		}
+		options := []string{}
+		search := []string{}
+		for _, x := range modules {
+			from := ""
+			// Only show from when the terminal width is big enough
+			// As there is a bug in survey when the terminal overflows
+			// https://github.com/AlecAivazis/survey/issues/101
+			if plain || termWidth > maxName+maxFrom+maxTo+11 {
+				from = formatFrom(x, maxFrom)
+			}
+			group := ""
+			if maxDir > 0 {
+				group = padRight(x.dir, maxDir) + " "
+			}
+			if maxGroup > 0 {
+				group += formatGroup(x.group, maxGroup)
+			}
+			review := ""
+			if x.review {
+				review = color.New(color.FgRed).Sprint(" (review required)")
+			}
+			extra := ""
+			if len(columns) > 0 {
+				// Align the columns after the target version
+				extra = padRight("", maxTo-len(displayVersion(x.to, x.toVersion)))
+			}
+			for _, c := range columns {
+				extra += " " + padRight(x.columns[c], maxColumns[c])
+			}
+			switch x.checksum {
+			case checksumMissing, checksumExcluded, checksumDisabled:
+				review += color.New(color.FgYellow).Sprintf(" (checksum %s)", x.checksum)
+			}
+			if x.snoozeExpired {
+				review += color.New(color.FgYellow).Sprint(" (snooze expired)")
+			}
+			switch x.provenance {
+			case provenanceNone:
+				review += color.New(color.FgYellow).Sprint(" (no provenance)")
+			case provenanceUnverified:
+				review += color.New(color.FgYellow).Sprint(" (provenance unverified)")
+			}
+			if x.patch != "" {
+				review += color.New(color.Faint).Sprintf(" (patch %s)", strings.TrimPrefix(x.patch, "v"))
+			}
+			if len(x.excluded) > 0 {
+				review += color.New(color.FgYellow).Sprintf(" (%s)", formatExcluded(x.excluded))
+			}
+			if len(x.members) > 0 {
+				review += color.New(color.Faint).Sprintf(" (%s)", memberVersions(x))
+			}
+			arrow := "->"
+			if accessible {
+				arrow = tr("to")
+			}
+			short := x
+			short.name = truncateName(x.name, maxName)
+			options = append(options, fmt.Sprintf("%s%s %s %s %s%s%s", group, formatName(short, maxName), from, arrow, formatTo(x), extra, review))
+			search = append(search, x.name+" "+options[len(options)-1])
+		}
+		return options, search
 	}
-	options := []string{}
+	options, search := format()
+	message := tr("Choose which modules to update")
+	if offline {
+		message += tr(" (offline, possibly stale)")
+	}
+	maxHeld := 0
+	for _, x := range held {
+		maxHeld = max(maxHeld, len(x.name))
+	}
+	heldOptions := []string{}
+	for _, x := range held {
+		heldOptions = append(heldOptions, fmt.Sprintf("%s %s (%s)", padRight(x.name, maxHeld), x.toVersion, x.held))
+	}
+	remembered := loadSelection()
+	defaults := []int{}
+	for i, x := range modules {
+		if remembered[x.name] || x.preselected {
+			defaults = append(defaults, i)
+		}
+	}
+	severities := []Severity{}
 	for _, x := range modules {
-		from := ""
-		// Only show from when the terminal width is big enough
-		// As there is a bug in survey when the terminal overflows
-		// https://github.com/AlecAivazis/survey/issues/101
-		if termWidth > maxName+maxFrom+maxTo+11 {
-			from = formatFrom(x.from, maxFrom)
+		severities = append(severities, x.severity)
+	}
+	prompt := &picker{
+		Message:    message,
+		Options:    options,
+		Search:     search,
+		Severities: severities,
+		Default:    defaults,
+		Held:       heldOptions,
+		PageSize:   pageSize,
+	}
+	graphs := map[string]*requirementGraph{}
+	prompt.Details = func(i int) []string {
+		dir := modules[i].dir
+		if len(modules[i].members) > 0 {
+			dir = modules[i].members[0].dir
 		}
-		options = append(options, fmt.Sprintf("%s %s -> %s", formatName(x, maxName), from, formatTo(x)))
+		path := []string{fmt.Sprintf("%s %s -> %s", modules[i].name, modules[i].fromVersion, modules[i].toVersion)}
+		r, ok := graphs[dir]
+		if !ok {
+			var err error
+			if r, err = loadRequirements(dir, false); err != nil {
+				return append(path, strings.Split(err.Error(), "\n")...)
+			}
+			graphs[dir] = r
+		}
+		return append(path, r.tree(modules[i].name)...)
 	}
-	prompt := &survey.MultiSelect{
-		Message:  "Choose which modules to update",
-		Options:  options,
-		PageSize: pageSize,
+	done := make(chan struct{})
+	stopped := make(chan struct{})
+	if results != nil && !plain {
+		refresh := make(chan pickerRefresh)
+		prompt.Refresh = refresh
+		go func() {
+			defer close(stopped)
+			for {
+				select {
+				case r, ok := <-results:
+					if !ok {
+						return
+					}
+					modules[r.index].columns[r.column] = r.value
+					options, search := format()
+					select {
+					case refresh <- pickerRefresh{options, search}:
+					case <-done:
+						return
+					}
+				case <-done:
+					return
+				}
+			}
+		}()
+	} else {
+		close(stopped)
 	}
 	choice := []int{}
-	err = survey.AskOne(prompt, &choice)
+	err = ask(prompt, &choice)
+	close(done)
+	<-stopped
+	if enrich != nil {
+		// The values still pending are not worth waiting for
+		enrich.cancel()
+		for i := range modules {
+			for _, c := range enrichColumns {
+				if modules[i].columns[c] == pendingValue {
+					modules[i].columns[c] = ""
+				}
+			}
+		}
+		enrich.report()
+	}
+	// Remember the selection, even when interrupted, for the next run
+	current := selection{}
+	for i, x := range modules {
+		if checked, ok := prompt.checked[i]; ok {
+			current[x.name] = checked
+		}
+	}
+	if serr := current.save(); serr != nil && err == nil {
+		fmt.Println(tr("Error while saving selection %v", serr))
+	}
 	if err == term.InterruptErr {
-		fmt.Println("Bye")
+		fmt.Println(tr("Bye"))
 		os.Exit(0)
 	} else if err != nil {
 		log.Fatal(err)
 	}
+	for i, x := range modules {
+		if prompt.excluded[i] {
+			if err := excludeTarget(x); err != nil {
+				fmt.Println(err)
+			}
+		}
+	}
 	updates := []Module{}
 	for _, x := range choice {
 		updates = append(updates, modules[x])
@@ -182,30 +600,737 @@ func choose(modules []Module, pageSize int) []Module {
 	return updates
 }
 
-func update(modules []Module) {
-	for _, x := range modules {
-		fmt.Fprintf(color.Output, "Updating %s to version %s...\n", formatName(x, len(x.name)), formatTo(x))
-		out, err := exec.Command("go", "get", x.name).CombinedOutput()
+// update applies the updates in order, returning the applied ones. It stops
+// at the first failure when failFast is set, or when interrupted, leaving the
+// journal behind for --resume.
+func update(modules []Module, events *eventLog, j *journal, failFast bool) ([]Module, bool) {
+	applied := []Module{}
+	w := watchInterrupt()
+	defer w.stop()
+	for i, x := range modules {
+		if w.isInterrupted() {
+			reportInterrupted(applied, modules[i:])
+			return applied, false
+		}
+		events.moduleEvent("update_started", x, nil)
+		if x.minimum && reached(x) {
+			progress("%s is already at version %s or later", x.name, x.toVersion)
+			events.moduleEvent("update_succeeded", x, nil)
+			if err := j.done(x); err != nil {
+				fmt.Println(tr("Error while saving progress %v", err))
+			}
+			applied = append(applied, x)
+			continue
+		}
+		if x.dir != "" {
+			progress("Updating %s in %s to version %s...", formatName(x, len(x.name)), x.dir, formatTo(x))
+		} else {
+			progress("Updating %s to version %s...", formatName(x, len(x.name)), formatTo(x))
+		}
+		var err error
+		if len(stepChecks) > 0 {
+			err = stepUpgrade(x)
+		} else if err = goGet(x.dir, x.name, x.toVersion); err != nil {
+			err = remedyChecksum(x, err)
+			if offerAuthHelp(err) {
+				err = goGet(x.dir, x.name, x.toVersion)
+			}
+		}
+		if err != nil && w.isInterrupted() {
+			// The update was aborted by the interrupt, --resume retries it
+			reportInterrupted(applied, modules[i:])
+			return applied, false
+		}
 		if err != nil {
-			fmt.Printf("Error while updating %s: %s\n", x.name, string(out))
+			err = &updateError{path: x.name, from: x.fromVersion, to: x.toVersion, dir: x.dir, err: err}
+		}
+		if err != nil && triage != nil {
+			fmt.Println(tr("Error while updating %s, %s failure", x.name, triage.add(x, err)))
+			events.moduleEvent("update_failed", x, err)
+			failedUpdates = append(failedUpdates, x)
+		} else if err != nil {
+			fmt.Println(tr("Error while updating %s: %v", x.name, err))
+			events.moduleEvent("update_failed", x, err)
+			failedUpdates = append(failedUpdates, x)
+		} else {
+			events.moduleEvent("update_succeeded", x, nil)
+			applied = append(applied, x)
+			// The failed updates stay in the journal, for --resume to retry
+			if err := j.done(x); err != nil {
+				fmt.Println(tr("Error while saving progress %v", err))
+			}
+		}
+		if err != nil && failFast {
+			progress("Stopping at the first failure")
+			return applied, false
+		}
+	}
+	return applied, true
+}
+
+// apply updates the modules, keeping track of the progress in a journal
+// which is only removed once every module has been processed
+func apply(modules []Module, events *eventLog, failFast bool) ([]Module, error) {
+	if len(modules) == 0 {
+		return nil, nil
+	}
+	for _, dir := range moduleDirsOf(modules) {
+		if err := checkWritable(dir); err != nil {
+			return nil, err
+		}
+	}
+	lock, err := acquireLock()
+	if err != nil {
+		return nil, err
+	}
+	defer lock.release()
+	j := newJournal(modules)
+	if err := j.save(); err != nil {
+		fmt.Println(tr("Error while saving progress %v", err))
+		j = nil
+	}
+	applied, ok := update(modules, events, j, failFast)
+	if err := revendorAll(applied); err != nil {
+		return applied, err
+	}
+	if ok && j != nil {
+		if err := j.remove(); err != nil {
+			fmt.Println(tr("Error while removing progress %v", err))
 		}
 	}
+	return applied, nil
+}
+
+// goEnv holds extra environment variables for every go command we run
+var goEnv []string
+
+func goCommand(args ...string) goCmd {
+	return goCommandIn("", args...)
+}
+
+// goCommandIn runs the go command in the module directory dir, the current
+// directory when empty
+func goCommandIn(dir string, args ...string) goCmd {
+	binary := goBinary
+	if b, ok := memberGo[dir]; ok {
+		binary = b
+	}
+	cmd := exec.Command(binary, args...)
+	cmd.Dir = dir
+	if len(goEnv) > 0 {
+		cmd.Env = append(os.Environ(), goEnv...)
+	}
+	return goCmd{cmd}
+}
+
+// reached reports whether the module is already required at its target
+// version or later
+func reached(m Module) bool {
+	out, err := goCommandIn(m.dir, "list", "-m", "-f", "{{.Version}}", m.name).Output()
+	if err != nil || m.to == nil {
+		return false
+	}
+	current, err := semver.NewVersion(strings.TrimSpace(string(out)))
+	return err == nil && !current.LessThan(m.to)
+}
+
+// goModFile returns the path of the go.mod file of the current module
+func goModFile() (string, error) {
+	return goModFileIn("")
+}
+
+// projectRoot is the directory holding the configuration and the lock: the
+// workspace root in recursive mode, else the directory of go.mod
+func projectRoot() (string, error) {
+	if workspaceRoot != "" {
+		return workspaceRoot, nil
+	}
+	gomod, err := moduleGoMod("")
+	if err != nil {
+		return "", err
+	}
+	return filepath.Dir(gomod), nil
+}
+
+// goModFileIn returns the go.mod file of the module in dir, the alternate
+// one when given
+func goModFileIn(dir string) (string, error) {
+	if modFile != "" {
+		return modFile, nil
+	}
+	return moduleGoMod(dir)
+}
+
+// moduleGoMod returns the go.mod file at the root of the module in dir,
+// even when an alternate one is used
+func moduleGoMod(dir string) (string, error) {
+	out, err := goCommandIn(dir, "env", "GOMOD").Output()
+	if err != nil {
+		return "", err
+	}
+	gomod := strings.TrimSpace(string(out))
+	if gomod == "" || gomod == os.DevNull {
+		return "", errNoGoMod
+	}
+	return gomod, nil
 }
 
 func main() {
+	enableVirtualTerminal()
 	var verbose bool
 	var pageSize int
+	var eventsTarget string
+	var offline bool
+	var debug bool
+	var resume bool
+	var maxUpdates int
+	var priorityOrder string
+	var failFast bool
+	var securityOnly bool
+	var reportFile string
+	var sbomFile string
+	var submitDeps bool
+	var copySummary bool
+	var hook bool
+	var changelogs bool
+	var githubToken string
+	var checksums bool
+	var checkAttestations bool
+	var recursive bool
+	var alignGo bool
+	var jobs int
+	var updateTemplate string
+	var bazel bool
+	var auditFile string
+	var failuresFile string
+	var planFile string
+	var majorReview bool
+	var commitBatches bool
+	var themeName string
+	var lang string
+	var stepwise bool
+	var sizePackage string
+	var platformList string
+	var analyze bool
+	var enrich bool
+	var explain bool
+	var extraGetArgs string
+	var testDeps string
+	var buildTime bool
+	var refreshProxy bool
+	var pullRequests bool
+	var majorIssues bool
 	flag.IntVar(&pageSize, "p", 10, "Specify page size, Default is 10")
 	flag.BoolVar(&verbose, "v", false, "Verbose mode")
+	flag.StringVar(&eventsTarget, "events", "", "Write NDJSON progress events to a file descriptor number or file path")
+	flag.BoolVar(&offline, "offline", false, "Only report upgrades resolvable from the local module cache")
+	flag.BoolVar(&refreshProxy, "refresh-proxy", false, "Look up the latest versions in the repositories, for releases the module proxy doesn't list yet")
+	flag.BoolVar(&debug, "debug", false, "Dump the raw output of the go commands")
+	flag.BoolVar(&resume, "resume", false, "Resume an interrupted update session")
+	flag.IntVar(&maxUpdates, "max-updates", 0, "Maximum number of modules to update, most significant updates first")
+	flag.StringVar(&priorityOrder, "priority", defaultPriority, "Order in which updates are applied, by severity, security ranking the ones fixing a known vulnerability")
+	flag.BoolVar(&failFast, "fail-fast", false, "Stop at the first update which fails")
+	flag.BoolVar(&securityOnly, "only-security", false, "Only update the modules with known vulnerabilities, to their fixed version, without asking")
+	flag.StringVar(&reportFile, "report", "", "Write a markdown report of the updates to a file, or an HTML one for .html files")
+	flag.BoolVar(&copySummary, "copy", false, "Copy the markdown table of the applied updates to the clipboard, for the pull request description")
+	flag.BoolVar(&submitDeps, "submit-dependencies", false, "Submit the dependencies to the GitHub dependency graph after the updates")
+	flag.StringVar(&sbomFile, "sbom", "", "Write a CycloneDX SBOM of the module after the updates, uploaded to Dependency-Track when configured")
+	flag.BoolVar(&changelogs, "changelog", false, "Summarize the release notes of the updates in the report")
+	flag.StringVar(&githubToken, "github-token", "", "GitHub API token, defaults to the GITHUB_TOKEN or GH_TOKEN environment variables")
+	flag.StringVar(&allowlistLocation, "allowlist", "", "File or URL of the approved versions, refusing upgrades to other versions")
+	flag.BoolVar(&allowlistOverride, "override", false, "Upgrade to versions missing from the allowlist anyway")
+	flag.BoolVar(&metadata.disabled, "no-cache", false, "Fetch the changelogs, checksum and provenance statuses again instead of using the cached ones")
+	flag.BoolVar(&checksums, "checksums", false, "Look up the target versions in the checksum database")
+	flag.BoolVar(&checkAttestations, "provenance", false, "Check the provenance attestations of the target versions on GitHub")
+	flag.StringVar(&platformList, "platforms", "", "GOOS/GOARCH pairs the module is built for, e.g. linux/amd64,windows/amd64, whose imports are taken into account and which the updated modules are cross-compiled for")
+	flag.StringVar(&buildTags, "tags", "", "Build tags of the go commands, e.g. --tags integration,netgo, so that discovery sees the packages of the builds")
+	flag.StringVar(&modFile, "modfile", "", "Discover and apply the upgrades with an alternate go.mod file, its go.sum being named after it, as go -modfile")
+	flag.BoolVar(&recursive, "r", false, "Recursive mode, update every module of the go.work workspace or below the current directory")
+	flag.BoolVar(&alignGo, "align-toolchains", false, "Align the go and toolchain directives of the workspace modules to the highest ones without asking, in recursive mode")
+	flag.IntVar(&jobs, "jobs", runtime.NumCPU(), "Number of modules discovered concurrently in recursive mode")
+	flag.StringVar(&updateTemplate, "update-command", "", "Command template applying an update, default "+strconv.Quote(defaultUpdateCommand))
+	flag.StringVar(&extraGetArgs, "get-args", "", "Extra arguments of go get, e.g. --get-args \"-t -x\", also given after --")
+	flag.BoolVar(&bazel, "bazel", false, "Sync the Bazel files with go.mod after updating, unless post-update commands are configured")
+	flag.StringVar(&failuresFile, "failures", "", "Write the output of the failed updates, classified, to a markdown file, or a JSON one for .json files, instead of the console")
+	flag.StringVar(&auditFile, "audit", "", "Append the applied updates to an audit trail file (JSON lines)")
+	flag.StringVar(&planFile, "plan", "", "Write the selected upgrades to a plan file for approval instead of applying them")
+	flag.BoolVar(&majorReview, "review-majors", false, "Review the changelog and API changes of each selected major upgrade before including it")
+	flag.BoolVar(&commitBatches, "commit", false, "Commit the updates, each major upgrade in its own commit")
+	flag.BoolVar(&majorIssues, "major-issues", false, "Open a GitHub issue tracking each major upgrade with the GitHub CLI, instead of listing it")
+	flag.BoolVar(&pullRequests, "pr", false, "Open a pull request per commit with the GitHub CLI, each major upgrade in its own")
+	flag.StringVar(&sizePackage, "size", "", "Report the binary size change of the package caused by each selected upgrade, e.g. --size ./cmd/app")
+	flag.BoolVar(&explain, "explain", false, "Explain why each module is shown or hidden: indirect, up to date, replaced, pinned, ignored, denied by the policy...")
+	flag.BoolVar(&enrich, "enrich", false, "Fetch the release dates, release notes, fixed vulnerabilities and licenses of the updates in the background, filling in the picker as they come")
+	flag.BoolVar(&analyze, "analyze", false, "Experimental: run the analyzer of the configuration, go vet ./... by default, before and after each upgrade, preselecting the upgrades fixing findings without introducing any")
+	flag.BoolVar(&buildTime, "build-time", false, "Report the build time change caused by each selected upgrade, of the --size package or ./...")
+	flag.StringVar(&testDeps, "test-deps", testDepsInclude, "Modules only needed by tests: include them, only update them, or exclude them")
+	flag.BoolVar(&patchTargets, "patch", false, "Also look up the latest patch of the current minor version, as go get -u=patch, choosing it or the latest version of each module")
+	flag.StringVar(&profileName, "profile", "", "Profile of the configuration bundling flags and ignore rules, e.g. nightly")
+	flag.BoolVar(&plain, "plain", false, "Ask with numbered questions answered on a line, for dumb terminals, serial consoles and docker exec, default when TERM is dumb")
+	flag.BoolVar(&accessible, "accessible", false, "Plain mode for screen readers, spelling out the state of each module and the severity of the updates")
+	flag.BoolVar(&hook, "hook", false, "Check for vulnerable and stale modules without asking, for pre-commit hooks, exiting with 2 or 3 when the configured policy fails")
+	flag.BoolVar(&quiet, "quiet", false, "Only print a single line summary of the updates, nothing when every module is up to date, for cron jobs and wrappers")
+	flag.BoolVar(&stepwise, "step", false, "Upgrade through the latest patch of each intermediate minor version, checking each step")
+	flag.StringVar(&lang, "lang", "", "Language of the messages, defaults to the one of LC_ALL, LC_MESSAGES or LANG")
+	flag.StringVar(&themeName, "theme", "", "Color theme of the list: default, colorblind or monochrome")
+	if err := setFlagsFromEnv(flag.CommandLine, ""); err != nil {
+		log.Fatal(err)
+	}
 	flag.Parse()
-	modules, err := discover(verbose)
+	if profileName != "" {
+		// The configuration of the recursive mode is the one of the
+		// current directory, which may only hold a go.work
+		if recursive {
+			root, err := filepath.Abs(".")
+			if err != nil {
+				log.Fatal(err)
+			}
+			workspaceRoot = root
+		}
+		cfg, err := loadConfig()
+		if err != nil {
+			log.Fatal(err)
+		}
+		if err := cfg.applyProfile(profileName); err != nil {
+			log.Fatal(err)
+		}
+	}
+	if err := setLanguage(lang); err != nil {
+		log.Fatal(err)
+	}
+	if hook {
+		quiet = true
+	}
+	if explain {
+		explanations = newExplainLog()
+	}
+	if os.Getenv("TERM") == "dumb" || accessible {
+		plain = true
+	}
+	if plain {
+		color.NoColor = true
+	}
+	useToolchain()
+	extra, err := shellquote.Split(extraGetArgs)
+	if err != nil {
+		log.Fatalf("--get-args: %v", err)
+	}
+	getArgs = extra
+	// The arguments after -- are given to go get
+	if n := len(os.Args) - flag.NArg(); n > 1 && os.Args[n-1] == "--" {
+		getArgs = append(getArgs, flag.Args()...)
+	}
+	priority, err := parsePriority(priorityOrder)
+	if err != nil {
+		log.Fatal(err)
+	}
+	env, message, err := modFlags()
+	if err != nil {
+		log.Fatal(err)
+	}
+	goEnv = append(goEnv, env...)
+	if platformList != "" {
+		if targetPlatforms, err = parsePlatforms(platformList); err != nil {
+			log.Fatal(err)
+		}
+	}
+	if modFile != "" && recursive {
+		log.Fatal("--modfile can't be combined with -r, as it names the go.mod of a single module")
+	}
+	if modFile != "" && (sizePackage != "" || buildTime) {
+		log.Fatal("--modfile can't be combined with --size and --build-time, which build a copy of the module")
+	}
+	if offline && refreshProxy {
+		log.Fatal("--offline and --refresh-proxy can't be combined")
+	}
+	if !offline && downloadsDisabled() {
+		notice("Module downloads are disabled by GOPROXY=off, switching to the offline mode: upgrades come from the local module cache, and the checksum database, provenance and fresh release lookups are unavailable")
+		offline = true
+		refreshProxy = false
+	}
+	offlineMode = offline
+	if offline {
+		env, err := offlineEnv()
+		if err != nil {
+			log.Fatal(err)
+		}
+		goEnv = append(goEnv, env...)
+	}
+	var members []string
+	if recursive {
+		if members, err = enterWorkspace(); err != nil {
+			log.Fatal(err)
+		}
+	}
+	if hook {
+		cfg, err := loadConfig()
+		if err != nil {
+			log.Fatal(err)
+		}
+		code, err := runHook(cfg.Hook, debug)
+		if err != nil {
+			log.Fatal(err)
+		}
+		os.Exit(code)
+	}
+	if flag.Arg(0) == "config" {
+		if err := configCommand(flag.Args()[1:]); err != nil {
+			log.Fatal(err)
+		}
+		return
+	}
+	if flag.Arg(0) == "import" {
+		if err := importCommand(flag.Args()[1:]); err != nil {
+			log.Fatal(err)
+		}
+		return
+	}
+	if flag.Arg(0) == "approve" {
+		if err := approveCommand(flag.Args()[1:]); err != nil {
+			log.Fatal(err)
+		}
+		return
+	}
+	if flag.Arg(0) == "pin" {
+		if err := pinCommand(flag.Args()[1:]); err != nil {
+			log.Fatal(err)
+		}
+		return
+	}
+	if flag.Arg(0) == "stats" {
+		if err := statsCommand(flag.Args()[1:], debug); err != nil {
+			log.Fatal(err)
+		}
+		return
+	}
+	if flag.Arg(0) == "skew" {
+		if err := skewCommand(flag.Args()[1:]); err != nil {
+			log.Fatal(err)
+		}
+		return
+	}
+	if flag.Arg(0) == "list" {
+		if err := listCommand(flag.Args()[1:], verbose, debug); err != nil {
+			log.Fatal(err)
+		}
+		return
+	}
+	if flag.Arg(0) == "jira" {
+		if err := jiraCommand(flag.Args()[1:], verbose, debug); err != nil {
+			log.Fatal(err)
+		}
+		return
+	}
+	if flag.Arg(0) == "suggest" {
+		if err := suggestCommand(flag.Args()[1:], debug); err != nil {
+			log.Fatal(err)
+		}
+		return
+	}
+	if flag.Arg(0) == "info" {
+		if err := infoCommand(flag.Args()[1:], debug); err != nil {
+			log.Fatal(err)
+		}
+		return
+	}
+	if flag.Arg(0) == "duplicates" {
+		if err := duplicatesCommand(flag.Args()[1:], debug); err != nil {
+			log.Fatal(err)
+		}
+		return
+	}
+	if flag.Arg(0) == "migrate" {
+		if err := migrateCommand(flag.Args()[1:], debug); err != nil {
+			log.Fatal(err)
+		}
+		return
+	}
+	if flag.Arg(0) == "graph" {
+		if err := graphCommand(flag.Args()[1:], debug); err != nil {
+			log.Fatal(err)
+		}
+		return
+	}
+	if flag.Arg(0) == "indirect" {
+		if err := indirectCommand(flag.Args()[1:], debug); err != nil {
+			log.Fatal(err)
+		}
+		return
+	}
+	if flag.Arg(0) == "cleanup" {
+		if err := cleanupCommand(flag.Args()[1:], debug); err != nil {
+			log.Fatal(err)
+		}
+		return
+	}
+	if flag.Arg(0) == "auth" {
+		if err := authCommand(flag.Args()[1:]); err != nil {
+			log.Fatal(err)
+		}
+		return
+	}
+	if flag.Arg(0) == "doctor" {
+		if err := doctorCommand(flag.Args()[1:]); err != nil {
+			log.Fatal(err)
+		}
+		return
+	}
+	if flag.Arg(0) == "serve" {
+		if err := serveCommand(flag.Args()[1:], offline); err != nil {
+			log.Fatal(err)
+		}
+		return
+	}
+	if message != "" {
+		progress("%s", message)
+	}
+	events, err := openEvents(eventsTarget)
+	if err != nil {
+		log.Fatal(err)
+	}
+	cfg, err := loadConfig()
 	if err != nil {
 		log.Fatal(err)
 	}
+	if themeName == "" {
+		themeName = cfg.Theme
+	}
+	if err := applyTheme(themeName, cfg.Colors); err != nil {
+		log.Fatal(err)
+	}
+	hosts, err := newProviders(cfg.Providers, githubToken)
+	if err != nil {
+		log.Fatal(err)
+	}
+	// Changelogs are only fetched for the report when asked
+	var enrichment *providers
+	if changelogs {
+		enrichment = hosts
+	}
+	if updateTemplate == "" {
+		updateTemplate = cfg.UpdateCommand
+	}
+	if updateTemplate != "" {
+		updateCommand = updateTemplate
+		// Report template errors before any update
+		if _, err := updateArgs(updateTarget{Path: "example.com/m", To: "v1.0.0"}); err != nil {
+			log.Fatal(err)
+		}
+	}
+	if failuresFile != "" {
+		triage = &failureReport{file: failuresFile}
+	}
+	if auditFile != "" {
+		cfg.Audit = &auditConfig{File: auditFile, Commit: cfg.Audit != nil && cfg.Audit.Commit}
+	}
+	audit, err := openAudit(cfg.Audit)
+	if err != nil {
+		log.Fatal(err)
+	}
+	allowed, err := configuredAllowlist(cfg)
+	if err != nil {
+		log.Fatal(err)
+	}
+	postUpdate := cfg.PostUpdate
+	if len(postUpdate) == 0 && bazel {
+		root, err := projectRoot()
+		if err != nil {
+			log.Fatal(err)
+		}
+		postUpdate = bazelCommands(root)
+		if len(postUpdate) == 0 {
+			progress("No MODULE.bazel nor WORKSPACE file found, skipping the Bazel sync")
+		}
+	}
+	if stepwise {
+		stepChecks = cfg.StepChecks
+		if len(stepChecks) == 0 {
+			stepChecks = defaultStepChecks
+		}
+	}
+	plugs := findPlugins(verbose)
+	// finish records the applied updates, runs the post-update actions and
+	// writes the report
+	finish := func(applied []Module, err error) {
+		if aerr := audit.record(applied); aerr != nil {
+			fmt.Printf("Error while recording the audit trail %v\n", aerr)
+		}
+		if terr := triage.write(); terr != nil {
+			fmt.Printf("Error while writing the failures %v\n", terr)
+		}
+		if err != nil {
+			log.Fatal(err)
+		}
+		verifyPlatforms(applied)
+		if len(applied) > 0 {
+			if err := runPostUpdate(postUpdate); err != nil {
+				fmt.Println(err)
+			}
+		}
+		plugs.postUpdate(applied)
+		if reportFile != "" {
+			if err := writeReport(reportFile, applied, enrichment, plugs); err != nil {
+				log.Fatal(err)
+			}
+		}
+		if sbomFile != "" {
+			if err := writeSBOM(sbomFile, cfg.DependencyTrack, debug); err != nil {
+				log.Fatal(err)
+			}
+		}
+		if copySummary && len(applied) > 0 {
+			if summary, err := markdownReport(newReportData(applied, nil)); err != nil {
+				fmt.Println(err)
+			} else if err := copyToClipboard(summary); err != nil {
+				fmt.Println(tr("Error while copying the summary %v", err))
+			} else {
+				progress("Copied the summary of the updates to the clipboard")
+			}
+		}
+		if submitDeps {
+			if err := submitDependencies(githubToken, debug); err != nil {
+				fmt.Println(err)
+			}
+		}
+		printSummary(applied)
+	}
+	if resume {
+		finish(resumeUpdate(events, failFast, allowed))
+		return
+	}
+	if flag.Arg(0) == "apply" {
+		modules, err := planModules(flag.Args()[1:])
+		if err != nil {
+			log.Fatal(err)
+		}
+		finish(apply(allowed.enforce(modules), events, failFast))
+		return
+	}
+	if securityOnly {
+		finish(onlySecurity(debug, events, failFast, allowed))
+		return
+	}
+	if _, err := loadJournal(); err == nil {
+		progress("A previous update session was interrupted, run with --resume to continue it")
+	}
+	progress("Discovering modules...")
+	if offline {
+		progress("Offline mode: upgrades come from the local module cache and may be stale")
+	}
+	// The proxy caches the latest version of a module for a while, but
+	// fetches the versions it is asked for, so only the discovery skips it
+	proxyEnv := goEnv
+	if refreshProxy {
+		progress("Looking up the latest versions in the repositories, bypassing the module proxy")
+		goEnv = append(goEnv[:len(goEnv):len(goEnv)], "GOPROXY=direct")
+	}
+	var modules []Module
+	if recursive {
+		if err := checkToolchainSkew(members, alignGo); err != nil {
+			log.Fatal(err)
+		}
+		modules, err = discoverAll(members, jobs, verbose, debug, events)
+	} else {
+		modules, err = discover(verbose, debug, events)
+	}
+	goEnv = proxyEnv
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		if errors.Is(err, errProxyUnreachable) && !offline {
+			fmt.Println(tr("Run with --offline to look for upgrades in the local module cache"))
+		} else if offerAuthHelp(err) {
+			fmt.Println("Run go-mod-upgrade again to use the new settings")
+		}
+		os.Exit(1)
+	}
+	held := cfg.heldBack(modules)
+	modules, err = cfg.filter(modules, verbose)
+	if err != nil {
+		log.Fatal(err)
+	}
+	if modules, err = filterTestDeps(modules, testDeps); err != nil {
+		log.Fatal(err)
+	}
+	if len(modules) == 0 && len(held) > 0 && !quiet {
+		fmt.Println(tr("Held back:"))
+		for _, x := range held {
+			fmt.Fprintf(color.Output, "  %s %s (%s)\n", formatName(x, len(x.name)), x.toVersion, x.held)
+		}
+	}
+	modules = allowed.enforce(modules)
+	if majorIssues {
+		if modules, err = openMajorIssues(modules, cfg.MajorIssues, hosts, offline); err != nil {
+			log.Fatal(err)
+		}
+	}
+	if recursive {
+		modules = aggregate(modules)
+	}
+	if checksums && offline {
+		progress("Skipping the checksum database lookups in offline mode")
+	} else if checksums {
+		if err := checkChecksums(modules); err != nil {
+			log.Fatal(err)
+		}
+	}
+	if checkAttestations && offline {
+		progress("Skipping the provenance checks in offline mode")
+	} else if checkAttestations {
+		checkProvenance(hosts, modules)
+	}
+	plugs.columns(modules)
+	if analyze && len(modules) > 0 {
+		analyzeUpgrades(modules, cfg.Analyzer)
+	}
+	var background *enricher
+	if enrich && offline {
+		progress("Skipping the enrichment in offline mode")
+	} else if enrich {
+		background = newEnricher(cfg.Enrichment, hosts)
+	}
+	explanations.print(modules)
 	if len(modules) > 0 {
-		modules = choose(modules, pageSize)
-		update(modules)
+		modules = choose(modules, held, pageSize, offline, background)
+		if patchTargets {
+			modules = choosePatches(modules)
+		}
+		if majorReview {
+			modules = reviewMajors(modules, hosts, offline)
+		}
+		modules = expand(modules)
+		if (contains(priority, prioritySecurity) || maxUpdates > 0) && len(modules) > 1 {
+			if offline {
+				progress("Skipping the vulnerability lookups of the security priority in offline mode")
+			} else {
+				markVulnerable(modules)
+			}
+		}
+		var skipped []Module
+		modules, skipped = limitUpdates(prioritize(modules, priority), maxUpdates)
+		for _, x := range skipped {
+			progress("Skipping %s, limited to %d updates", formatName(x, len(x.name)), maxUpdates)
+		}
+		if buildTime && sizePackage == "" {
+			sizePackage = "./..."
+		}
+		if sizePackage != "" && len(modules) > 0 {
+			if err := measureImpact(modules, sizePackage, buildTime); err != nil {
+				log.Fatal(err)
+			}
+		}
+		if planFile != "" {
+			if err := writePlan(planFile, modules); err != nil {
+				log.Fatal(err)
+			}
+			fmt.Println(tr("Proposed %d upgrade(s) in %s, to apply once approved with go-mod-upgrade apply --from-plan %s", len(modules), planFile, planFile))
+			return
+		}
+		if commitBatches || pullRequests {
+			finish(applyBatches(modules, events, failFast, pullRequests))
+		} else {
+			finish(apply(modules, events, failFast))
+		}
 	} else {
-		fmt.Println("All modules are up to date")
+		progress("All modules are up to date")
 	}
 }
@@ -0,0 +1,94 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+const (
+	// release notes are seldom edited once published
+	changelogTTL = 7 * 24 * time.Hour
+	// statusTTL is the lifetime of the statuses which may change, like an
+	// archived repository or a version missing from the checksum database
+	statusTTL = 24 * time.Hour
+	// never expiring entries are the immutable facts about a version
+	never = time.Duration(0)
+)
+
+// metadataCache holds the enrichment fetched from the code hosts and the
+// checksum database, keyed by module@version and shared by the projects of
+// the user, so that repeated runs don't query the APIs again
+type metadataCache struct {
+	// disabled skips the cached entries, with --no-cache, while still
+	// refreshing them
+	disabled bool
+}
+
+var metadata = &metadataCache{}
+
+// cacheEntry is a cached value along with the time it was fetched
+type cacheEntry struct {
+	Time  time.Time       `json:"time"`
+	Key   string          `json:"key"`
+	Value json.RawMessage `json:"value"`
+}
+
+// file returns the file of the entry, named after the hash of the key to
+// stay clear of the characters file systems reject
+func (c *metadataCache) file(kind, key string) (string, error) {
+	cache, err := os.UserCacheDir()
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256([]byte(key))
+	return filepath.Join(cache, "go-mod-upgrade", "metadata", kind, fmt.Sprintf("%x.json", sum[:16])), nil
+}
+
+// load decodes the entry of the key into v, unless it is missing or older
+// than ttl
+func (c *metadataCache) load(kind, key string, ttl time.Duration, v interface{}) bool {
+	if c.disabled {
+		return false
+	}
+	file, err := c.file(kind, key)
+	if err != nil {
+		return false
+	}
+	data, err := ioutil.ReadFile(file)
+	if err != nil {
+		return false
+	}
+	var e cacheEntry
+	if err := json.Unmarshal(data, &e); err != nil || e.Key != key {
+		return false
+	}
+	if ttl != never && time.Since(e.Time) > ttl {
+		return false
+	}
+	return json.Unmarshal(e.Value, v) == nil
+}
+
+// store records v as the entry of the key, on a best-effort basis
+func (c *metadataCache) store(kind, key string, v interface{}) {
+	file, err := c.file(kind, key)
+	if err != nil {
+		return
+	}
+	value, err := json.Marshal(v)
+	if err != nil {
+		return
+	}
+	data, err := json.Marshal(cacheEntry{Time: time.Now().UTC(), Key: key, Value: value})
+	if err != nil {
+		return
+	}
+	if err := os.MkdirAll(filepath.Dir(file), 0755); err != nil {
+		return
+	}
+	_ = writeFileAtomic(file, data)
+}
@@ -0,0 +1,147 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// modFile is the alternate go.mod file given with --modfile or in GOFLAGS,
+// which the go commands read and update instead of the go.mod of the module
+var modFile string
+
+// modFlags makes every go command run by the tool resolve modules with
+// -mod=mod, whatever -mod GOFLAGS forces, keeping the other flags. The
+// returned message explains the override, if any.
+func modFlags() (env []string, message string, err error) {
+	out, err := goCommand("env", "GOFLAGS").Output()
+	if err != nil {
+		return nil, "", err
+	}
+	flags := []string{}
+	forced := ""
+	for _, f := range strings.Fields(string(out)) {
+		if strings.HasPrefix(f, "-mod=") || strings.HasPrefix(f, "--mod=") {
+			forced = f[strings.Index(f, "=")+1:]
+			continue
+		}
+		if buildTags != "" && (strings.HasPrefix(f, "-tags=") || strings.HasPrefix(f, "--tags=")) {
+			continue
+		}
+		if strings.HasPrefix(f, "-modfile=") || strings.HasPrefix(f, "--modfile=") {
+			if modFile == "" {
+				modFile = f[strings.Index(f, "=")+1:]
+			}
+			continue
+		}
+		flags = append(flags, f)
+	}
+	flags = append(flags, "-mod=mod")
+	if buildTags != "" {
+		// GOFLAGS is split on spaces, while -tags accepts commas too
+		flags = append(flags, "-tags="+strings.Join(strings.FieldsFunc(buildTags, func(r rune) bool { return r == ',' || r == ' ' }), ","))
+	}
+	if modFile != "" {
+		// The go commands run in other directories too
+		if modFile, err = filepath.Abs(modFile); err != nil {
+			return nil, "", err
+		}
+		flags = append(flags, "-modfile="+modFile)
+	}
+	switch forced {
+	case "readonly":
+		message = "GOFLAGS sets -mod=readonly, which forbids updating go.mod: go-mod-upgrade uses -mod=mod for its own go commands"
+	case "vendor":
+		message = "GOFLAGS sets -mod=vendor, which hides available updates: go-mod-upgrade uses -mod=mod for its own go commands"
+	}
+	return []string{"GOFLAGS=" + strings.Join(flags, " ")}, message, nil
+}
+
+// withModFile runs f with the go commands using file as go.mod, the go.mod
+// of the module when empty
+func withModFile(file string, f func() error) error {
+	flags := os.Getenv("GOFLAGS")
+	for _, e := range goEnv {
+		if strings.HasPrefix(e, "GOFLAGS=") {
+			flags = strings.TrimPrefix(e, "GOFLAGS=")
+		}
+	}
+	kept := []string{}
+	for _, f := range strings.Fields(flags) {
+		if !strings.HasPrefix(f, "-modfile=") && !strings.HasPrefix(f, "--modfile=") {
+			kept = append(kept, f)
+		}
+	}
+	if file != "" {
+		kept = append(kept, "-modfile="+file)
+	}
+	saved := goEnv
+	goEnv = append(goEnv[:len(goEnv):len(goEnv)], "GOFLAGS="+strings.Join(kept, " "))
+	defer func() { goEnv = saved }()
+	return f()
+}
+
+// sumFile returns the go.sum file going with a go.mod file, named after it
+// for the alternate ones as the go command does
+func sumFile(gomod string) string {
+	return strings.TrimSuffix(gomod, ".mod") + ".sum"
+}
+
+// vendored reports whether the module in dir vendors its dependencies, in
+// which case the vendor directory must be refreshed after updating go.mod
+func vendored(dir string) bool {
+	gomod, err := moduleGoMod(dir)
+	if err != nil {
+		return false
+	}
+	_, err = os.Stat(filepath.Join(filepath.Dir(gomod), "vendor", "modules.txt"))
+	return err == nil
+}
+
+// checkWritable explains why updates can't be written when go.mod or go.sum
+// are read-only
+func checkWritable(dir string) error {
+	gomod, err := goModFileIn(dir)
+	if err != nil {
+		return err
+	}
+	for _, file := range []string{gomod, sumFile(gomod)} {
+		f, err := os.OpenFile(file, os.O_WRONLY, 0)
+		if os.IsNotExist(err) {
+			continue
+		}
+		if err != nil {
+			return fmt.Errorf("updates can't be written, %s is not writable: %v", filepath.Base(file), err)
+		}
+		f.Close()
+	}
+	return nil
+}
+
+// revendor refreshes the vendor directory of the module in dir after updates
+func revendor(dir string) error {
+	args := []string{"mod", "vendor"}
+	if _, err := goCommandIn(dir, args...).Output(); err != nil {
+		return newGoError(args, err)
+	}
+	return nil
+}
+
+// revendorAll refreshes the vendor directories of the updated modules
+func revendorAll(applied []Module) error {
+	for _, dir := range moduleDirsOf(applied) {
+		if !vendored(dir) {
+			continue
+		}
+		if dir == "" {
+			progress("Updating the vendor directory...")
+		} else {
+			progress("Updating the vendor directory of %s...", dir)
+		}
+		if err := revendor(dir); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,45 @@
+package main
+
+import (
+	"path/filepath"
+	"strings"
+)
+
+// offlineMode tells whether upgrades come from the local module cache, with
+// --offline or when downloads are disabled
+var offlineMode bool
+
+// offlineEnv returns the environment resolving upgrades from the local module
+// cache only, -mod=mod being already forced by modFlags. GOPROXY=off would
+// disable version queries altogether, so the cache download directory is used
+// as a file proxy instead.
+func offlineEnv() ([]string, error) {
+	out, err := goCommand("env", "GOMODCACHE", "GOPATH").Output()
+	if err != nil {
+		return nil, err
+	}
+	lines := strings.Split(strings.TrimSpace(string(out)), "\n")
+	cache := strings.TrimSpace(lines[0])
+	if cache == "" && len(lines) > 1 {
+		// GOMODCACHE is only known to go 1.15 and later
+		gopath := filepath.SplitList(strings.TrimSpace(lines[1]))
+		if len(gopath) > 0 {
+			cache = filepath.Join(gopath[0], "pkg", "mod")
+		}
+	}
+	dir := filepath.ToSlash(filepath.Join(cache, "cache", "download"))
+	if !strings.HasPrefix(dir, "/") {
+		dir = "/" + dir
+	}
+	return []string{
+		"GOPROXY=file://" + dir,
+		"GOSUMDB=off",
+	}, nil
+}
+
+// downloadsDisabled tells whether GOPROXY=off disables module downloads, in
+// which case only the offline mode can find upgrades
+func downloadsDisabled() bool {
+	out, err := goCommand("env", "GOPROXY").Output()
+	return err == nil && strings.TrimSpace(string(out)) == "off"
+}
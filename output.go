@@ -0,0 +1,72 @@
+package main
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	"github.com/fatih/color"
+)
+
+// writeFileAtomic writes the file through a temporary file renamed over it,
+// so that readers never see a partial file, or to stdout for -
+func writeFileAtomic(file string, data []byte) error {
+	return writeFileAtomicMode(file, data, 0644)
+}
+
+// writeFileAtomicMode is writeFileAtomic with the permissions of the file
+func writeFileAtomicMode(file string, data []byte, perm os.FileMode) error {
+	if file == "-" {
+		_, err := os.Stdout.Write(data)
+		return err
+	}
+	f, err := ioutil.TempFile(filepath.Dir(file), "."+filepath.Base(file)+".*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(f.Name())
+	if _, err := f.Write(data); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Chmod(perm); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	return os.Rename(f.Name(), file)
+}
+
+// output is where a subcommand writes its result: stdout, or the file given
+// with --output-file, written once the result is complete
+type output struct {
+	file string
+	buf  bytes.Buffer
+}
+
+func newOutput(file string) *output {
+	if file == "-" {
+		file = ""
+	}
+	if file != "" {
+		color.NoColor = true
+	}
+	return &output{file: file}
+}
+
+func (o *output) Write(p []byte) (int, error) {
+	if o.file == "" {
+		return color.Output.Write(p)
+	}
+	return o.buf.Write(p)
+}
+
+func (o *output) close() error {
+	if o.file == "" {
+		return nil
+	}
+	return writeFileAtomic(o.file, o.buf.Bytes())
+}
@@ -0,0 +1,132 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/AlecAivazis/survey/v2"
+	term "github.com/AlecAivazis/survey/v2/terminal"
+	"github.com/Masterminds/semver/v3"
+)
+
+// patchTargets also looks up the latest patch of the current minor version
+// of each module, as go get -u=patch does, to choose it over the latest
+var patchTargets bool
+
+// patchUpdates returns the latest patch of the current minor version of
+// the modules of dir, for the ones which have one. Unlike go get, go list
+// has no -u=patch, so the patch is picked among the available versions.
+func patchUpdates(dir string, debug bool, paths []string) (map[string]string, error) {
+	args := append([]string{"list", "-mod=mod", "-versions", "-json", "-m"}, paths...)
+	list, err := goList(dir, debug, args...)
+	if err != nil {
+		return nil, err
+	}
+	patches := map[string]string{}
+	for _, m := range list {
+		current, err := semver.NewVersion(m.Version)
+		if err != nil {
+			continue
+		}
+		latest := current
+		for _, v := range m.Versions {
+			parsed, err := semver.NewVersion(v)
+			if err != nil || parsed.Prerelease() != "" {
+				continue
+			}
+			if parsed.Major() == current.Major() && parsed.Minor() == current.Minor() && parsed.GreaterThan(latest) {
+				latest = parsed
+				patches[m.Path] = v
+			}
+		}
+	}
+	return patches, nil
+}
+
+// retarget returns the upgrade of the module to another version, whose
+// release time is unknown
+func retarget(m Module, version string) Module {
+	n := newModule(m.name, m.fromVersion, version)
+	m.toVersion = n.toVersion
+	m.to = n.to
+	m.severity = n.severity
+	m.toTime = time.Time{}
+	m.patch = ""
+	return m
+}
+
+// toPatch upgrades the module, or the members of an aggregated row, to
+// their latest patch, dropping the ones without any
+func toPatch(x Module) []Module {
+	if x.members == nil {
+		if x.patch == "" {
+			return nil
+		}
+		return []Module{retarget(x, x.patch)}
+	}
+	members := []Module{}
+	for _, m := range x.members {
+		if m.patch != "" {
+			members = append(members, retarget(m, m.patch))
+		}
+	}
+	if len(members) == 0 {
+		return nil
+	}
+	x = members[0]
+	if len(members) > 1 {
+		x.members = members
+		x.dir = fmt.Sprintf("%d modules", len(members))
+	}
+	return []Module{x}
+}
+
+// hasPatch tells whether the module, or a member of an aggregated row, can
+// be upgraded to a patch before its latest version
+func hasPatch(x Module) bool {
+	if x.patch != "" {
+		return true
+	}
+	for _, m := range x.members {
+		if m.patch != "" {
+			return true
+		}
+	}
+	return false
+}
+
+// choosePatches asks for each module with a patch before its latest version
+// which of them to upgrade to
+func choosePatches(modules []Module) []Module {
+	chosen := []Module{}
+	for _, x := range modules {
+		if !hasPatch(x) {
+			chosen = append(chosen, x)
+			continue
+		}
+		patch := tr("latest patch %s", x.patch)
+		if x.patch == "" {
+			patch = tr("latest patch of each module")
+		}
+		latest := tr("latest %s (%s)", x.toVersion, x.severity)
+		answer := ""
+		prompt := &survey.Select{
+			Message: tr("Upgrade %s from %s to", x.name, x.fromVersion),
+			Options: []string{patch, latest},
+		}
+		err := ask(prompt, &answer)
+		if err == term.InterruptErr {
+			fmt.Println(tr("Bye"))
+			os.Exit(0)
+		} else if err != nil {
+			fmt.Println(err)
+		}
+		if answer == patch {
+			chosen = append(chosen, toPatch(x)...)
+		} else {
+			chosen = append(chosen, x)
+		}
+	}
+	return chosen
+}
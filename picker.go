@@ -0,0 +1,386 @@
+package main
+
+import (
+	"errors"
+	"strings"
+
+	"github.com/AlecAivazis/survey/v2"
+	"github.com/AlecAivazis/survey/v2/core"
+	"github.com/AlecAivazis/survey/v2/terminal"
+)
+
+const keyTab = '\t'
+
+// keyHeld toggles the held back section, ctrl-o
+const keyHeld = '\x0f'
+
+// keyDetails toggles the requirement tree of the focused module, ctrl-t
+const keyDetails = '\x14'
+
+// keyExclude marks the target version of the module to be excluded in
+// go.mod, ctrl-x
+const keyExclude = '\x18'
+
+// keySelectPatch selects the patch updates, ctrl-y
+const keySelectPatch = '\x19'
+
+// keySelectMinor selects the minor updates, ctrl-g
+const keySelectMinor = '\x07'
+
+// keyUnselectMajor unselects the major upgrades, ctrl-k
+const keyUnselectMajor = '\x0b'
+
+// pickerKeys describes the keys of the picker, shown with the help input
+var pickerKeys = [][2]string{
+	{"↑ ↓", "move"},
+	{"space", "select or unselect the module"},
+	{"→", "select the matching modules"},
+	{"←", "unselect the matching modules"},
+	{"tab", "invert the selection of the matching modules"},
+	{"ctrl-y", "select the matching patch updates"},
+	{"ctrl-g", "select the matching minor updates"},
+	{"ctrl-k", "unselect the matching major upgrades"},
+	{"letters", "filter the modules, backspace and ctrl-w to erase"},
+	{"ctrl-o", "show or hide the held back modules"},
+	{"ctrl-x", "exclude the target version in go.mod, or cancel"},
+	{"ctrl-t", "show or hide what requires the module"},
+	{"enter", "update the selected modules"},
+	{"ctrl-c", "quit"},
+}
+
+// picker is a multi select prompt, like survey.MultiSelect, with bulk
+// selection actions. Bulk actions only apply to the options matching the
+// current filter, which are all options when no filter is typed. Held
+// entries are listed in a collapsed section, and can't be selected. Options
+// marked to be excluded are unselected until the mark is cancelled. Details
+// of the focused option, when given, are shown below the options on demand.
+// Severities, when given, let the options be selected by severity, and
+// Search, the texts the filter matches instead of the options.
+type picker struct {
+	survey.Renderer
+	Message    string
+	Options    []string
+	Search     []string
+	Severities []Severity
+	Default    []int
+	Held       []string
+	Help       string
+	PageSize   int
+	Details    func(index int) []string
+	// Refresh replaces the options while the picker is shown
+	Refresh <-chan pickerRefresh
+
+	filter         string
+	selectedIndex  int
+	checked        map[int]bool
+	excluded       map[int]bool
+	showingHelp    bool
+	showingHeld    bool
+	showingDetails bool
+}
+
+// pickerRefresh is the new text of the options and their search texts
+type pickerRefresh struct {
+	Options []string
+	Search  []string
+}
+
+type pickerTemplateData struct {
+	Message       string
+	Filter        string
+	Help          string
+	Hint          string
+	HelpHint      string
+	HeldTitle     string
+	Answer        string
+	ShowAnswer    bool
+	ShowHelp      bool
+	Keys          []string
+	Checked       map[int]bool
+	Excluded      map[int]bool
+	SelectedIndex int
+	PageEntries   []core.OptionAnswer
+	Held          []string
+	ShowHeld      bool
+	Details       []string
+	Config        *survey.PromptConfig
+}
+
+var pickerTemplate = `
+{{- if and .ShowHelp .Help }}{{- color .Config.Icons.Help.Format }}{{ .Config.Icons.Help.Text }} {{ .Help }}{{color "reset"}}{{"\n"}}{{end}}
+{{- color .Config.Icons.Question.Format }}{{ .Config.Icons.Question.Text }} {{color "reset"}}
+{{- color "default+hb"}}{{ .Message }}{{ if .Filter }} {{ .Filter }}{{ end }}{{color "reset"}}
+{{- if .ShowAnswer}}{{color "cyan"}} {{.Answer}}{{color "reset"}}{{"\n"}}
+{{- else }}
+	{{- "  "}}{{- color "cyan"}}[{{ .Hint }}{{- if not .ShowHelp}}, {{ .Config.HelpInput }} {{ .HelpHint }}{{end}}]{{color "reset"}}
+  {{- "\n"}}
+  {{- range $ix, $option := .PageEntries}}
+    {{- if eq $ix $.SelectedIndex }}{{color $.Config.Icons.SelectFocus.Format }}{{ $.Config.Icons.SelectFocus.Text }}{{color "reset"}}{{else}} {{end}}
+    {{- if index $.Excluded $option.Index }}{{color "red"}} [✗] {{else if index $.Checked $option.Index }}{{color $.Config.Icons.MarkedOption.Format }} {{ $.Config.Icons.MarkedOption.Text }} {{else}}{{color $.Config.Icons.UnmarkedOption.Format }} {{ $.Config.Icons.UnmarkedOption.Text }} {{end}}
+    {{- color "reset"}}
+    {{- " "}}{{$option.Value}}{{"\n"}}
+  {{- end}}
+  {{- if .Details}}
+    {{- color "default+d"}}
+    {{- range .Details}}    {{.}}{{"\n"}}{{end}}
+    {{- color "reset"}}
+  {{- end}}
+  {{- if .Held}}
+    {{- color "default+d"}}
+    {{- if .ShowHeld}}  ▾ {{ .HeldTitle }}{{"\n"}}
+      {{- range .Held}}       {{.}}{{"\n"}}{{end}}
+    {{- else}}  ▸ {{ .HeldTitle }}{{"\n"}}{{end}}
+    {{- color "reset"}}
+  {{- end}}
+  {{- if .ShowHelp}}
+    {{- "\n"}}{{- color "cyan"}}
+    {{- range .Keys}}  {{.}}{{"\n"}}{{end}}
+    {{- color "reset"}}
+  {{- end}}
+{{- end}}`
+
+func (p *picker) filterOptions(config *survey.PromptConfig) []core.OptionAnswer {
+	if p.filter == "" {
+		return core.OptionAnswerList(p.Options)
+	}
+	answers := []core.OptionAnswer{}
+	for i, opt := range p.Options {
+		text := opt
+		if i < len(p.Search) {
+			text = p.Search[i]
+		}
+		if config.Filter(p.filter, text, i) {
+			answers = append(answers, core.OptionAnswer{Index: i, Value: opt})
+		}
+	}
+	return answers
+}
+
+func (p *picker) onChange(key rune, config *survey.PromptConfig) {
+	options := p.filterOptions(config)
+	oldFilter := p.filter
+
+	switch {
+	case key == terminal.KeyArrowUp:
+		if p.selectedIndex == 0 {
+			p.selectedIndex = len(options) - 1
+		} else {
+			p.selectedIndex--
+		}
+	case key == terminal.KeyArrowDown:
+		if p.selectedIndex >= len(options)-1 {
+			p.selectedIndex = 0
+		} else {
+			p.selectedIndex++
+		}
+	case key == terminal.KeySpace:
+		if p.selectedIndex < len(options) {
+			index := options[p.selectedIndex].Index
+			p.checked[index] = !p.checked[index] && !p.excluded[index]
+			p.filter = ""
+		}
+	case key == keyExclude:
+		if p.selectedIndex < len(options) {
+			index := options[p.selectedIndex].Index
+			p.excluded[index] = !p.excluded[index]
+			p.checked[index] = false
+		}
+	case key == terminal.KeyArrowRight:
+		for _, opt := range options {
+			p.checked[opt.Index] = !p.excluded[opt.Index]
+		}
+	case key == terminal.KeyArrowLeft:
+		for _, opt := range options {
+			p.checked[opt.Index] = false
+		}
+	case key == keyTab:
+		for _, opt := range options {
+			p.checked[opt.Index] = !p.checked[opt.Index] && !p.excluded[opt.Index]
+		}
+	case key == keySelectPatch || key == keySelectMinor || key == keyUnselectMajor:
+		for _, opt := range options {
+			if opt.Index >= len(p.Severities) {
+				continue
+			}
+			switch s := p.Severities[opt.Index]; {
+			case key == keySelectPatch && s == SeverityPatch, key == keySelectMinor && s == SeverityMinor:
+				p.checked[opt.Index] = !p.excluded[opt.Index]
+			case key == keyUnselectMajor && s == SeverityMajor:
+				p.checked[opt.Index] = false
+			}
+		}
+	case key == keyHeld:
+		p.showingHeld = !p.showingHeld
+	case key == keyDetails:
+		p.showingDetails = !p.showingDetails
+	case string(key) == config.HelpInput:
+		p.showingHelp = !p.showingHelp
+	case key == terminal.KeyDeleteWord || key == terminal.KeyDeleteLine:
+		p.filter = ""
+	case key == terminal.KeyDelete || key == terminal.KeyBackspace:
+		if p.filter != "" {
+			p.filter = p.filter[:len(p.filter)-1]
+		}
+	case key >= terminal.KeySpace:
+		p.filter += string(key)
+	}
+
+	if oldFilter != p.filter {
+		options = p.filterOptions(config)
+		if len(options) > 0 && len(options) <= p.selectedIndex {
+			p.selectedIndex = len(options) - 1
+		}
+	}
+	p.render(options, config)
+}
+
+func (p *picker) render(options []core.OptionAnswer, config *survey.PromptConfig) error {
+	pageSize := p.PageSize
+	if pageSize == 0 {
+		pageSize = config.PageSize
+	}
+	opts, idx := paginate(pageSize, options, p.selectedIndex)
+	hint := translate("arrows to move, space to select, → all, ← none, tab to invert, type to filter")
+	if len(p.Held) > 0 {
+		hint += ", " + translate("ctrl-o held back")
+	}
+	keys := []string{}
+	if p.showingHelp {
+		width := 0
+		for _, k := range pickerKeys {
+			width = max(width, len([]rune(k[0])))
+		}
+		for _, k := range pickerKeys {
+			if k[0] == "ctrl-o" && len(p.Held) == 0 || k[0] == "ctrl-t" && p.Details == nil {
+				continue
+			}
+			keys = append(keys, k[0]+strings.Repeat(" ", width-len([]rune(k[0])))+"  "+translate(k[1]))
+		}
+	}
+	var details []string
+	if p.showingDetails && p.Details != nil && p.selectedIndex < len(options) {
+		details = p.Details(options[p.selectedIndex].Index)
+	}
+	return p.Render(pickerTemplate, pickerTemplateData{
+		Message:       p.Message,
+		Filter:        p.filter,
+		Help:          p.Help,
+		HelpHint:      translate("for help"),
+		Keys:          keys,
+		Hint:          hint,
+		HeldTitle:     tr("%d held back", len(p.Held)),
+		ShowHelp:      p.showingHelp,
+		Checked:       p.checked,
+		Excluded:      p.excluded,
+		SelectedIndex: idx,
+		PageEntries:   opts,
+		Held:          p.Held,
+		ShowHeld:      p.showingHeld,
+		Details:       details,
+		Config:        config,
+	})
+}
+
+func (p *picker) Prompt(config *survey.PromptConfig) (interface{}, error) {
+	if len(p.Options) == 0 {
+		return nil, errors.New("please provide options to select from")
+	}
+	p.checked = make(map[int]bool)
+	p.excluded = make(map[int]bool)
+	for _, i := range p.Default {
+		p.checked[i] = true
+	}
+
+	cursor := p.NewCursor()
+	cursor.Hide()
+	defer cursor.Show()
+
+	if err := p.render(core.OptionAnswerList(p.Options), config); err != nil {
+		return nil, err
+	}
+
+	rr := p.NewRuneReader()
+	if err := rr.SetTermMode(); err != nil {
+		return nil, err
+	}
+	defer func() {
+		_ = rr.RestoreTermMode()
+	}()
+
+	// Keys are read in the background to render the refreshes meanwhile,
+	// until the last one so that the next prompts get the input
+	type key struct {
+		r   rune
+		err error
+	}
+	keys := make(chan key)
+	go func() {
+		for {
+			r, _, err := rr.ReadRune()
+			keys <- key{r, err}
+			if err != nil || r == '\r' || r == '\n' || r == terminal.KeyEndTransmission || r == terminal.KeyInterrupt {
+				return
+			}
+		}
+	}()
+loop:
+	for {
+		select {
+		case k := <-keys:
+			if k.err != nil {
+				return nil, k.err
+			}
+			if k.r == '\r' || k.r == '\n' || k.r == terminal.KeyEndTransmission {
+				break loop
+			}
+			if k.r == terminal.KeyInterrupt {
+				return nil, terminal.InterruptErr
+			}
+			p.onChange(k.r, config)
+		case r := <-p.Refresh:
+			p.Options, p.Search = r.Options, r.Search
+			if err := p.render(p.filterOptions(config), config); err != nil {
+				return nil, err
+			}
+		}
+	}
+	p.filter = ""
+
+	answers := []core.OptionAnswer{}
+	for i, opt := range p.Options {
+		if p.checked[i] {
+			answers = append(answers, core.OptionAnswer{Value: opt, Index: i})
+		}
+	}
+	return answers, nil
+}
+
+func (p *picker) Cleanup(config *survey.PromptConfig, val interface{}) error {
+	names := []string{}
+	for _, ans := range val.([]core.OptionAnswer) {
+		names = append(names, ans.Value)
+	}
+	return p.Render(pickerTemplate, pickerTemplateData{
+		Message:    p.Message,
+		Answer:     strings.Join(names, ", "),
+		ShowAnswer: true,
+		Config:     config,
+	})
+}
+
+// paginate returns the page of options around the selected index, along
+// with the position of the selected index in that page
+func paginate(pageSize int, options []core.OptionAnswer, sel int) ([]core.OptionAnswer, int) {
+	switch {
+	case len(options) < pageSize:
+		return options, sel
+	case sel < pageSize/2:
+		return options[:pageSize], sel
+	case len(options)-sel-1 < pageSize/2:
+		start := len(options) - pageSize
+		return options[start:], sel - start
+	default:
+		start := sel - pageSize/2
+		return options[start : start+pageSize], pageSize / 2
+	}
+}
@@ -0,0 +1,146 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// pin holds a module at a version, for a reason worth remembering
+type pin struct {
+	Path    string `yaml:"path"`
+	Version string `yaml:"version"`
+	Reason  string `yaml:"reason,omitempty"`
+}
+
+func (c *config) pinned(m Module) *pin {
+	for i, p := range c.Pins {
+		if p.Path == m.name {
+			return &c.Pins[i]
+		}
+	}
+	return nil
+}
+
+// pinCommand pins a module at a version: go.mod requires that version, and
+// the pin recorded in the configuration holds the module in later runs
+func pinCommand(args []string) error {
+	fs := flag.NewFlagSet("pin", flag.ExitOnError)
+	reason := fs.String("reason", "", "Why the module is held at this version")
+	remove := fs.Bool("remove", false, "Remove the pin of the module, leaving go.mod as is")
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+	if fs.NArg() != 1 {
+		return errors.New("usage: go-mod-upgrade pin [--reason text] <module>@<version> | --remove <module>")
+	}
+	path, version := fs.Arg(0), ""
+	if i := strings.LastIndex(path, "@"); i >= 0 {
+		path, version = path[:i], path[i+1:]
+	}
+	if *remove {
+		if err := editConfig(func(root *yaml.Node) error {
+			removePin(root, path)
+			return nil
+		}); err != nil {
+			return err
+		}
+		fmt.Printf("Unpinned %s\n", path)
+		return nil
+	}
+	if version == "" {
+		return errors.New("pin: a version is required, as in <module>@<version>")
+	}
+	cfg, err := loadConfig()
+	if err != nil {
+		return err
+	}
+	if cfg.RequireReasons && *reason == "" {
+		return errors.New("pin: the configuration requires a --reason")
+	}
+	if err := checkWritable(""); err != nil {
+		return err
+	}
+	lock, err := acquireLock()
+	if err != nil {
+		return err
+	}
+	defer lock.release()
+	if err := goGet("", path, version); err != nil {
+		return err
+	}
+	if err := editConfig(func(root *yaml.Node) error {
+		pins := removePin(root, path)
+		var n yaml.Node
+		if err := n.Encode(pin{Path: path, Version: version, Reason: *reason}); err != nil {
+			return err
+		}
+		pins.Content = append(pins.Content, &n)
+		return nil
+	}); err != nil {
+		return err
+	}
+	fmt.Printf("Pinned %s at %s\n", path, version)
+	return nil
+}
+
+// removePin removes the pins of the module from the configuration, returning
+// the pins sequence
+func removePin(root *yaml.Node, path string) *yaml.Node {
+	var pins *yaml.Node
+	for i := 0; i+1 < len(root.Content); i += 2 {
+		if root.Content[i].Value == "pins" {
+			pins = root.Content[i+1]
+		}
+	}
+	if pins == nil {
+		pins = &yaml.Node{Kind: yaml.SequenceNode}
+		root.Content = append(root.Content, &yaml.Node{Kind: yaml.ScalarNode, Value: "pins"}, pins)
+	}
+	kept := []*yaml.Node{}
+	for _, n := range pins.Content {
+		var p pin
+		if err := n.Decode(&p); err == nil && p.Path == path {
+			continue
+		}
+		kept = append(kept, n)
+	}
+	pins.Content = kept
+	return pins
+}
+
+// editConfig edits the configuration file as a YAML tree, which keeps the
+// comments and the order of the keys
+func editConfig(edit func(root *yaml.Node) error) error {
+	file, err := configFile()
+	if err != nil {
+		return err
+	}
+	data, err := ioutil.ReadFile(file)
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	var doc yaml.Node
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return fmt.Errorf("%s: %v", file, err)
+	}
+	if len(doc.Content) == 0 {
+		doc = yaml.Node{Kind: yaml.DocumentNode, Content: []*yaml.Node{{Kind: yaml.MappingNode}}}
+	}
+	if err := edit(doc.Content[0]); err != nil {
+		return err
+	}
+	var buf bytes.Buffer
+	enc := yaml.NewEncoder(&buf)
+	enc.SetIndent(2)
+	if err := enc.Encode(&doc); err != nil {
+		return err
+	}
+	return ioutil.WriteFile(file, buf.Bytes(), 0644)
+}
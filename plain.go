@@ -0,0 +1,238 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/AlecAivazis/survey/v2"
+	"github.com/AlecAivazis/survey/v2/terminal"
+)
+
+// plain replaces the prompts with numbered questions answered on a line,
+// without raw mode nor cursor movements, for dumb terminals, serial consoles
+// and docker exec without a TTY
+var plain bool
+
+var plainInput = bufio.NewReader(os.Stdin)
+
+// ask prompts like survey.AskOne, with plain questions in plain mode
+func ask(prompt survey.Prompt, response interface{}, opts ...survey.AskOpt) error {
+	if !plain {
+		return survey.AskOne(prompt, response, opts...)
+	}
+	switch p := prompt.(type) {
+	case *survey.Confirm:
+		return plainConfirm(p, response.(*bool))
+	case *survey.Select:
+		return plainSelect(p, response.(*string))
+	case *survey.Input:
+		return plainLine(p.Message, p.Default, response.(*string))
+	case *survey.Password:
+		return plainLine(p.Message, "", response.(*string))
+	case *picker:
+		return plainPick(p, response.(*[]int))
+	}
+	return fmt.Errorf("no plain version of the %T prompt", prompt)
+}
+
+// readLine reads an answer, end of input standing for an interrupt
+func readLine() (string, error) {
+	line, err := plainInput.ReadString('\n')
+	if err != nil && line == "" {
+		fmt.Println()
+		return "", terminal.InterruptErr
+	}
+	return strings.TrimSpace(line), nil
+}
+
+func plainConfirm(p *survey.Confirm, response *bool) error {
+	hint := "y/N"
+	if p.Default {
+		hint = "Y/n"
+	}
+	for {
+		fmt.Printf("? %s (%s) ", p.Message, hint)
+		line, err := readLine()
+		if err != nil {
+			return err
+		}
+		switch strings.ToLower(line) {
+		case "":
+			*response = p.Default
+			return nil
+		case "y", "yes":
+			*response = true
+			return nil
+		case "n", "no":
+			*response = false
+			return nil
+		}
+	}
+}
+
+func plainSelect(p *survey.Select, response *string) error {
+	def := 0
+	if d, ok := p.Default.(string); ok {
+		for i, o := range p.Options {
+			if o == d {
+				def = i
+			}
+		}
+	}
+	fmt.Printf("? %s\n", p.Message)
+	for i, o := range p.Options {
+		fmt.Printf("  %d) %s\n", i+1, o)
+	}
+	for {
+		fmt.Printf("Choice [%d]: ", def+1)
+		line, err := readLine()
+		if err != nil {
+			return err
+		}
+		n := def + 1
+		if line != "" {
+			if n, err = strconv.Atoi(line); err != nil {
+				continue
+			}
+		}
+		if n >= 1 && n <= len(p.Options) {
+			*response = p.Options[n-1]
+			return nil
+		}
+	}
+}
+
+// plainLine reads a required answer
+func plainLine(message, def string, response *string) error {
+	for {
+		if def != "" {
+			fmt.Printf("? %s [%s]: ", message, def)
+		} else {
+			fmt.Printf("? %s: ", message)
+		}
+		line, err := readLine()
+		if err != nil {
+			return err
+		}
+		if line == "" {
+			line = def
+		}
+		if line != "" {
+			*response = line
+			return nil
+		}
+	}
+}
+
+// parseChoice parses a selection such as 1,3-5, all, none or the names of
+// severities such as patch,minor among the options
+func parseChoice(line string, severities []Severity, n int) ([]int, error) {
+	switch line {
+	case "all":
+		all := []int{}
+		for i := 0; i < n; i++ {
+			all = append(all, i)
+		}
+		return all, nil
+	case "none":
+		return []int{}, nil
+	}
+	seen := map[int]bool{}
+	choice := []int{}
+	for _, part := range strings.FieldsFunc(line, func(r rune) bool { return r == ',' || r == ' ' }) {
+		if s, err := parseSeverity(part); err == nil {
+			for i := 0; i < n && i < len(severities); i++ {
+				if severities[i] == s && !seen[i] {
+					seen[i] = true
+					choice = append(choice, i)
+				}
+			}
+			continue
+		}
+		from, to := part, part
+		if i := strings.Index(part, "-"); i > 0 {
+			from, to = part[:i], part[i+1:]
+		}
+		a, err := strconv.Atoi(from)
+		if err != nil {
+			return nil, fmt.Errorf("%q is not a number", from)
+		}
+		b, err := strconv.Atoi(to)
+		if err != nil {
+			return nil, fmt.Errorf("%q is not a number", to)
+		}
+		if a < 1 || b > n || a > b {
+			return nil, fmt.Errorf("%s is not between 1 and %d", part, n)
+		}
+		for i := a - 1; i < b; i++ {
+			if !seen[i] {
+				seen[i] = true
+				choice = append(choice, i)
+			}
+		}
+	}
+	sort.Ints(choice)
+	return choice, nil
+}
+
+// plainPick lists the numbered options, then reads the selection, the
+// preselected options when empty
+func plainPick(p *picker, response *[]int) error {
+	fmt.Printf("? %s\n", p.Message)
+	p.checked = map[int]bool{}
+	for _, i := range p.Default {
+		p.checked[i] = true
+	}
+	for i, o := range p.Options {
+		if accessible {
+			fmt.Printf("%s: %s\n", itemState(i, len(p.Options), p.checked[i]), o)
+			continue
+		}
+		mark := " "
+		if p.checked[i] {
+			mark = "x"
+		}
+		fmt.Printf("  [%s] %d) %s\n", mark, i+1, o)
+	}
+	if len(p.Held) > 0 {
+		fmt.Println(tr("Held back:"))
+		for _, h := range p.Held {
+			fmt.Printf("      %s\n", h)
+		}
+	}
+	for {
+		fmt.Print(tr("Modules to update, e.g. 1,3-5, patch, minor, all or none, empty for the marked ones: "))
+		line, err := readLine()
+		if err != nil {
+			return err
+		}
+		if line == "" {
+			*response = append([]int{}, p.Default...)
+			if accessible {
+				fmt.Println(selectionState(*response, len(p.Options)))
+			}
+			return nil
+		}
+		choice, err := parseChoice(strings.ToLower(line), p.Severities, len(p.Options))
+		if err != nil {
+			fmt.Println(err)
+			continue
+		}
+		p.checked = map[int]bool{}
+		for i := range p.Options {
+			p.checked[i] = false
+		}
+		for _, i := range choice {
+			p.checked[i] = true
+		}
+		if accessible {
+			fmt.Println(selectionState(choice, len(p.Options)))
+		}
+		*response = choice
+		return nil
+	}
+}
@@ -0,0 +1,270 @@
+package main
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"os/user"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// plan is a set of proposed upgrades, applied exactly once approved by a
+// second person
+type plan struct {
+	ProposedBy string     `yaml:"proposed-by,omitempty" json:"proposed-by,omitempty"`
+	ProposedAt *time.Time `yaml:"proposed-at,omitempty" json:"proposed-at,omitempty"`
+	ApprovedBy string     `yaml:"approved-by,omitempty" json:"approved-by,omitempty"`
+	ApprovedAt *time.Time `yaml:"approved-at,omitempty" json:"approved-at,omitempty"`
+	// Approved is the digest of the upgrades and go.mod hashes approved, so
+	// that they can't be edited afterwards
+	Approved string `yaml:"approved,omitempty" json:"approved,omitempty"`
+	// GoMod holds the hashes of the go.mod files by module directory, the
+	// plan only applies to the go.mod files it was made for
+	GoMod   map[string]string `yaml:"go-mod,omitempty" json:"go-mod,omitempty"`
+	Modules []planned         `yaml:"modules" json:"modules"`
+}
+
+type planned struct {
+	Path string `yaml:"path" json:"path"`
+	From string `yaml:"from" json:"from"`
+	To   string `yaml:"to" json:"to"`
+	Dir  string `yaml:"dir,omitempty" json:"dir,omitempty"`
+}
+
+func currentUser() string {
+	if u, err := user.Current(); err == nil {
+		return u.Username
+	}
+	return ""
+}
+
+// goModHash hashes the go.mod file of the module directory
+func goModHash(dir string) (string, error) {
+	gomod, err := goModFileIn(dir)
+	if err != nil {
+		return "", err
+	}
+	return fileDigest(gomod)
+}
+
+// writePlan proposes the upgrades instead of applying them
+func writePlan(file string, modules []Module) error {
+	now := time.Now().UTC()
+	p := plan{ProposedBy: currentUser(), ProposedAt: &now, GoMod: map[string]string{}}
+	for _, x := range modules {
+		p.Modules = append(p.Modules, planned{Path: x.name, From: x.fromVersion, To: x.toVersion, Dir: x.dir})
+	}
+	for _, dir := range moduleDirsOf(modules) {
+		hash, err := goModHash(dir)
+		if err != nil {
+			return err
+		}
+		p.GoMod[dir] = hash
+	}
+	return p.save(file)
+}
+
+// loadPlan reads a YAML or JSON plan, which may also be a bare list of
+// modules as output by JSON consumers such as serve
+func loadPlan(file string) (*plan, error) {
+	data, err := ioutil.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+	p := &plan{}
+	if trimmed := bytes.TrimSpace(data); len(trimmed) > 0 && trimmed[0] == '[' {
+		err = json.Unmarshal(trimmed, &p.Modules)
+	} else {
+		err = yaml.Unmarshal(data, p)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v", file, err)
+	}
+	return p, nil
+}
+
+// proposed tells whether the plan was written by --plan, which requires an
+// approval, unlike the bare lists of modules
+func (p *plan) proposed() bool {
+	return p.ProposedBy != "" || p.ProposedAt != nil || len(p.GoMod) > 0 || p.ApprovedBy != "" || p.Approved != ""
+}
+
+// digest hashes the upgrades and go.mod hashes of the plan
+func (p *plan) digest() (string, error) {
+	data, err := json.Marshal(struct {
+		GoMod   map[string]string `json:"go-mod"`
+		Modules []planned         `json:"modules"`
+	}{p.GoMod, p.Modules})
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256(data)
+	return "sha256:" + hex.EncodeToString(sum[:]), nil
+}
+
+// check fails when a go.mod file changed since the plan was made
+func (p *plan) check() error {
+	if len(p.GoMod) == 0 {
+		return p.checkVersions()
+	}
+	for dir, hash := range p.GoMod {
+		current, err := goModHash(dir)
+		if err != nil {
+			return err
+		}
+		if current != hash {
+			name := "go.mod"
+			if dir != "" {
+				name = filepath.Join(dir, name)
+			}
+			return fmt.Errorf("%s changed since the plan was made, make a new plan", name)
+		}
+	}
+	return nil
+}
+
+// checkVersions compares the versions the upgrades start from with the
+// current ones, for the plans without go.mod hashes such as the output of
+// list --json
+func (p *plan) checkVersions() error {
+	dirs := []string{}
+	byDir := map[string][]planned{}
+	for _, m := range p.Modules {
+		if byDir[m.Dir] == nil {
+			dirs = append(dirs, m.Dir)
+		}
+		byDir[m.Dir] = append(byDir[m.Dir], m)
+	}
+	for _, dir := range dirs {
+		args := []string{"list", "-e", "-mod=mod", "-json", "-m"}
+		for _, m := range byDir[dir] {
+			args = append(args, m.Path)
+		}
+		list, err := goList(dir, false, args...)
+		if err != nil {
+			return err
+		}
+		current := map[string]string{}
+		for _, m := range list {
+			current[m.Path] = m.Version
+		}
+		for _, m := range byDir[dir] {
+			if current[m.Path] == "" {
+				return fmt.Errorf("%s is no longer required since the plan was made, make a new plan", m.Path)
+			}
+			if current[m.Path] != m.From {
+				return fmt.Errorf("%s is at %s but the plan upgrades it from %s, make a new plan", m.Path, current[m.Path], m.From)
+			}
+		}
+	}
+	return nil
+}
+
+func (p *plan) save(file string) error {
+	if strings.ToLower(filepath.Ext(file)) == ".json" {
+		data, err := json.MarshalIndent(p, "", "  ")
+		if err != nil {
+			return err
+		}
+		return writeFileAtomic(file, append(data, '\n'))
+	}
+	var buf bytes.Buffer
+	enc := yaml.NewEncoder(&buf)
+	enc.SetIndent(2)
+	if err := enc.Encode(p); err != nil {
+		return err
+	}
+	return writeFileAtomic(file, buf.Bytes())
+}
+
+func (p *plan) modules() []Module {
+	modules := []Module{}
+	for _, m := range p.Modules {
+		x := newModule(m.Path, m.From, m.To)
+		x.dir = m.Dir
+		modules = append(modules, x)
+	}
+	return modules
+}
+
+// approveCommand records the approval of a plan by someone else than its
+// author
+func approveCommand(args []string) error {
+	fs := flag.NewFlagSet("approve", flag.ExitOnError)
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+	if fs.NArg() != 1 {
+		return errors.New("usage: go-mod-upgrade approve <plan.yaml>")
+	}
+	file := fs.Arg(0)
+	p, err := loadPlan(file)
+	if err != nil {
+		return err
+	}
+	approver := currentUser()
+	if approver == p.ProposedBy {
+		return fmt.Errorf("%s proposed the plan, it must be approved by someone else", approver)
+	}
+	digest, err := p.digest()
+	if err != nil {
+		return err
+	}
+	now := time.Now().UTC()
+	p.ApprovedBy = approver
+	p.ApprovedAt = &now
+	p.Approved = digest
+	if err := p.save(file); err != nil {
+		return err
+	}
+	fmt.Printf("Approved %d upgrade(s) proposed by %s\n", len(p.Modules), p.ProposedBy)
+	return nil
+}
+
+// planModules returns the upgrades of an approved plan, for the apply
+// command
+func planModules(args []string) ([]Module, error) {
+	fs := flag.NewFlagSet("apply", flag.ExitOnError)
+	file := fs.String("from-plan", "", "Approved plan to apply")
+	if err := parseFlags(fs, args); err != nil {
+		return nil, err
+	}
+	if *file == "" {
+		return nil, errors.New("usage: go-mod-upgrade apply --from-plan <plan.yaml>")
+	}
+	p, err := loadPlan(*file)
+	if err != nil {
+		return nil, err
+	}
+	// Only plans proposed for approval need one
+	if p.proposed() {
+		if p.ApprovedBy == "" || p.Approved == "" {
+			return nil, fmt.Errorf("%s is not approved yet, run go-mod-upgrade approve %s", *file, *file)
+		}
+		digest, err := p.digest()
+		if err != nil {
+			return nil, err
+		}
+		if digest != p.Approved {
+			return nil, fmt.Errorf("%s changed since it was approved, have it approved again", *file)
+		}
+	}
+	if err := p.check(); err != nil {
+		return nil, err
+	}
+	if p.proposed() {
+		fmt.Printf("Applying %d upgrade(s) proposed by %s, approved by %s\n", len(p.Modules), p.ProposedBy, p.ApprovedBy)
+	} else {
+		fmt.Printf("Applying %d upgrade(s) from %s\n", len(p.Modules), *file)
+	}
+	return p.modules(), nil
+}
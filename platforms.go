@@ -0,0 +1,97 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strings"
+)
+
+// targetPlatforms are the GOOS/GOARCH pairs given with --platforms, whose
+// imports the discovery takes into account and which the updated modules are
+// cross-compiled for
+var targetPlatforms []string
+
+// distList returns the GOOS/GOARCH pairs supported by the go command
+func distList() ([]string, error) {
+	args := []string{"tool", "dist", "list"}
+	out, err := goCommand(args...).Output()
+	if err != nil {
+		return nil, newGoError(args, err)
+	}
+	return strings.Fields(string(out)), nil
+}
+
+// parsePlatforms parses a comma separated list of GOOS/GOARCH pairs
+func parsePlatforms(s string) ([]string, error) {
+	list, err := distList()
+	if err != nil {
+		return nil, err
+	}
+	supported := map[string]bool{}
+	for _, p := range list {
+		supported[p] = true
+	}
+	platforms := []string{}
+	for _, p := range strings.Split(s, ",") {
+		p = strings.TrimSpace(p)
+		if p == "" {
+			continue
+		}
+		if !supported[p] {
+			return nil, fmt.Errorf("--platforms: unknown platform %q, expected GOOS/GOARCH as listed by go tool dist list", p)
+		}
+		platforms = append(platforms, p)
+	}
+	return platforms, nil
+}
+
+// withPlatform runs f with the go commands targeting the platform, the
+// current one when empty
+func withPlatform(platform string, f func() error) error {
+	if platform == "" {
+		return f()
+	}
+	parts := strings.SplitN(platform, "/", 2)
+	saved := goEnv
+	goEnv = append(goEnv[:len(goEnv):len(goEnv)], "GOOS="+parts[0], "GOARCH="+parts[1])
+	defer func() { goEnv = saved }()
+	return f()
+}
+
+// buildFor builds the packages of dir for the platform
+func buildFor(dir, platform string) error {
+	args := []string{"build", "-o", os.DevNull, "./..."}
+	return withPlatform(platform, func() error {
+		if out, err := goCommandIn(dir, args...).CombinedOutput(); err != nil {
+			return fmt.Errorf("go build for %s: %v: %s", platform, err, strings.TrimSpace(string(out)))
+		}
+		return nil
+	})
+}
+
+// crossBuild builds the packages of dir for each target platform, stopping
+// at the first failure
+func crossBuild(dir string) error {
+	for _, p := range targetPlatforms {
+		if err := buildFor(dir, p); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// verifyPlatforms cross-compiles the updated modules for each target
+// platform, reporting the failures
+func verifyPlatforms(applied []Module) {
+	if len(targetPlatforms) == 0 || len(applied) == 0 {
+		return
+	}
+	progress("Building for %s...", strings.Join(targetPlatforms, ", "))
+	for _, dir := range moduleDirsOf(applied) {
+		for _, p := range targetPlatforms {
+			if err := buildFor(dir, p); err != nil {
+				fmt.Println(err)
+			}
+		}
+	}
+}
@@ -0,0 +1,165 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+)
+
+const pluginPrefix = "go-mod-upgrade-"
+
+// Plugin hooks, given as the first argument of the plugin executable
+const (
+	hookDescribe   = "describe"
+	hookColumns    = "columns"
+	hookFormat     = "format"
+	hookPostUpdate = "post-update"
+)
+
+// plugin is a go-mod-upgrade-<name> executable found on PATH. It is run with
+// the hook as argument, a JSON request on stdin, and answers on stdout.
+type plugin struct {
+	name string
+	path string
+	pluginCapabilities
+}
+
+// pluginCapabilities is the answer of a plugin to the describe hook
+type pluginCapabilities struct {
+	// Columns are added to the list of modules
+	Columns []string `json:"columns"`
+	// Formats are report formats, selected by the report file extension
+	Formats []string `json:"formats"`
+	// PostUpdate asks for the applied updates once done
+	PostUpdate bool `json:"post-update"`
+}
+
+type pluginRequest struct {
+	Format  string   `json:"format,omitempty"`
+	Modules []Module `json:"modules"`
+}
+
+type plugins []plugin
+
+// findPlugins describes the plugins found on PATH, the first one found
+// winning for a given name as for commands
+func findPlugins(verbose bool) plugins {
+	found := plugins{}
+	seen := map[string]bool{}
+	for _, dir := range filepath.SplitList(os.Getenv("PATH")) {
+		files, err := ioutil.ReadDir(dir)
+		if err != nil {
+			continue
+		}
+		for _, f := range files {
+			name := f.Name()
+			if runtime.GOOS == "windows" {
+				name = strings.TrimSuffix(name, filepath.Ext(name))
+			} else if f.Mode()&0111 == 0 {
+				continue
+			}
+			if f.IsDir() || !strings.HasPrefix(name, pluginPrefix) || seen[name] {
+				continue
+			}
+			seen[name] = true
+			p := plugin{name: strings.TrimPrefix(name, pluginPrefix), path: filepath.Join(dir, f.Name())}
+			if err := p.call(hookDescribe, pluginRequest{}, &p.pluginCapabilities); err != nil {
+				fmt.Printf("Ignoring plugin %s: %v\n", p.name, err)
+				continue
+			}
+			if verbose {
+				verbosef("", "Using plugin %s (%s)", p.name, p.path)
+			}
+			found = append(found, p)
+		}
+	}
+	return found
+}
+
+// run runs the hook of the plugin and returns its output
+func (p plugin) run(hook string, req pluginRequest) ([]byte, error) {
+	data, err := json.Marshal(req)
+	if err != nil {
+		return nil, err
+	}
+	cmd := exec.Command(p.path, hook)
+	cmd.Stdin = bytes.NewReader(data)
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("plugin %s %s failed: %v %s", p.name, hook, err, strings.TrimSpace(stderr.String()))
+	}
+	return out, nil
+}
+
+// call runs the hook of the plugin and decodes its JSON output into v
+func (p plugin) call(hook string, req pluginRequest, v interface{}) error {
+	out, err := p.run(hook, req)
+	if err != nil {
+		return err
+	}
+	if err := json.Unmarshal(out, v); err != nil {
+		return fmt.Errorf("plugin %s %s returned invalid JSON: %v", p.name, hook, err)
+	}
+	return nil
+}
+
+// columns fills the columns of the modules, the plugins answering with the
+// column values by module path
+func (ps plugins) columns(modules []Module) {
+	for _, p := range ps {
+		if len(p.Columns) == 0 {
+			continue
+		}
+		values := map[string]map[string]string{}
+		if err := p.call(hookColumns, pluginRequest{Modules: modules}, &values); err != nil {
+			fmt.Println(err)
+			continue
+		}
+		for i, x := range modules {
+			for _, c := range p.Columns {
+				v, ok := values[x.name][c]
+				if !ok {
+					continue
+				}
+				if modules[i].columns == nil {
+					modules[i].columns = map[string]string{}
+				}
+				modules[i].columns[c] = v
+			}
+		}
+	}
+}
+
+// formatter returns the plugin handling the report format, if any
+func (ps plugins) formatter(format string) (plugin, bool) {
+	for _, p := range ps {
+		for _, f := range p.Formats {
+			if f == format {
+				return p, true
+			}
+		}
+	}
+	return plugin{}, false
+}
+
+// postUpdate hands the applied updates to the plugins, showing their output
+func (ps plugins) postUpdate(modules []Module) {
+	for _, p := range ps {
+		if !p.PostUpdate {
+			continue
+		}
+		out, err := p.run(hookPostUpdate, pluginRequest{Modules: modules})
+		os.Stdout.Write(out)
+		if err != nil {
+			fmt.Println(err)
+		}
+	}
+}
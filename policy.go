@@ -0,0 +1,130 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os/exec"
+	"strings"
+	"time"
+
+	"github.com/Masterminds/semver/v3"
+	"github.com/kballard/go-shellquote"
+)
+
+// Policy decisions
+const (
+	policyAllow  = "allow"
+	policyDeny   = "deny"
+	policyReview = "require-review"
+)
+
+// policyConfig delegates upgrade decisions to an external command, such as
+// a CEL evaluator, or `opa eval --format raw` for Rego policies. The command
+// receives the candidate upgrade as JSON on stdin and prints allow, deny or
+// require-review, bare or as a JSON string, and nothing else.
+type policyConfig struct {
+	Command string `yaml:"command"`
+
+	// known are the vulnerabilities of the candidates by path@version,
+	// unknown offline
+	known   map[string][]vulnerability
+	offline bool
+}
+
+// policyInput is the candidate upgrade given to the policy command
+type policyInput struct {
+	Path     string   `json:"path"`
+	From     string   `json:"from"`
+	To       string   `json:"to"`
+	Severity Severity `json:"severity"`
+	AgeDays  float64  `json:"age_days"`
+	// Vulns are the known vulnerabilities of the current version, none when
+	// Offline
+	Vulns   []policyVuln `json:"vulns"`
+	Offline bool         `json:"offline"`
+}
+
+type policyVuln struct {
+	ID string `json:"id"`
+	// Fixed is the first version fixing it, empty when there is no fix yet
+	Fixed string `json:"fixed,omitempty"`
+	// FixedByUpgrade tells whether the target version fixes it
+	FixedByUpgrade bool `json:"fixed_by_upgrade"`
+}
+
+// prefetch looks up the vulnerabilities of the current versions of the
+// candidates at once, unless offline
+func (p *policyConfig) prefetch(modules []Module) error {
+	p.offline = offlineMode
+	if p.offline {
+		notice("Skipping the vulnerability lookups of the policy in offline mode")
+		return nil
+	}
+	current := []goModule{}
+	for _, x := range modules {
+		if x.fromVersion != "" {
+			current = append(current, goModule{Path: x.name, Version: x.fromVersion})
+		}
+	}
+	known, err := newVulnDB().byVersion(current)
+	if err != nil {
+		return fmt.Errorf("policy input: %v", err)
+	}
+	p.known = known
+	return nil
+}
+
+// vulnsOf returns the vulnerabilities of the current version of the module
+func (p *policyConfig) vulnsOf(m Module) []policyVuln {
+	vulns := []policyVuln{}
+	to, _ := semver.NewVersion(m.toVersion)
+	for _, v := range p.known[m.name+"@"+m.fromVersion] {
+		pv := policyVuln{ID: v.ID, Fixed: v.Fixed}
+		if fixed, err := semver.NewVersion(v.Fixed); err == nil && to != nil {
+			pv.FixedByUpgrade = !to.LessThan(fixed)
+		}
+		vulns = append(vulns, pv)
+	}
+	return vulns
+}
+
+func (p *policyConfig) evaluate(m Module) (string, error) {
+	args, err := shellquote.Split(p.Command)
+	if err != nil {
+		return "", fmt.Errorf("policy command: %v", err)
+	}
+	if len(args) == 0 {
+		return policyAllow, nil
+	}
+	input := policyInput{
+		Path:     m.name,
+		From:     m.fromVersion,
+		To:       m.toVersion,
+		Severity: m.severity,
+	}
+	if !m.toTime.IsZero() {
+		input.AgeDays = time.Since(m.toTime).Hours() / 24
+	}
+	input.Vulns = p.vulnsOf(m)
+	input.Offline = p.offline
+	data, err := json.Marshal(input)
+	if err != nil {
+		return "", err
+	}
+	cmd := exec.Command(args[0], args[1:]...)
+	cmd.Stdin = bytes.NewReader(data)
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		return "", fmt.Errorf("policy command failed for %s: %v %s", m.name, err, strings.TrimSpace(stderr.String()))
+	}
+	// Accept JSON strings too, as printed by most policy engines
+	decision := strings.Trim(strings.TrimSpace(string(out)), `"`)
+	switch decision {
+	case policyAllow, policyDeny, policyReview:
+		return decision, nil
+	}
+	return "", fmt.Errorf("policy command returned %q for %s, expected %s, %s or %s", decision, m.name, policyAllow, policyDeny, policyReview)
+}
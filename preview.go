@@ -0,0 +1,37 @@
+package main
+
+import (
+	"os"
+	"strings"
+)
+
+// previewDiff returns the unified diff of the go.mod files, and of the
+// go.sum ones when withSum is set, which the updates would produce. The
+// updates are resolved in sandboxes, leaving the module untouched.
+func previewDiff(modules []Module, withSum bool) (string, error) {
+	wd, err := os.Getwd()
+	if err != nil {
+		return "", err
+	}
+	var diff strings.Builder
+	dirs, targets := upgradesByDir(modules)
+	for _, dir := range dirs {
+		if len(targets[dir]) == 0 {
+			continue
+		}
+		s, err := newSandbox(dir)
+		if err != nil {
+			return "", err
+		}
+		defer s.remove()
+		if err := s.get(targets[dir]...); err != nil {
+			return "", err
+		}
+		d, err := s.diff(wd, withSum)
+		if err != nil {
+			return "", err
+		}
+		diff.WriteString(d)
+	}
+	return diff.String(), nil
+}
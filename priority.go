@@ -0,0 +1,96 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/Masterminds/semver/v3"
+)
+
+// prioritySecurity ranks the updates fixing a known vulnerability of the
+// current version, whatever their severity
+const prioritySecurity = "security"
+
+// defaultPriority is the order in which updates are applied
+const defaultPriority = "security,major,minor,patch,prerelease"
+
+// parsePriority parses a comma separated list of severities, or security
+func parsePriority(s string) ([]string, error) {
+	priority := []string{}
+	for _, name := range strings.Split(s, ",") {
+		name = strings.TrimSpace(name)
+		if name == "" {
+			continue
+		}
+		if name != prioritySecurity {
+			if _, err := parseSeverity(name); err != nil {
+				return nil, fmt.Errorf("%v, or %s", err, prioritySecurity)
+			}
+		}
+		priority = append(priority, name)
+	}
+	return priority, nil
+}
+
+// fixesVulnerability tells whether the target version fixes a known
+// vulnerability of the current one
+func fixesVulnerability(m Module) bool {
+	if m.to == nil {
+		return false
+	}
+	for _, v := range m.vulns {
+		if fixed, err := semver.NewVersion(v.Fixed); err == nil && !m.to.LessThan(fixed) {
+			return true
+		}
+	}
+	return false
+}
+
+// markVulnerable fills the known vulnerabilities of the current versions of
+// the modules, in a single lookup
+func markVulnerable(modules []Module) {
+	current := []goModule{}
+	for _, x := range modules {
+		if x.fromVersion != "" {
+			current = append(current, goModule{Path: x.name, Version: x.fromVersion})
+		}
+	}
+	vulns, err := newVulnDB().vulnerabilities(current)
+	if err != nil {
+		fmt.Println(tr("Error while looking up the vulnerabilities %v", err))
+		return
+	}
+	for i := range modules {
+		modules[i].vulns = vulns[modules[i].name]
+	}
+}
+
+// prioritize sorts modules by the priority of their severity, or of the
+// vulnerabilities they fix, the ones not part of the priority coming last.
+// The order is otherwise preserved.
+func prioritize(modules []Module, priority []string) []Module {
+	rank := func(m Module) int {
+		for i, name := range priority {
+			if name == prioritySecurity && fixesVulnerability(m) || name == m.severity.String() {
+				return i
+			}
+		}
+		return len(priority)
+	}
+	sorted := append([]Module{}, modules...)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return rank(sorted[i]) < rank(sorted[j])
+	})
+	return sorted
+}
+
+// limitUpdates keeps at most max of the prioritized modules, the security
+// fixes first whatever the priority. A max of zero or less means no limit.
+func limitUpdates(modules []Module, max int) (kept, skipped []Module) {
+	if max <= 0 || len(modules) <= max {
+		return modules, nil
+	}
+	modules = prioritize(modules, []string{prioritySecurity})
+	return modules[:max], modules[max:]
+}
@@ -0,0 +1,61 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// profileName is the profile of the configuration selected with --profile
+var profileName string
+
+// profile bundles the flags and ignore rules of a kind of run, like a
+// nightly security job or a weekly pull request
+type profile struct {
+	// Flags are the values of the flags by name, which the command line and
+	// the environment override
+	Flags  map[string]string `yaml:"flags,omitempty"`
+	Ignore []ignoreRule      `yaml:"ignore,omitempty"`
+}
+
+func (c *config) profile(name string) (*profile, error) {
+	p, ok := c.Profiles[name]
+	if !ok {
+		names := []string{}
+		for n := range c.Profiles {
+			names = append(names, n)
+		}
+		sort.Strings(names)
+		if len(names) == 0 {
+			return nil, fmt.Errorf("unknown profile %q, the configuration defines none", name)
+		}
+		return nil, fmt.Errorf("unknown profile %q, expected one of %s", name, strings.Join(names, ", "))
+	}
+	return &p, nil
+}
+
+// applyProfile sets the flags of the profile which were neither given on the
+// command line nor by the environment
+func (c *config) applyProfile(name string) error {
+	p, err := c.profile(name)
+	if err != nil {
+		return err
+	}
+	given := map[string]bool{}
+	flag.Visit(func(f *flag.Flag) {
+		given[f.Name] = true
+	})
+	for key, value := range p.Flags {
+		if key == "profile" || flag.Lookup(key) == nil {
+			return fmt.Errorf("profile %s: unknown flag %q", name, key)
+		}
+		if given[key] {
+			continue
+		}
+		if err := flag.Set(key, value); err != nil {
+			return fmt.Errorf("profile %s: flag %s: %v", name, key, err)
+		}
+	}
+	return nil
+}
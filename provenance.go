@@ -0,0 +1,183 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"os/exec"
+	"strings"
+)
+
+// Provenance status of a target version
+const (
+	provenanceVerified = "verified"
+	provenanceAttested = "attested"
+	// provenanceUnverified is an attestation gh failed to verify
+	provenanceUnverified = "unverified"
+	provenanceNone       = "none"
+	provenanceUnknown    = "unknown"
+)
+
+const (
+	slsaProvenance = "https://slsa.dev/provenance/v1"
+	// slsaPredicates prefixes the predicate types of all the SLSA provenance
+	// versions
+	slsaPredicates = "https://slsa.dev/provenance/"
+	inTotoPayload  = "application/vnd.in-toto+json"
+)
+
+type githubAttestations struct {
+	Attestations []struct {
+		Bundle struct {
+			DSSEEnvelope struct {
+				Payload     string `json:"payload"`
+				PayloadType string `json:"payloadType"`
+			} `json:"dsseEnvelope"`
+		} `json:"bundle"`
+	} `json:"attestations"`
+}
+
+// attested tells whether the repository published a SLSA provenance
+// attestation for the artifact digest. Other attestations, such as SBOMs,
+// don't count.
+func (g *githubProvider) attested(repo, digest string) (bool, error) {
+	var a githubAttestations
+	err := g.api.get(fmt.Sprintf("/repos/%s/attestations/%s", repo, url.PathEscape(digest)), &a)
+	if errors.Is(err, errNotFound) {
+		// Not found when there is no attestation
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	for _, x := range a.Attestations {
+		envelope := x.Bundle.DSSEEnvelope
+		if envelope.PayloadType != inTotoPayload {
+			continue
+		}
+		payload, err := base64.StdEncoding.DecodeString(envelope.Payload)
+		if err != nil {
+			continue
+		}
+		var statement struct {
+			PredicateType string `json:"predicateType"`
+		}
+		if json.Unmarshal(payload, &statement) == nil && strings.HasPrefix(statement.PredicateType, slsaPredicates) {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// downloadedModule is a module version in the module cache
+type downloadedModule struct {
+	Zip string
+	Dir string
+}
+
+func downloadModule(path, version string) (*downloadedModule, error) {
+	args := []string{"mod", "download", "-json", path + "@" + version}
+	out, err := goCommand(args...).Output()
+	if err != nil {
+		return nil, newGoError(args, err)
+	}
+	info := &downloadedModule{}
+	if err := json.Unmarshal(out, info); err != nil {
+		return nil, err
+	}
+	return info, nil
+}
+
+// moduleZip downloads the module zip of the target version, the artifact
+// attestations refer to
+func moduleZip(m Module) (string, error) {
+	info, err := downloadModule(m.name, m.toVersion)
+	if err != nil {
+		return "", err
+	}
+	return info.Zip, nil
+}
+
+func fileDigest(file string) (string, error) {
+	f, err := os.Open(file)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return "", err
+	}
+	return "sha256:" + hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// provenance checks, on a best-effort basis, whether the module zip of the
+// target version has a provenance attestation on GitHub. Attestations are
+// verified with `gh attestation verify` when the GitHub CLI is installed.
+func provenance(p *providers, m Module) string {
+	key := m.name + "@" + m.toVersion
+	var status string
+	if metadata.load("provenance", key, never, &status) && status == provenanceVerified {
+		return status
+	}
+	if metadata.load("provenance", key, statusTTL, &status) {
+		return status
+	}
+	status = lookupProvenance(p, m)
+	if status != provenanceUnknown {
+		metadata.store("provenance", key, status)
+	}
+	return status
+}
+
+// lookupProvenance looks up the attestations of the module zip
+func lookupProvenance(p *providers, m Module) string {
+	pr, repo, _, ok := p.lookup(m.name)
+	gh, isGithub := pr.(*githubProvider)
+	if !ok || !isGithub {
+		return provenanceUnknown
+	}
+	zip, err := moduleZip(m)
+	if err != nil {
+		return provenanceUnknown
+	}
+	digest, err := fileDigest(zip)
+	if err != nil {
+		return provenanceUnknown
+	}
+	attested, err := gh.attested(repo, digest)
+	if err != nil {
+		return provenanceUnknown
+	}
+	if !attested {
+		return provenanceNone
+	}
+	if _, err := exec.LookPath("gh"); err != nil {
+		return provenanceAttested
+	}
+	cmd := exec.Command("gh", "attestation", "verify", zip, "--repo", repo, "--predicate-type", slsaProvenance)
+	if err := cmd.Run(); err != nil {
+		return provenanceUnverified
+	}
+	return provenanceVerified
+}
+
+// checkProvenance fills the provenance status of the modules, flagging the
+// ones without any attestation, or whose attestation failed to verify
+func checkProvenance(p *providers, modules []Module) {
+	for i, x := range modules {
+		modules[i].provenance = provenance(p, x)
+		switch modules[i].provenance {
+		case provenanceNone:
+			progress("Warning: %s %s has no provenance attestation", x.name, x.toVersion)
+		case provenanceUnverified:
+			progress("Warning: %s %s has a provenance attestation that failed to verify", x.name, x.toVersion)
+		}
+	}
+}
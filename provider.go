@@ -0,0 +1,102 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+)
+
+// release is a release, or an annotated tag, published by a code host
+type release struct {
+	Tag   string
+	Notes string
+	URL   string
+}
+
+// provider fetches the release notes and the status of the repositories of
+// a code host
+type provider interface {
+	releases(repo string) ([]release, error)
+	releasesURL(repo string) string
+	compareURL(repo, from, to string) string
+	archived(repo string) (bool, error)
+}
+
+// providerConfig configures the provider of a self-hosted code host
+type providerConfig struct {
+	Host string `yaml:"host"`
+	// Type is one of github, gitlab, bitbucket or gitea
+	Type string `yaml:"type"`
+	// API defaults to the usual API location of the type
+	API string `yaml:"api,omitempty"`
+	// TokenEnv is the environment variable holding the API token
+	TokenEnv string `yaml:"token-env,omitempty"`
+}
+
+// providers finds the provider of the modules by host
+type providers struct {
+	byHost map[string]provider
+}
+
+var defaultProviders = []providerConfig{
+	{Host: "github.com", Type: "github"},
+	{Host: "gitlab.com", Type: "gitlab"},
+	{Host: "bitbucket.org", Type: "bitbucket"},
+}
+
+// newProviders sets up the providers of the well-known hosts and the
+// configured ones. githubToken, given explicitly, takes precedence over the
+// configured token for github.com.
+func newProviders(configs []providerConfig, githubToken string) (*providers, error) {
+	p := &providers{byHost: map[string]provider{}}
+	for _, c := range append(append([]providerConfig{}, defaultProviders...), configs...) {
+		token := ""
+		if c.TokenEnv != "" {
+			token = os.Getenv(c.TokenEnv)
+		}
+		var pr provider
+		switch c.Type {
+		case "github":
+			if c.Host == "github.com" && githubToken != "" {
+				token = githubToken
+			}
+			pr = newGithubProvider(c.Host, c.API, token)
+		case "gitlab":
+			pr = newGitlabProvider(c.Host, c.API, token)
+		case "bitbucket":
+			pr = newBitbucketProvider(c.Host, c.API, token)
+		case "gitea":
+			pr = newGiteaProvider(c.Host, c.API, token)
+		default:
+			return nil, fmt.Errorf("unknown provider type %q for %s, expected github, gitlab, bitbucket or gitea", c.Type, c.Host)
+		}
+		p.byHost[c.Host] = pr
+	}
+	return p, nil
+}
+
+var majorSuffix = regexp.MustCompile(`^v[0-9]+$`)
+
+// lookup returns the provider of the module along with its owner/repo and
+// the subdirectory prefix of its tags for nested modules
+func (p *providers) lookup(path string) (pr provider, repo, tagPrefix string, ok bool) {
+	parts := strings.Split(path, "/")
+	if len(parts) < 3 {
+		return nil, "", "", false
+	}
+	pr, ok = p.byHost[parts[0]]
+	if !ok {
+		return nil, "", "", false
+	}
+	repo = parts[1] + "/" + strings.TrimSuffix(parts[2], ".git")
+	sub := parts[3:]
+	// The major version suffix is not part of the tags
+	if len(sub) > 0 && majorSuffix.MatchString(sub[len(sub)-1]) {
+		sub = sub[:len(sub)-1]
+	}
+	if len(sub) > 0 {
+		tagPrefix = strings.Join(sub, "/") + "/"
+	}
+	return pr, repo, tagPrefix, true
+}
@@ -0,0 +1,56 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/fatih/color"
+)
+
+// quiet leaves out the progress messages, for cron jobs and wrappers which
+// only want the outcome
+var quiet bool
+
+// failedUpdates are the updates which failed, for the quiet summary
+var failedUpdates []Module
+
+// progress prints a progress message, unless quiet
+func progress(format string, args ...interface{}) {
+	if !quiet {
+		fmt.Fprintln(color.Output, tr(format, args...))
+	}
+}
+
+// notice prints a progress message to stderr, unless quiet, for the ones
+// printed before the subcommands run, which mustn't mix with their output
+func notice(format string, args ...interface{}) {
+	if !quiet {
+		fmt.Fprintln(os.Stderr, tr(format, args...))
+	}
+}
+
+// printSummary prints the outcome of the updates on a single line in quiet
+// mode, as updated=1 failed=0 updates=example.com/m@v1.2.0, and nothing when
+// no update was attempted
+func printSummary(applied []Module) {
+	if !quiet || len(applied)+len(failedUpdates) == 0 {
+		return
+	}
+	fields := []string{fmt.Sprintf("updated=%d", len(applied)), fmt.Sprintf("failed=%d", len(failedUpdates))}
+	if len(applied) > 0 {
+		fields = append(fields, "updates="+joinTargets(applied))
+	}
+	if len(failedUpdates) > 0 {
+		fields = append(fields, "failures="+joinTargets(failedUpdates))
+	}
+	fmt.Println(strings.Join(fields, " "))
+}
+
+func joinTargets(modules []Module) string {
+	targets := []string{}
+	for _, x := range modules {
+		targets = append(targets, x.name+"@"+x.toVersion)
+	}
+	return strings.Join(targets, ",")
+}
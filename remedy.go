@@ -0,0 +1,138 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"unicode"
+
+	"github.com/AlecAivazis/survey/v2"
+	term "github.com/AlecAivazis/survey/v2/terminal"
+	"golang.org/x/crypto/ssh/terminal"
+)
+
+// mismatchedModule matches the module version failing the go.sum
+// verification, as in verifying example.com/m@v1.0.0/go.mod: checksum mismatch
+var mismatchedModule = regexp.MustCompile(`verifying ([^\s@]+)@([^\s:/]+)(/go\.mod)?: checksum mismatch`)
+
+const (
+	remedyClear   = "Remove the cached copy and retry"
+	remedyDirect  = "Retry downloading from the repository, bypassing the module proxy"
+	remedyDetails = "Show what happened"
+	remedySkip    = "Skip the update"
+)
+
+// escapeModulePath escapes the upper case letters of a module path as the
+// module cache does, as !a for A
+func escapeModulePath(path string) string {
+	var b strings.Builder
+	for _, r := range path {
+		if unicode.IsUpper(r) {
+			b.WriteByte('!')
+			r = unicode.ToLower(r)
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
+
+// clearCachedModule removes the downloaded files and the extracted copy of
+// the module version from the module cache, which are read-only
+func clearCachedModule(path, version string) error {
+	out, err := goCommand("env", "GOMODCACHE").Output()
+	if err != nil {
+		return newGoError([]string{"env", "GOMODCACHE"}, err)
+	}
+	cache := strings.TrimSpace(string(out))
+	if cache == "" {
+		// GOMODCACHE appeared in Go 1.15
+		out, err := goCommand("env", "GOPATH").Output()
+		if err != nil {
+			return newGoError([]string{"env", "GOPATH"}, err)
+		}
+		cache = filepath.Join(filepath.SplitList(strings.TrimSpace(string(out)))[0], "pkg", "mod")
+	}
+	escaped := filepath.FromSlash(escapeModulePath(path))
+	files, err := filepath.Glob(filepath.Join(cache, "cache", "download", escaped, "@v", version+".*"))
+	if err != nil {
+		return err
+	}
+	for _, f := range files {
+		if err := os.Remove(f); err != nil {
+			return err
+		}
+	}
+	dir := filepath.Join(cache, escaped+"@"+version)
+	if _, err := os.Stat(dir); os.IsNotExist(err) {
+		return nil
+	}
+	err = filepath.Walk(dir, func(file string, fi os.FileInfo, err error) error {
+		if err == nil && fi.IsDir() {
+			err = os.Chmod(file, 0755)
+		}
+		return err
+	})
+	if err != nil {
+		return err
+	}
+	return os.RemoveAll(dir)
+}
+
+// remedyChecksum guides through a go.sum verification failure of the
+// update, in a terminal: the cached copy may be corrupted, or the proxy may
+// serve another content than the one recorded in go.sum. It returns the
+// error of the last attempt, nil once the update succeeds.
+func remedyChecksum(m Module, err error) error {
+	if !strings.Contains(err.Error(), "checksum mismatch") || !terminal.IsTerminal(int(os.Stdin.Fd())) {
+		return err
+	}
+	path, version := m.name, m.toVersion
+	if match := mismatchedModule.FindStringSubmatch(err.Error()); match != nil {
+		path, version = match[1], match[2]
+	}
+	for {
+		answer := ""
+		prompt := &survey.Select{
+			Message: fmt.Sprintf("The checksum of %s@%s doesn't match go.sum", path, version),
+			Options: []string{remedyClear, remedyDirect, remedyDetails, remedySkip},
+		}
+		if aerr := ask(prompt, &answer); aerr == term.InterruptErr {
+			fmt.Println(tr("Bye"))
+			os.Exit(0)
+		} else if aerr != nil {
+			return err
+		}
+		switch answer {
+		case remedyClear:
+			if cerr := clearCachedModule(path, version); cerr != nil {
+				fmt.Printf("Error while removing the cached copy %v\n", cerr)
+				continue
+			}
+			err = goGet(m.dir, m.name, m.toVersion)
+		case remedyDirect:
+			saved := goEnv
+			goEnv = append(goEnv[:len(goEnv):len(goEnv)], "GOPROXY=direct")
+			err = goGet(m.dir, m.name, m.toVersion)
+			goEnv = saved
+		case remedyDetails:
+			fmt.Printf(`The content of %s@%s differs from the checksum recorded in go.sum or by
+the checksum database. Either the copy in the module cache is corrupted,
+which removing it fixes, or the module proxy serves another content than
+the repository, or the version was retagged upstream. Check the go.sum
+change in the version control history before trusting a new checksum, and
+see https://go.dev/ref/mod#authenticating.
+
+`, path, version)
+			fmt.Println(err)
+			continue
+		default:
+			return err
+		}
+		if err == nil || !strings.Contains(err.Error(), "checksum mismatch") {
+			return err
+		}
+		fmt.Println(tr("Error while updating %s: %v", m.name, err))
+	}
+}
@@ -0,0 +1,217 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	htmltemplate "html/template"
+	"path/filepath"
+	"strings"
+	"text/template"
+)
+
+// reportEntry is a module in the report, along with its changelog summary
+// when changelogs are fetched
+type reportEntry struct {
+	Path      string
+	From      string
+	To        string
+	Severity  Severity
+	Size      string
+	BuildTime string
+	// Analysis summarizes the Fixed and Introduced findings of the analyzer
+	Analysis   string
+	Fixed      []string
+	Introduced []string
+	Changelog  *changelog
+}
+
+// reportData is what the report templates render
+type reportData struct {
+	Entries []reportEntry
+	// Sizes and BuildTimes tell whether the binary size and the build time
+	// changes were measured
+	Sizes      bool
+	BuildTimes bool
+	// Analyses tells whether the findings of the analyzer were compared
+	Analyses bool
+}
+
+var markdownTable = `# Module updates
+
+| Module | From | To | Severity |{{if .Sizes}} Binary size |{{end}}{{if .BuildTimes}} Build time |{{end}}{{if .Analyses}} Findings |{{end}}
+| --- | --- | --- | --- |{{if .Sizes}} --- |{{end}}{{if .BuildTimes}} --- |{{end}}{{if .Analyses}} --- |{{end}}
+{{- range .Entries}}
+| {{.Path}} | {{.From}} | {{.To}} | {{.Severity}} |{{if $.Sizes}} {{.Size}} |{{end}}{{if $.BuildTimes}} {{.BuildTime}} |{{end}}{{if $.Analyses}} {{.Analysis}} |{{end}}
+{{- end}}
+`
+
+var markdownEntry = `
+## {{.Path}} {{.From}} → {{.To}}
+{{- if .Fixed}}
+
+**Fixed findings**
+{{range .Fixed}}
+- {{.}}
+{{- end}}{{end}}
+{{- if .Introduced}}
+
+**Introduced findings**
+{{range .Introduced}}
+- {{.}}
+{{- end}}{{end}}
+{{with .Changelog}}
+[Release notes]({{.URL}}) · [Compare]({{.CompareURL}}){{if .Archived}}
+
+**The repository is archived**{{end}}
+{{- if .Breaking}}
+
+**Breaking changes**
+{{range .Breaking}}
+- {{.}}
+{{- end}}{{end}}
+{{- if .Deprecated}}
+
+**Deprecations**
+{{range .Deprecated}}
+- {{.}}
+{{- end}}{{end}}
+{{- if .Features}}
+
+**Notable features**
+{{range .Features}}
+- {{.}}
+{{- end}}{{end}}
+{{end}}`
+
+var htmlReport = `<!DOCTYPE html>
+<html>
+<head><meta charset="utf-8"><title>Module updates</title></head>
+<body>
+<h1>Module updates</h1>
+<table>
+<tr><th>Module</th><th>From</th><th>To</th><th>Severity</th>{{if .Sizes}}<th>Binary size</th>{{end}}{{if .BuildTimes}}<th>Build time</th>{{end}}{{if .Analyses}}<th>Findings</th>{{end}}</tr>
+{{- range .Entries}}
+<tr><td>{{.Path}}</td><td>{{.From}}</td><td>{{.To}}</td><td>{{.Severity}}</td>{{if $.Sizes}}<td>{{.Size}}</td>{{end}}{{if $.BuildTimes}}<td>{{.BuildTime}}</td>{{end}}{{if $.Analyses}}<td>{{.Analysis}}</td>{{end}}</tr>
+{{- end}}
+</table>
+{{- range .Entries}}{{if or .Changelog .Fixed .Introduced}}
+<h2>{{.Path}} {{.From}} → {{.To}}</h2>
+{{- if .Fixed}}
+<h3>Fixed findings</h3>
+<ul>{{range .Fixed}}<li>{{.}}</li>{{end}}</ul>
+{{- end}}
+{{- if .Introduced}}
+<h3>Introduced findings</h3>
+<ul>{{range .Introduced}}<li>{{.}}</li>{{end}}</ul>
+{{- end}}
+{{- with .Changelog}}
+<p><a href="{{.URL}}">Release notes</a> · <a href="{{.CompareURL}}">Compare</a></p>
+{{- if .Archived}}
+<p><strong>The repository is archived</strong></p>
+{{- end}}
+{{- if .Breaking}}
+<h3>Breaking changes</h3>
+<ul>{{range .Breaking}}<li>{{.}}</li>{{end}}</ul>
+{{- end}}
+{{- if .Deprecated}}
+<h3>Deprecations</h3>
+<ul>{{range .Deprecated}}<li>{{.}}</li>{{end}}</ul>
+{{- end}}
+{{- if .Features}}
+<h3>Notable features</h3>
+<ul>{{range .Features}}<li>{{.}}</li>{{end}}</ul>
+{{- end}}
+{{- end}}
+{{- end}}{{end}}
+</body>
+</html>
+`
+
+// writeReport writes the modules as a markdown report, or an HTML one when
+// file has an .html extension. Changelogs are fetched when p is not nil,
+// and skipped once the rate limit of their provider is exceeded. Plugins may
+// handle other extensions.
+func writeReport(file string, modules []Module, p *providers, plugs plugins) error {
+	format := strings.TrimPrefix(strings.ToLower(filepath.Ext(file)), ".")
+	if plug, ok := plugs.formatter(format); ok {
+		out, err := plug.run(hookFormat, pluginRequest{Format: format, Modules: modules})
+		if err != nil {
+			return err
+		}
+		return writeFileAtomic(file, out)
+	}
+	data := newReportData(modules, p)
+	var buf bytes.Buffer
+	switch strings.ToLower(filepath.Ext(file)) {
+	case ".html", ".htm":
+		t := htmltemplate.Must(htmltemplate.New("report").Parse(htmlReport))
+		if err := t.Execute(&buf, data); err != nil {
+			return err
+		}
+	default:
+		out, err := markdownReport(data)
+		if err != nil {
+			return err
+		}
+		buf.Write(out)
+	}
+	return writeFileAtomic(file, buf.Bytes())
+}
+
+// newReportData gathers what the reports show of the modules, fetching
+// their changelogs when p is not nil
+func newReportData(modules []Module, p *providers) reportData {
+	data := reportData{}
+	for _, x := range modules {
+		e := reportEntry{
+			Path:     x.name,
+			From:     x.fromVersion,
+			To:       x.toVersion,
+			Severity: x.severity,
+		}
+		if x.sizeDelta != nil {
+			e.Size = formatSize(*x.sizeDelta)
+			data.Sizes = true
+		}
+		if x.buildTimeDelta != nil {
+			e.BuildTime = formatDuration(*x.buildTimeDelta)
+			data.BuildTimes = true
+		}
+		if x.analysis != nil {
+			e.Analysis = x.analysis.String()
+			e.Fixed, e.Introduced = x.analysis.fixed, x.analysis.introduced
+			data.Analyses = true
+		}
+		if p != nil {
+			c, err := fetchChangelog(p, x)
+			if err == errRateLimited {
+				fmt.Printf("API rate limit exceeded, skipping the changelog of %s (set an API token for a higher limit)\n", x.name)
+			} else if err != nil {
+				fmt.Printf("Error while fetching the changelog of %s: %v\n", x.name, err)
+			}
+			e.Changelog = c
+		}
+		data.Entries = append(data.Entries, e)
+	}
+	return data
+}
+
+// markdownReport renders the table of the modules, followed by their
+// changelogs
+func markdownReport(data reportData) ([]byte, error) {
+	var buf bytes.Buffer
+	t := template.Must(template.New("report").Parse(markdownTable))
+	if err := t.Execute(&buf, data); err != nil {
+		return nil, err
+	}
+	e := template.Must(template.New("entry").Parse(markdownEntry))
+	for _, entry := range data.Entries {
+		if entry.Changelog == nil && len(entry.Fixed) == 0 && len(entry.Introduced) == 0 {
+			continue
+		}
+		if err := e.Execute(&buf, entry); err != nil {
+			return nil, err
+		}
+	}
+	return buf.Bytes(), nil
+}
@@ -0,0 +1,89 @@
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/AlecAivazis/survey/v2"
+	term "github.com/AlecAivazis/survey/v2/terminal"
+	"github.com/fatih/color"
+)
+
+// maxRemovals is the number of API removals shown per module
+const maxRemovals = 10
+
+// reviewMajors shows the changelog and the API removals of each selected
+// major upgrade, which is only kept once confirmed. The other upgrades are
+// kept as selected.
+func reviewMajors(modules []Module, hosts *providers, offline bool) []Module {
+	kept := []Module{}
+	bold := color.New(color.Bold).SprintFunc()
+	for _, x := range modules {
+		if x.severity != SeverityMajor {
+			kept = append(kept, x)
+			continue
+		}
+		fmt.Fprintf(color.Output, "\n%s %s -> %s\n", bold(tr("Major upgrade of")), formatName(x, len(x.name)), formatTo(x))
+		if !offline {
+			c, err := fetchChangelog(hosts, x)
+			if err != nil {
+				fmt.Println(tr("  Changelog unavailable: %v", err))
+			}
+			printChangelog(c)
+		}
+		removed, err := apiRemovals(x)
+		if err != nil {
+			fmt.Println(tr("  API changes unavailable: %v", err))
+		} else if len(removed) == 0 {
+			fmt.Println(tr("  No exported identifier removed"))
+		} else {
+			fmt.Println(tr("  %d exported identifier(s) removed:", len(removed)))
+			for i, id := range removed {
+				if i == maxRemovals {
+					fmt.Println(tr("    and %d more", len(removed)-maxRemovals))
+					break
+				}
+				fmt.Printf("    - %s\n", id)
+			}
+		}
+		include := false
+		prompt := &survey.Confirm{Message: tr("Include the upgrade of %s to %s?", x.name, x.toVersion)}
+		err = ask(prompt, &include)
+		if err == term.InterruptErr {
+			fmt.Println(tr("Bye"))
+			os.Exit(0)
+		} else if err != nil {
+			fmt.Println(err)
+		}
+		if include {
+			kept = append(kept, x)
+		}
+	}
+	return kept
+}
+
+func printChangelog(c *changelog) {
+	if c == nil {
+		return
+	}
+	if c.Archived {
+		fmt.Fprintln(color.Output, color.RedString(tr("  The repository is archived")))
+	}
+	for _, section := range []struct {
+		title string
+		items []string
+	}{
+		{"Breaking changes", c.Breaking},
+		{"Deprecations", c.Deprecated},
+		{"Notable features", c.Features},
+	} {
+		if len(section.items) == 0 {
+			continue
+		}
+		fmt.Printf("  %s:\n", tr(section.title))
+		for _, item := range section.items {
+			fmt.Printf("    - %s\n", item)
+		}
+	}
+	fmt.Println(tr("  Release notes: %s\n  Compare: %s", c.URL, c.CompareURL))
+}
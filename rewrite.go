@@ -0,0 +1,264 @@
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"go/ast"
+	"go/format"
+	"go/parser"
+	"go/token"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// maxMajorProbes bounds the lookups of newer major versions, which stop at
+// the first missing one
+const maxMajorProbes = 20
+
+// rewrittenPath returns the import path with the old module path replaced by
+// the new one, unless it isn't a package of the old module
+func rewrittenPath(path, old, new string) (string, bool) {
+	if path != old && !strings.HasPrefix(path, old+"/") {
+		return "", false
+	}
+	// Other major versions are other modules
+	rest := strings.SplitN(strings.TrimPrefix(path, old+"/"), "/", 2)
+	if path != old && majorSuffix.MatchString(rest[0]) {
+		return "", false
+	}
+	return new + strings.TrimPrefix(path, old), true
+}
+
+// rewriteImports replaces the imports of the old module path, or of its
+// packages, by the new one in the Go files of the module in root, whatever
+// their build constraints, and formats them as gofmt does. It returns the
+// rewritten files.
+func rewriteImports(root, old, new string) ([]string, error) {
+	rewritten := []string{}
+	err := filepath.Walk(root, func(file string, fi os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		name := fi.Name()
+		if fi.IsDir() {
+			if file == root {
+				return nil
+			}
+			if name == "vendor" || name == "testdata" || strings.HasPrefix(name, ".") || strings.HasPrefix(name, "_") {
+				return filepath.SkipDir
+			}
+			if _, err := os.Stat(filepath.Join(file, "go.mod")); err == nil {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if !strings.HasSuffix(name, ".go") {
+			return nil
+		}
+		src, err := ioutil.ReadFile(file)
+		if err != nil {
+			return err
+		}
+		out, err := rewriteFile(file, src, old, new)
+		if err != nil || out == nil {
+			// Files the go command can't parse either are left alone
+			return nil
+		}
+		rewritten = append(rewritten, file)
+		return writeFileAtomicMode(file, out, fi.Mode().Perm())
+	})
+	return rewritten, err
+}
+
+// rewriteFile returns the source with the imports rewritten, nil when none
+// was
+func rewriteFile(file string, src []byte, old, new string) ([]byte, error) {
+	fset := token.NewFileSet()
+	f, err := parser.ParseFile(fset, file, src, parser.ParseComments)
+	if err != nil {
+		return nil, err
+	}
+	edited := false
+	for _, spec := range f.Imports {
+		path, err := strconv.Unquote(spec.Path.Value)
+		if err != nil {
+			continue
+		}
+		p, ok := rewrittenPath(path, old, new)
+		if !ok {
+			continue
+		}
+		// A renamed module may change the name of the package, which is
+		// kept for the code using it
+		if spec.Name == nil && assumedName(p) != assumedName(path) {
+			// On the line of the path, for the sorting of the imports
+			spec.Name = &ast.Ident{NamePos: spec.Path.Pos(), Name: assumedName(path)}
+		}
+		spec.Path.Value = strconv.Quote(p)
+		edited = true
+	}
+	if !edited {
+		return nil, nil
+	}
+	// The new paths may sort elsewhere in their import block
+	ast.SortImports(fset, f)
+	var buf bytes.Buffer
+	if err := format.Node(&buf, fset, f); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// assumedName guesses the name of the package of an import path as
+// goimports does, from its last element without major version
+func assumedName(path string) string {
+	base, _ := majorOf(path)
+	name := base[strings.LastIndex(base, "/")+1:]
+	name = strings.TrimPrefix(name, "go-")
+	if i := strings.IndexAny(name, ".-"); i > 0 {
+		name = name[:i]
+	}
+	return name
+}
+
+// majorPath returns the module path of the major version of base
+func majorPath(base string, major int) string {
+	if strings.HasPrefix(base, "gopkg.in/") {
+		return fmt.Sprintf("%s.v%d", base, major)
+	}
+	if major <= 1 {
+		return base
+	}
+	return fmt.Sprintf("%s/v%d", base, major)
+}
+
+// latestMajor returns the path and latest version of the newest major
+// version of the module, probing the next major versions until one is
+// missing
+func latestMajor(dir, path string, debug bool) (string, string, error) {
+	base, major := majorOf(path)
+	latest, version := "", ""
+	for n := major + 1; n <= major+maxMajorProbes; n++ {
+		p := majorPath(base, n)
+		list, err := goList(dir, debug, "list", "-mod=mod", "-json", "-m", p+"@latest")
+		if err != nil || len(list) == 0 {
+			break
+		}
+		latest, version = p, list[0].Version
+	}
+	if latest == "" {
+		return "", "", fmt.Errorf("no major version of %s newer than v%d", base, major)
+	}
+	return latest, version, nil
+}
+
+// migrateCommand moves the modules requiring a module to another module
+// path, its newest major version by default: it requires the new path,
+// rewrites the imports, tidies go.mod and checks the build
+func migrateCommand(args []string, debug bool) error {
+	fs := flag.NewFlagSet("migrate", flag.ExitOnError)
+	recursive := fs.Bool("r", false, "Migrate every module of the go.work workspace or below the current directory requiring the module")
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+	if fs.NArg() < 1 || fs.NArg() > 2 {
+		return fmt.Errorf("usage: go-mod-upgrade migrate [-r] <module> [<new module>[@version]]")
+	}
+	old := fs.Arg(0)
+	dirs := []string{""}
+	if *recursive {
+		all, err := enterWorkspace()
+		if err != nil {
+			return err
+		}
+		dirs = []string{}
+		for _, dir := range all {
+			required, err := requirements(dir)
+			if err != nil {
+				return fmt.Errorf("%s: %v", dir, err)
+			}
+			if required[old] != "" {
+				dirs = append(dirs, dir)
+			}
+		}
+		if len(dirs) == 0 {
+			return fmt.Errorf("no module of the workspace requires %s", old)
+		}
+	}
+	cfg, err := loadConfig()
+	if err != nil {
+		return err
+	}
+	if cfg.UpdateCommand != "" {
+		updateCommand = cfg.UpdateCommand
+	}
+	var target, version string
+	if fs.NArg() == 2 {
+		target = fs.Arg(1)
+		version = "latest"
+		if i := strings.LastIndex(target, "@"); i > 0 {
+			target, version = target[:i], target[i+1:]
+		}
+	} else if target, version, err = latestMajor(dirs[0], old, debug); err != nil {
+		return err
+	}
+	for _, dir := range dirs {
+		if err := checkWritable(dir); err != nil {
+			return err
+		}
+	}
+	lock, err := acquireLock()
+	if err != nil {
+		return err
+	}
+	defer lock.release()
+	for _, dir := range dirs {
+		root := dir
+		if root == "" {
+			if root, err = projectRoot(); err != nil {
+				return err
+			}
+		}
+		if dir == "" {
+			progress("Migrating %s to %s@%s...", old, target, version)
+		} else {
+			progress("Migrating %s to %s@%s in %s...", old, target, version, dir)
+		}
+		if err := goGet(dir, target, version); err != nil {
+			return fmt.Errorf("go get %s@%s: %v", target, version, err)
+		}
+		files, err := rewriteImports(root, old, target)
+		if err != nil {
+			return err
+		}
+		printRewritten(root, files)
+		if out, err := goCommandIn(dir, "mod", "tidy").CombinedOutput(); err != nil {
+			return fmt.Errorf("go mod tidy: %v: %s", err, strings.TrimSpace(string(out)))
+		}
+		if vendored(dir) {
+			if err := revendor(dir); err != nil {
+				return err
+			}
+		}
+		if out, err := goCommandIn(dir, "build", "-o", os.DevNull, "./...").CombinedOutput(); err != nil {
+			fmt.Printf("The build fails after the migration, the API of %s differs:\n%s\n", target, strings.TrimSpace(string(out)))
+		}
+	}
+	return nil
+}
+
+// printRewritten reports the files whose imports were rewritten, relative to
+// root
+func printRewritten(root string, files []string) {
+	fmt.Printf("Rewrote the imports of %d file(s)\n", len(files))
+	for _, file := range files {
+		if rel, err := filepath.Rel(root, file); err == nil {
+			file = rel
+		}
+		fmt.Printf("  %s\n", file)
+	}
+}
@@ -0,0 +1,41 @@
+package main
+
+import "os/exec"
+
+// commandRunner runs the commands of the tool, mostly go commands. Tests and
+// embedders replace it with a fake which looks at the arguments, directory
+// and environment of the command instead of running it.
+type commandRunner interface {
+	// Output returns the standard output of the command, its standard error
+	// being in the *exec.ExitError on failure
+	Output(cmd *exec.Cmd) ([]byte, error)
+	// CombinedOutput returns the standard output and error of the command
+	CombinedOutput(cmd *exec.Cmd) ([]byte, error)
+}
+
+// execRunner runs the commands with os/exec
+type execRunner struct{}
+
+func (execRunner) Output(cmd *exec.Cmd) ([]byte, error) {
+	return cmd.Output()
+}
+
+func (execRunner) CombinedOutput(cmd *exec.Cmd) ([]byte, error) {
+	return cmd.CombinedOutput()
+}
+
+// runner runs the go commands and the update commands
+var runner commandRunner = execRunner{}
+
+// goCmd is a command run through the runner
+type goCmd struct {
+	*exec.Cmd
+}
+
+func (c goCmd) Output() ([]byte, error) {
+	return runner.Output(c.Cmd)
+}
+
+func (c goCmd) CombinedOutput() ([]byte, error) {
+	return runner.CombinedOutput(c.Cmd)
+}
@@ -0,0 +1,212 @@
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// sandbox resolves hypothetical upgrades of a module on copies of its go.mod
+// and go.sum, which the go commands use through -modfile in GOFLAGS. The
+// real files are only touched when the upgrades are applied.
+type sandbox struct {
+	// dir is the module directory, where the go commands run
+	dir string
+	// files are the real go.mod and go.sum files
+	files  []string
+	tmp    string
+	before map[string]string
+}
+
+var sandboxFiles = []string{"go.mod", "go.sum"}
+
+// newSandbox copies the go.mod and go.sum of the module in dir
+func newSandbox(dir string) (*sandbox, error) {
+	goMod, err := goModFileIn(dir)
+	if err != nil {
+		return nil, err
+	}
+	tmp, err := ioutil.TempDir("", "go-mod-upgrade-sandbox")
+	if err != nil {
+		return nil, err
+	}
+	s := &sandbox{dir: dir, files: []string{goMod, sumFile(goMod)}, tmp: tmp, before: map[string]string{}}
+	for i, name := range sandboxFiles {
+		data, err := ioutil.ReadFile(s.files[i])
+		if err != nil && !os.IsNotExist(err) {
+			s.remove()
+			return nil, err
+		}
+		s.before[name] = string(data)
+		if err := ioutil.WriteFile(filepath.Join(tmp, name), data, 0644); err != nil {
+			s.remove()
+			return nil, err
+		}
+	}
+	return s, nil
+}
+
+func (s *sandbox) remove() {
+	os.RemoveAll(s.tmp)
+}
+
+// run runs f with every go command reading and writing the copies
+func (s *sandbox) run(f func() error) error {
+	return withModFile(filepath.Join(s.tmp, "go.mod"), f)
+}
+
+// get runs go get with the targets, as module@version
+func (s *sandbox) get(targets ...string) error {
+	args := append([]string{"get"}, targets...)
+	return s.run(func() error {
+		if _, err := goCommandIn(s.dir, args...).Output(); err != nil {
+			return newGoError(args, err)
+		}
+		return nil
+	})
+}
+
+// buildList returns the versions of the modules of the build list
+func (s *sandbox) buildList(debug bool) (map[string]string, error) {
+	versions := map[string]string{}
+	err := s.run(func() error {
+		list, err := goList(s.dir, debug, "list", "-m", "-json", "all")
+		for _, m := range list {
+			if !m.Main {
+				versions[m.Path] = m.Version
+			}
+		}
+		return err
+	})
+	return versions, err
+}
+
+// requirers returns the modules requiring each module@version of the graph
+func (s *sandbox) requirers() (map[string][]string, error) {
+	graph := map[string][]string{}
+	args := []string{"mod", "graph"}
+	err := s.run(func() error {
+		out, err := goCommandIn(s.dir, args...).Output()
+		if err != nil {
+			return newGoError(args, err)
+		}
+		for _, line := range strings.Split(string(out), "\n") {
+			fields := strings.Fields(line)
+			if len(fields) == 2 {
+				graph[fields[1]] = append(graph[fields[1]], fields[0])
+			}
+		}
+		return nil
+	})
+	return graph, err
+}
+
+// diff returns the unified diff of the copies against the real files, named
+// relative to wd, limited to go.mod unless withSum is set
+func (s *sandbox) diff(wd string, withSum bool) (string, error) {
+	files := sandboxFiles
+	if !withSum {
+		files = files[:1]
+	}
+	var diff strings.Builder
+	for i, name := range files {
+		after, err := ioutil.ReadFile(filepath.Join(s.tmp, name))
+		if err != nil && !os.IsNotExist(err) {
+			return "", err
+		}
+		file := s.files[i]
+		if rel, err := filepath.Rel(wd, file); err == nil {
+			file = rel
+		}
+		file = filepath.ToSlash(file)
+		diff.WriteString(unifiedDiff("a/"+file, "b/"+file, s.before[name], string(after)))
+	}
+	return diff.String(), nil
+}
+
+// moduleChange is a module of the build list which an upgrade adds, removes
+// or moves to another version, From being empty for the added ones and To
+// for the removed ones
+type moduleChange struct {
+	Path string   `json:"path"`
+	From string   `json:"from,omitempty"`
+	To   string   `json:"to,omitempty"`
+	Via  []string `json:"via,omitempty"`
+}
+
+// upgradesByDir groups the targets of the upgrades by module directory
+func upgradesByDir(modules []Module) ([]string, map[string][]string) {
+	targets := map[string][]string{}
+	for _, x := range modules {
+		targets[x.dir] = append(targets[x.dir], x.name+"@"+x.toVersion)
+	}
+	return moduleDirsOf(modules), targets
+}
+
+// transitiveChanges resolves the upgrades in sandboxes and returns the
+// changes of the build lists besides the upgraded modules, with the modules
+// requiring the added ones
+func transitiveChanges(modules []Module, debug bool) ([]moduleChange, error) {
+	upgraded := map[string]bool{}
+	for _, x := range modules {
+		upgraded[x.name] = true
+	}
+	dirs, targets := upgradesByDir(modules)
+	seen := map[string]bool{}
+	changes := []moduleChange{}
+	for _, dir := range dirs {
+		if len(targets[dir]) == 0 {
+			continue
+		}
+		s, err := newSandbox(dir)
+		if err != nil {
+			return nil, err
+		}
+		defer s.remove()
+		before, err := s.buildList(debug)
+		if err != nil {
+			return nil, err
+		}
+		if err := s.get(targets[dir]...); err != nil {
+			return nil, err
+		}
+		after, err := s.buildList(debug)
+		if err != nil {
+			return nil, err
+		}
+		graph, err := s.requirers()
+		if err != nil {
+			return nil, err
+		}
+		found := []moduleChange{}
+		for path, to := range after {
+			if from := before[path]; from != to && !upgraded[path] {
+				c := moduleChange{Path: path, From: from, To: to}
+				if from == "" {
+					for _, r := range graph[path+"@"+to] {
+						c.Via = append(c.Via, strings.SplitN(r, "@", 2)[0])
+					}
+				}
+				found = append(found, c)
+			}
+		}
+		for path, from := range before {
+			if _, ok := after[path]; !ok && !upgraded[path] {
+				found = append(found, moduleChange{Path: path, From: from})
+			}
+		}
+		for _, c := range found {
+			key := c.Path + " " + c.From + " " + c.To
+			if !seen[key] {
+				seen[key] = true
+				changes = append(changes, c)
+			}
+		}
+	}
+	sort.Slice(changes, func(i, j int) bool {
+		return changes[i].Path < changes[j].Path
+	})
+	return changes, nil
+}
@@ -0,0 +1,143 @@
+package main
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+)
+
+// dependencyTrackConfig tells where to upload the SBOM written with --sbom
+type dependencyTrackConfig struct {
+	URL            string `yaml:"url"`
+	Project        string `yaml:"project"`
+	ProjectVersion string `yaml:"project-version,omitempty"`
+	// APIKeyEnv names the environment variable of the API key, DT_API_KEY by
+	// default
+	APIKeyEnv string `yaml:"api-key-env,omitempty"`
+	// AutoCreate creates the project on the first upload
+	AutoCreate bool `yaml:"auto-create,omitempty"`
+}
+
+// cycloneDX is the subset of a CycloneDX 1.4 BOM we write
+type cycloneDX struct {
+	BOMFormat    string      `json:"bomFormat"`
+	SpecVersion  string      `json:"specVersion"`
+	SerialNumber string      `json:"serialNumber"`
+	Version      int         `json:"version"`
+	Metadata     bomMetadata `json:"metadata"`
+	Components   []component `json:"components"`
+}
+
+type bomMetadata struct {
+	Timestamp time.Time           `json:"timestamp"`
+	Tools     []map[string]string `json:"tools"`
+	Component *component          `json:"component,omitempty"`
+}
+
+type component struct {
+	Type    string `json:"type"`
+	BOMRef  string `json:"bom-ref"`
+	Name    string `json:"name"`
+	Version string `json:"version,omitempty"`
+	PURL    string `json:"purl"`
+}
+
+func purl(path, version string) string {
+	p := "pkg:golang/" + path
+	if version != "" {
+		p += "@" + version
+	}
+	return p
+}
+
+// buildSBOM lists the modules of the build list as a CycloneDX BOM, the
+// replacements in place of the modules they replace
+func buildSBOM(debug bool) ([]byte, error) {
+	list, err := goList("", debug, "list", "-mod=mod", "-json", "-m", "all")
+	if err != nil {
+		return nil, err
+	}
+	serial := make([]byte, 16)
+	if _, err := rand.Read(serial); err != nil {
+		return nil, err
+	}
+	serial[6] = serial[6]&0x0f | 0x40
+	serial[8] = serial[8]&0x3f | 0x80
+	bom := cycloneDX{
+		BOMFormat:    "CycloneDX",
+		SpecVersion:  "1.4",
+		SerialNumber: fmt.Sprintf("urn:uuid:%x-%x-%x-%x-%x", serial[0:4], serial[4:6], serial[6:8], serial[8:10], serial[10:]),
+		Version:      1,
+		Metadata: bomMetadata{
+			Timestamp: time.Now().UTC(),
+			Tools:     []map[string]string{{"name": "go-mod-upgrade"}},
+		},
+		Components: []component{},
+	}
+	for _, m := range list {
+		if m.Main {
+			bom.Metadata.Component = &component{Type: "application", BOMRef: purl(m.Path, ""), Name: m.Path, PURL: purl(m.Path, "")}
+			continue
+		}
+		if m.Replace != nil && m.Replace.Version != "" {
+			m = *m.Replace
+		}
+		if m.Version == "" {
+			continue
+		}
+		ref := purl(m.Path, m.Version)
+		bom.Components = append(bom.Components, component{Type: "library", BOMRef: ref, Name: m.Path, Version: m.Version, PURL: ref})
+	}
+	return json.MarshalIndent(bom, "", "  ")
+}
+
+// writeSBOM writes the SBOM of the module to the file, and uploads it to
+// Dependency-Track when configured
+func writeSBOM(file string, dt *dependencyTrackConfig, debug bool) error {
+	data, err := buildSBOM(debug)
+	if err != nil {
+		return err
+	}
+	if err := writeFileAtomic(file, append(data, '\n')); err != nil {
+		return err
+	}
+	if dt == nil {
+		return nil
+	}
+	return uploadSBOM(dt, data)
+}
+
+// uploadSBOM sends the SBOM to the BOM endpoint of Dependency-Track, which
+// processes it asynchronously
+func uploadSBOM(c *dependencyTrackConfig, data []byte) error {
+	if c.URL == "" || c.Project == "" {
+		return errors.New("dependency-track: configure the url and project")
+	}
+	keyEnv := c.APIKeyEnv
+	if keyEnv == "" {
+		keyEnv = "DT_API_KEY"
+	}
+	key := os.Getenv(keyEnv)
+	if key == "" {
+		return fmt.Errorf("dependency-track: %s is not set", keyEnv)
+	}
+	api := newAPIClient(strings.TrimSuffix(c.URL, "/")+"/api/v1", key, func(key string) string { return key })
+	api.authHeader = "X-Api-Key"
+	var result struct{ Token string }
+	err := api.put("/bom", map[string]interface{}{
+		"projectName":    c.Project,
+		"projectVersion": c.ProjectVersion,
+		"autoCreate":     c.AutoCreate,
+		"bom":            base64.StdEncoding.EncodeToString(data),
+	}, &result)
+	if err != nil {
+		return fmt.Errorf("dependency-track: %w", err)
+	}
+	progress("Uploaded the SBOM to Dependency-Track (%s)", result.Token)
+	return nil
+}
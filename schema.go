@@ -0,0 +1,6 @@
+package main
+
+// schemaVersion is the version of the JSON output, described by the JSON
+// schemas of the schema directory. Fields are only added within a major
+// version, renaming or removing one bumps it.
+const schemaVersion = "1.0"
@@ -0,0 +1,60 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+)
+
+// selection records which modules were checked (true) or explicitly
+// unchecked (false) in the picker, so an interrupted session can be resumed.
+type selection map[string]bool
+
+// stateFile returns the file holding the kind of state of the current
+// project, stored in the user cache directory and keyed by its root.
+func stateFile(kind string) (string, error) {
+	root, err := projectRoot()
+	if err != nil {
+		return "", err
+	}
+	cache, err := os.UserCacheDir()
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256([]byte(root))
+	return filepath.Join(cache, "go-mod-upgrade", kind, fmt.Sprintf("%x.json", sum[:8])), nil
+}
+
+func loadSelection() selection {
+	s := selection{}
+	file, err := stateFile("selections")
+	if err != nil {
+		return s
+	}
+	data, err := ioutil.ReadFile(file)
+	if err != nil {
+		return s
+	}
+	if err := json.Unmarshal(data, &s); err != nil {
+		return selection{}
+	}
+	return s
+}
+
+func (s selection) save() error {
+	file, err := stateFile("selections")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(file), 0755); err != nil {
+		return err
+	}
+	data, err := json.Marshal(s)
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(file, data, 0644)
+}
@@ -0,0 +1,186 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+)
+
+// JSON-RPC 2.0 error codes
+const (
+	rpcParseError     = -32700
+	rpcInvalidRequest = -32600
+	rpcMethodNotFound = -32601
+	rpcInvalidParams  = -32602
+	rpcServerError    = -32000
+)
+
+type rpcRequest struct {
+	JSONRPC string          `json:"jsonrpc"`
+	ID      json.RawMessage `json:"id,omitempty"`
+	Method  string          `json:"method"`
+	Params  json.RawMessage `json:"params,omitempty"`
+}
+
+type rpcError struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+type rpcResponse struct {
+	JSONRPC string          `json:"jsonrpc"`
+	ID      json.RawMessage `json:"id"`
+	Result  interface{}     `json:"result,omitempty"`
+	Error   *rpcError       `json:"error,omitempty"`
+}
+
+type applyParams struct {
+	Modules []struct {
+		Path    string `json:"path"`
+		Version string `json:"version"`
+	} `json:"modules"`
+}
+
+type applyResult struct {
+	Path  string `json:"path"`
+	Error string `json:"error,omitempty"`
+}
+
+type server struct {
+	offline bool
+}
+
+func serveCommand(args []string, offline bool) error {
+	fs := flag.NewFlagSet("serve", flag.ExitOnError)
+	stdio := fs.Bool("stdio", false, "Serve JSON-RPC over stdin/stdout")
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+	if !*stdio {
+		return errors.New("serve: only the --stdio transport is supported")
+	}
+	srv := &server{offline: offline}
+	return srv.serve(os.Stdin, os.Stdout)
+}
+
+// serve answers newline-delimited JSON-RPC 2.0 requests until r is closed.
+func (s *server) serve(r io.Reader, w io.Writer) error {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 16*1024*1024)
+	enc := json.NewEncoder(w)
+	for scanner.Scan() {
+		if len(scanner.Bytes()) == 0 {
+			continue
+		}
+		var req rpcRequest
+		if err := json.Unmarshal(scanner.Bytes(), &req); err != nil {
+			if err := enc.Encode(rpcResponse{JSONRPC: "2.0", ID: json.RawMessage("null"), Error: &rpcError{rpcParseError, err.Error()}}); err != nil {
+				return err
+			}
+			continue
+		}
+		result, rerr := s.handle(req)
+		// Requests without id are notifications and get no response
+		if req.ID == nil {
+			continue
+		}
+		if err := enc.Encode(rpcResponse{JSONRPC: "2.0", ID: req.ID, Result: result, Error: rerr}); err != nil {
+			return err
+		}
+	}
+	return scanner.Err()
+}
+
+// allowedVersion resolves the version query when there is an allowlist, and
+// returns the version it resolves to once approved
+func allowedVersion(allowed *allowlist, path, query string) (string, error) {
+	if allowed == nil {
+		return query, nil
+	}
+	if query == "" {
+		query = "upgrade"
+	}
+	list, err := goList("", false, "list", "-mod=mod", "-json", "-m", path+"@"+query)
+	if err != nil {
+		return "", err
+	}
+	if len(list) == 0 {
+		return "", fmt.Errorf("%s@%s not found", path, query)
+	}
+	version := list[0].Version
+	if !allowed.permits(newModule(path, "", version)) {
+		return "", fmt.Errorf("%s %s is not in the allowlist", path, version)
+	}
+	return version, nil
+}
+
+func (s *server) handle(req rpcRequest) (interface{}, *rpcError) {
+	if req.JSONRPC != "2.0" {
+		return nil, &rpcError{rpcInvalidRequest, "jsonrpc must be 2.0"}
+	}
+	switch req.Method {
+	case "discover":
+		cfg, err := loadConfig()
+		if err != nil {
+			return nil, &rpcError{rpcServerError, err.Error()}
+		}
+		modules, err := discover(false, false, nil)
+		if err != nil {
+			return nil, &rpcError{rpcServerError, err.Error()}
+		}
+		modules, err = cfg.filter(modules, false)
+		if err != nil {
+			return nil, &rpcError{rpcServerError, err.Error()}
+		}
+		return map[string]interface{}{"modules": modules, "offline": s.offline}, nil
+	case "apply":
+		var params applyParams
+		if err := json.Unmarshal(req.Params, &params); err != nil {
+			return nil, &rpcError{rpcInvalidParams, err.Error()}
+		}
+		if err := checkWritable(""); err != nil {
+			return nil, &rpcError{rpcServerError, err.Error()}
+		}
+		cfg, err := loadConfig()
+		if err != nil {
+			return nil, &rpcError{rpcServerError, err.Error()}
+		}
+		if cfg.UpdateCommand != "" {
+			updateCommand = cfg.UpdateCommand
+		}
+		allowed, err := configuredAllowlist(cfg)
+		if err != nil {
+			return nil, &rpcError{rpcServerError, err.Error()}
+		}
+		lock, err := acquireLock()
+		if err != nil {
+			return nil, &rpcError{rpcServerError, err.Error()}
+		}
+		defer lock.release()
+		results := []applyResult{}
+		for _, m := range params.Modules {
+			if m.Path == "" {
+				return nil, &rpcError{rpcInvalidParams, "module path is required"}
+			}
+			res := applyResult{Path: m.Path}
+			if version, err := allowedVersion(allowed, m.Path, m.Version); err != nil {
+				res.Error = err.Error()
+			} else if err := goGet("", m.Path, version); err != nil {
+				res.Error = err.Error()
+			}
+			results = append(results, res)
+		}
+		if vendored("") {
+			if err := revendor(""); err != nil {
+				return nil, &rpcError{rpcServerError, err.Error()}
+			}
+		}
+		return map[string]interface{}{"results": results}, nil
+	default:
+		return nil, &rpcError{rpcMethodNotFound, fmt.Sprintf("method %q not found", req.Method)}
+	}
+}
@@ -0,0 +1,71 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/Masterminds/semver/v3"
+)
+
+// Severity is the most significant part of the version changed by an update
+type Severity int
+
+const (
+	SeverityMetadata Severity = iota
+	SeverityPrerelease
+	SeverityPatch
+	SeverityMinor
+	SeverityMajor
+	// SeverityNonSemver is for versions which can't be compared
+	SeverityNonSemver
+)
+
+var severityNames = map[Severity]string{
+	SeverityMajor:      "major",
+	SeverityMinor:      "minor",
+	SeverityPatch:      "patch",
+	SeverityPrerelease: "prerelease",
+	SeverityMetadata:   "metadata",
+	SeverityNonSemver:  "non-semver",
+}
+
+func (s Severity) String() string {
+	return severityNames[s]
+}
+
+func (s Severity) MarshalText() ([]byte, error) {
+	return []byte(s.String()), nil
+}
+
+func (s *Severity) UnmarshalText(text []byte) error {
+	parsed, err := parseSeverity(string(text))
+	if err != nil {
+		return err
+	}
+	*s = parsed
+	return nil
+}
+
+func parseSeverity(name string) (Severity, error) {
+	for s, n := range severityNames {
+		if n == name {
+			return s, nil
+		}
+	}
+	return 0, fmt.Errorf("unknown severity %q, expected one of major, minor, patch, prerelease, metadata, non-semver", name)
+}
+
+func severity(from, to *semver.Version) Severity {
+	switch {
+	case from == nil || to == nil:
+		return SeverityNonSemver
+	case from.Major() != to.Major():
+		return SeverityMajor
+	case from.Minor() != to.Minor():
+		return SeverityMinor
+	case from.Patch() != to.Patch():
+		return SeverityPatch
+	case from.Prerelease() != to.Prerelease():
+		return SeverityPrerelease
+	}
+	return SeverityMetadata
+}
@@ -0,0 +1,312 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/AlecAivazis/survey/v2"
+	"github.com/Masterminds/semver/v3"
+)
+
+// skewedModule is a dependency required at different versions by the
+// workspace modules
+type skewedModule struct {
+	path string
+	// dirs lists the workspace modules by required version
+	dirs     map[string][]string
+	versions []string
+	highest  string
+}
+
+// requirements lists the versions required by the go.mod of dir
+func requirements(dir string) (map[string]string, error) {
+	args := []string{"mod", "edit", "-json"}
+	out, err := goCommandIn(dir, args...).Output()
+	if err != nil {
+		return nil, newGoError(args, err)
+	}
+	var gomod struct {
+		Require []struct {
+			Path    string
+			Version string
+		}
+	}
+	if err := json.Unmarshal(out, &gomod); err != nil {
+		return nil, err
+	}
+	required := map[string]string{}
+	for _, r := range gomod.Require {
+		required[r.Path] = r.Version
+	}
+	return required, nil
+}
+
+// findSkew lists the dependencies required at different versions, sorted by
+// path, with their versions sorted in ascending order
+func findSkew(dirs []string) ([]skewedModule, error) {
+	byPath := map[string]map[string][]string{}
+	for _, dir := range dirs {
+		required, err := requirements(dir)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %v", dir, err)
+		}
+		for path, version := range required {
+			if byPath[path] == nil {
+				byPath[path] = map[string][]string{}
+			}
+			byPath[path][version] = append(byPath[path][version], dir)
+		}
+	}
+	skewed := []skewedModule{}
+	for path, dirs := range byPath {
+		if len(dirs) < 2 {
+			continue
+		}
+		s := skewedModule{path: path, dirs: dirs}
+		for v := range dirs {
+			s.versions = append(s.versions, v)
+		}
+		sort.Slice(s.versions, func(i, j int) bool {
+			return compareVersions(s.versions[i], s.versions[j]) < 0
+		})
+		s.highest = s.versions[len(s.versions)-1]
+		skewed = append(skewed, s)
+	}
+	sort.Slice(skewed, func(i, j int) bool {
+		return skewed[i].path < skewed[j].path
+	})
+	return skewed, nil
+}
+
+// compareVersions compares semver versions, falling back to a lexical
+// comparison for the others
+func compareVersions(a, b string) int {
+	va, erra := semver.NewVersion(a)
+	vb, errb := semver.NewVersion(b)
+	if erra != nil || errb != nil {
+		return strings.Compare(a, b)
+	}
+	return va.Compare(vb)
+}
+
+// skewCommand reports the dependencies required at different versions by the
+// workspace modules, and aligns them to the highest version
+func skewCommand(args []string) error {
+	fs := flag.NewFlagSet("skew", flag.ExitOnError)
+	align := fs.Bool("align", false, "Align every dependency to its highest version, and the go and toolchain directives, without asking")
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+	dirs, err := enterWorkspace()
+	if err != nil {
+		return err
+	}
+	cfg, err := loadConfig()
+	if err != nil {
+		return err
+	}
+	if cfg.UpdateCommand != "" {
+		updateCommand = cfg.UpdateCommand
+	}
+	if err := checkToolchainSkew(dirs, *align); err != nil {
+		return err
+	}
+	skewed, err := findSkew(dirs)
+	if err != nil {
+		return err
+	}
+	if len(skewed) == 0 {
+		fmt.Println("No version skew across the workspace modules")
+		return nil
+	}
+	for _, s := range skewed {
+		fmt.Println(s.path)
+		for _, v := range s.versions {
+			fmt.Printf("  %s %s\n", padRight(v, 12), strings.Join(s.dirs[v], ", "))
+		}
+	}
+	if !*align {
+		confirm := false
+		prompt := &survey.Confirm{
+			Message: fmt.Sprintf("Align the %d dependencies to their highest version?", len(skewed)),
+		}
+		if err := ask(prompt, &confirm); err != nil || !confirm {
+			return err
+		}
+	}
+	allowed, err := configuredAllowlist(cfg)
+	if err != nil {
+		return err
+	}
+	lock, err := acquireLock()
+	if err != nil {
+		return err
+	}
+	defer lock.release()
+	modules := []Module{}
+	for _, s := range skewed {
+		for _, v := range s.versions[:len(s.versions)-1] {
+			for _, dir := range s.dirs[v] {
+				m := newModule(s.path, v, s.highest)
+				m.dir = dir
+				modules = append(modules, m)
+			}
+		}
+	}
+	modules = allowed.enforce(modules)
+	for _, dir := range moduleDirsOf(modules) {
+		if err := checkWritable(dir); err != nil {
+			return err
+		}
+	}
+	update(modules, nil, nil, false)
+	return revendorAll(modules)
+}
+
+// goDirectives are the go and toolchain directives of a go.mod
+type goDirectives struct {
+	Go        string
+	Toolchain string
+}
+
+// required returns the Go version the module requires, its toolchain when
+// newer than its go directive, as go1.22.3 or 1.21
+func (d goDirectives) required() string {
+	if d.Toolchain != "" && d.Toolchain != "default" {
+		if t, g := parseGoVersion(d.Toolchain), parseGoVersion(d.Go); t != nil && (g == nil || g.LessThan(t)) {
+			return d.Toolchain
+		}
+	}
+	return d.Go
+}
+
+func (d goDirectives) String() string {
+	if d.Toolchain == "" {
+		return "go " + d.Go
+	}
+	return "go " + d.Go + ", toolchain " + d.Toolchain
+}
+
+// goDirectivesOf reads the directives of the go.mod of each directory
+func goDirectivesOf(dirs []string) (map[string]goDirectives, error) {
+	directives := map[string]goDirectives{}
+	for _, dir := range dirs {
+		args := []string{"mod", "edit", "-json"}
+		out, err := goCommandIn(dir, args...).Output()
+		if err != nil {
+			return nil, fmt.Errorf("%s: %v", dir, newGoError(args, err))
+		}
+		var gomod struct {
+			Go        string
+			Toolchain json.RawMessage
+		}
+		if err := json.Unmarshal(out, &gomod); err != nil {
+			return nil, fmt.Errorf("%s: %v", dir, err)
+		}
+		d := goDirectives{Go: gomod.Go}
+		// The toolchain is a string, or an object with the go versions
+		// documenting it so
+		var toolchain struct {
+			Name string
+		}
+		if json.Unmarshal(gomod.Toolchain, &d.Toolchain) != nil && json.Unmarshal(gomod.Toolchain, &toolchain) == nil {
+			d.Toolchain = toolchain.Name
+		}
+		directives[dir] = d
+	}
+	return directives, nil
+}
+
+// alignedDirectives returns the directives every workspace module would
+// declare once aligned: the highest go version, and the highest toolchain
+// when newer, and whether they differ today
+func alignedDirectives(directives map[string]goDirectives) (goDirectives, bool) {
+	aligned := goDirectives{}
+	var highestGo, highestToolchain *semver.Version
+	seen := map[goDirectives]bool{}
+	for _, d := range directives {
+		seen[d] = true
+		if v := parseGoVersion(d.Go); v != nil && (highestGo == nil || highestGo.LessThan(v)) {
+			highestGo, aligned.Go = v, d.Go
+		}
+		if v := parseGoVersion(d.Toolchain); v != nil && (highestToolchain == nil || highestToolchain.LessThan(v)) {
+			highestToolchain, aligned.Toolchain = v, d.Toolchain
+		}
+	}
+	if highestToolchain != nil && highestGo != nil && !highestGo.LessThan(highestToolchain) {
+		aligned.Toolchain = ""
+	}
+	return aligned, len(seen) > 1
+}
+
+// printToolchainSkew lists the workspace modules by directives
+func printToolchainSkew(directives map[string]goDirectives) {
+	byDirectives := map[string][]string{}
+	keys := []string{}
+	for dir, d := range directives {
+		if byDirectives[d.String()] == nil {
+			keys = append(keys, d.String())
+		}
+		byDirectives[d.String()] = append(byDirectives[d.String()], dir)
+	}
+	sort.Strings(keys)
+	fmt.Println(tr("The workspace modules declare different Go versions:"))
+	for _, k := range keys {
+		sort.Strings(byDirectives[k])
+		fmt.Printf("  %s %s\n", padRight(k, 30), strings.Join(byDirectives[k], ", "))
+	}
+}
+
+// alignToolchains writes the aligned directives to the go.mod of the
+// workspace modules declaring others
+func alignToolchains(directives map[string]goDirectives, aligned goDirectives) error {
+	dirs := []string{}
+	for dir := range directives {
+		dirs = append(dirs, dir)
+	}
+	sort.Strings(dirs)
+	for _, dir := range dirs {
+		d := directives[dir]
+		if d == aligned {
+			continue
+		}
+		args := []string{"mod", "edit", "-go=" + aligned.Go}
+		if aligned.Toolchain != "" {
+			args = append(args, "-toolchain="+aligned.Toolchain)
+		} else if d.Toolchain != "" {
+			args = append(args, "-toolchain=none")
+		}
+		if out, err := goCommandIn(dir, args...).CombinedOutput(); err != nil {
+			return fmt.Errorf("%s: go %s: %v: %s", dir, strings.Join(args, " "), err, strings.TrimSpace(string(out)))
+		}
+		directives[dir] = aligned
+		progress("Aligned %s to %s", dir, aligned)
+	}
+	return nil
+}
+
+// checkToolchainSkew reports the workspace modules declaring different go or
+// toolchain directives and offers to align them, without asking when align
+// is set. The go command run in each module is then picked accordingly.
+func checkToolchainSkew(dirs []string, align bool) error {
+	directives, err := goDirectivesOf(dirs)
+	if err != nil {
+		return err
+	}
+	// The quiet mode only aligns when asked to
+	if aligned, skewed := alignedDirectives(directives); skewed && (align || !quiet) {
+		if !quiet {
+			printToolchainSkew(directives)
+		}
+		if align || askConfirm(tr("Align every module to %s?", aligned)) {
+			if err := alignToolchains(directives, aligned); err != nil {
+				return err
+			}
+		}
+	}
+	selectMemberToolchains(directives)
+	return nil
+}
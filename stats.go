@@ -0,0 +1,210 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"math"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// stats measures the freshness of the direct dependencies
+type stats struct {
+	Time         time.Time `json:"time"`
+	Dependencies int       `json:"dependencies"`
+	Outdated     int       `json:"outdated"`
+	// Libyear is the sum of the time lag of each dependency behind its
+	// latest version, in years
+	Libyear    float64        `json:"libyear"`
+	Severities map[string]int `json:"severities"`
+	Modules    []moduleStats  `json:"modules,omitempty"`
+}
+
+type moduleStats struct {
+	Path     string   `json:"path"`
+	From     string   `json:"from"`
+	To       string   `json:"to"`
+	Severity Severity `json:"severity"`
+	Libyear  float64  `json:"libyear"`
+}
+
+const hoursPerYear = 365.25 * 24
+
+func computeStats(debug bool) (*stats, error) {
+	list, err := goList("", debug, "list", "-u", "-mod=mod", "-json", "-m", "all")
+	if err != nil {
+		return nil, err
+	}
+	s := &stats{Time: time.Now().UTC(), Severities: map[string]int{}}
+	for _, m := range list {
+		if m.Main || m.Indirect {
+			continue
+		}
+		s.Dependencies++
+		if m.Update == nil {
+			continue
+		}
+		s.Outdated++
+		x := newModule(m.Path, m.Version, m.Update.Version)
+		s.Severities[x.severity.String()]++
+		ms := moduleStats{Path: m.Path, From: m.Version, To: m.Update.Version, Severity: x.severity}
+		if m.Time != nil && m.Update.Time != nil && m.Update.Time.After(*m.Time) {
+			ms.Libyear = m.Update.Time.Sub(*m.Time).Hours() / hoursPerYear
+		}
+		s.Libyear += ms.Libyear
+		s.Modules = append(s.Modules, ms)
+	}
+	sort.Slice(s.Modules, func(i, j int) bool {
+		return s.Modules[i].Libyear > s.Modules[j].Libyear
+	})
+	return s, nil
+}
+
+func (s *stats) print(w io.Writer) {
+	fmt.Fprintf(w, "Dependencies: %d direct, %d outdated\n", s.Dependencies, s.Outdated)
+	fmt.Fprintf(w, "Libyear: %.1f years\n", s.Libyear)
+	for _, sev := range []Severity{SeverityMajor, SeverityMinor, SeverityPatch, SeverityPrerelease, SeverityMetadata, SeverityNonSemver} {
+		if n := s.Severities[sev.String()]; n > 0 {
+			fmt.Fprintf(w, "  %-11s %d\n", sev.String()+":", n)
+		}
+	}
+	for _, m := range s.Modules {
+		fmt.Fprintf(w, "%5.1f  %s %s -> %s\n", m.Libyear, m.Path, m.From, m.To)
+	}
+}
+
+// statsPoint is a run of the stats command, kept in the history
+type statsPoint struct {
+	Time         time.Time      `json:"time"`
+	Dependencies int            `json:"dependencies"`
+	Outdated     int            `json:"outdated"`
+	Libyear      float64        `json:"libyear"`
+	Severities   map[string]int `json:"severities"`
+}
+
+func loadHistory() ([]statsPoint, error) {
+	file, err := stateFile("stats")
+	if err != nil {
+		return nil, err
+	}
+	data, err := ioutil.ReadFile(file)
+	if os.IsNotExist(err) {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+	history := []statsPoint{}
+	if err := json.Unmarshal(data, &history); err != nil {
+		return nil, err
+	}
+	return history, nil
+}
+
+// record appends the statistics to the history of the module
+func (s *stats) record() error {
+	history, err := loadHistory()
+	if err != nil {
+		return err
+	}
+	history = append(history, statsPoint{
+		Time:         s.Time,
+		Dependencies: s.Dependencies,
+		Outdated:     s.Outdated,
+		Libyear:      s.Libyear,
+		Severities:   s.Severities,
+	})
+	file, err := stateFile("stats")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(file), 0755); err != nil {
+		return err
+	}
+	data, err := json.Marshal(history)
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(file, data, 0644)
+}
+
+// printHistory charts the libyear of the recorded runs
+func printHistory(w io.Writer, history []statsPoint) {
+	if len(history) == 0 {
+		fmt.Fprintln(w, "No statistics recorded yet, run go-mod-upgrade stats")
+		return
+	}
+	highest := 0.0
+	for _, p := range history {
+		highest = math.Max(highest, p.Libyear)
+	}
+	const width = 40
+	for _, p := range history {
+		bar := 0
+		if highest > 0 {
+			bar = int(math.Round(p.Libyear / highest * width))
+		}
+		fmt.Fprintf(w, "%s %6.1f %3d outdated %s\n", p.Time.Local().Format("2006-01-02 15:04"), p.Libyear, p.Outdated, strings.Repeat("#", bar))
+	}
+}
+
+// statsCommand reports the freshness of the dependencies, as text or JSON,
+// and records it in the history
+func statsCommand(args []string, debug bool) error {
+	fs := flag.NewFlagSet("stats", flag.ExitOnError)
+	asJSON := fs.Bool("json", false, "Output the statistics as JSON")
+	showHistory := fs.Bool("history", false, "Show how the libyear evolved over the recorded runs")
+	outputFile := fs.String("output-file", "", "Write the statistics to a file, replaced atomically, or - for stdout")
+	badgeFile := fs.String("badge", "", "Write a shields.io endpoint JSON summarizing the outdated dependencies to a file")
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+	out := newOutput(*outputFile)
+	if *showHistory {
+		history, err := loadHistory()
+		if err != nil {
+			return err
+		}
+		if *asJSON {
+			if err := json.NewEncoder(out).Encode(struct {
+				SchemaVersion string       `json:"schemaVersion"`
+				History       []statsPoint `json:"history"`
+			}{schemaVersion, history}); err != nil {
+				return err
+			}
+		} else {
+			printHistory(out, history)
+		}
+		return out.close()
+	}
+	s, err := computeStats(debug)
+	if err != nil {
+		return err
+	}
+	if err := s.record(); err != nil {
+		fmt.Fprintf(os.Stderr, "Error while recording the statistics %v\n", err)
+	}
+	if *badgeFile != "" {
+		if err := writeBadge(*badgeFile, s); err != nil {
+			return err
+		}
+	}
+	if *asJSON {
+		enc := json.NewEncoder(out)
+		enc.SetIndent("", "  ")
+		if err := enc.Encode(struct {
+			SchemaVersion string `json:"schemaVersion"`
+			stats
+		}{schemaVersion, *s}); err != nil {
+			return err
+		}
+	} else {
+		s.print(out)
+	}
+	return out.close()
+}
@@ -0,0 +1,70 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/Masterminds/semver/v3"
+)
+
+var defaultStepChecks = []string{"go build ./...", "go test ./..."}
+
+// stepChecks are the commands run after each step of an upgrade with
+// --step, which upgrades straight to the target when empty
+var stepChecks []string
+
+// upgradeSteps returns the versions an upgrade goes through, the latest
+// patch of each minor version after the current one, ending with the target
+func upgradeSteps(m Module) ([]string, error) {
+	versions, err := newerVersions(m, false)
+	if err != nil {
+		return nil, err
+	}
+	steps := []string{}
+	for i, v := range versions {
+		if i+1 < len(versions) {
+			current, _ := semver.NewVersion(v)
+			next, _ := semver.NewVersion(versions[i+1])
+			if current.Major() == next.Major() && current.Minor() == next.Minor() {
+				continue
+			}
+		}
+		steps = append(steps, v)
+	}
+	if len(steps) == 0 || steps[len(steps)-1] != m.toVersion {
+		steps = append(steps, m.toVersion)
+	}
+	return steps, nil
+}
+
+// stepUpgrade upgrades the module one minor version at a time, running the
+// step checks after each one. On failure, the module goes back to the last
+// step which passed them.
+func stepUpgrade(m Module) error {
+	steps, err := upgradeSteps(m)
+	if err != nil {
+		return err
+	}
+	good := m.fromVersion
+	for _, v := range steps {
+		fmt.Printf("Stepping %s to %s...\n", m.name, v)
+		err := goGet(m.dir, m.name, v)
+		if err == nil {
+			err = runCommands(m.dir, "step check", stepChecks)
+		}
+		if err == nil {
+			err = crossBuild(m.dir)
+		}
+		if err == nil {
+			good = v
+			continue
+		}
+		if rerr := goGet(m.dir, m.name, good); rerr != nil {
+			return fmt.Errorf("step %s: %v, and going back to %s failed: %v", v, err, good, rerr)
+		}
+		if good != m.fromVersion {
+			return fmt.Errorf("step %s: %v, staying at %s", v, err, good)
+		}
+		return fmt.Errorf("step %s: %v", v, err)
+	}
+	return nil
+}
@@ -0,0 +1,229 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"os"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/fatih/color"
+)
+
+// candidate is a possible replacement of a dead module
+type candidate struct {
+	path  string
+	stars int
+	forks int
+	// named is set when the deprecation message names the candidate
+	named bool
+}
+
+// suggester finds replacements on pkg.go.dev, ranked by the popularity of
+// their repository on deps.dev
+type suggester struct {
+	pkgsite string
+	depsdev string
+	client  *http.Client
+}
+
+func newSuggester() *suggester {
+	s := &suggester{
+		pkgsite: "https://pkg.go.dev",
+		depsdev: "https://api.deps.dev",
+		client:  &http.Client{Timeout: 20 * time.Second},
+	}
+	if u := os.Getenv("PKGSITE_URL"); u != "" {
+		s.pkgsite = strings.TrimSuffix(u, "/")
+	}
+	if u := os.Getenv("DEPSDEV_URL"); u != "" {
+		s.depsdev = strings.TrimSuffix(u, "/")
+	}
+	return s
+}
+
+var (
+	searchResult = regexp.MustCompile(`<a href="/([^"?#]+)"\s+data-gtmc="search result"`)
+	// modulePathInText matches the module paths named in a deprecation
+	modulePathInText = regexp.MustCompile(`\b[a-z0-9.-]+\.[a-z]{2,}(/[A-Za-z0-9._~-]+)+`)
+)
+
+// search returns the modules found on pkg.go.dev for the query
+func (s *suggester) search(query string) ([]string, error) {
+	resp, err := s.client.Get(s.pkgsite + "/search?m=module&limit=10&q=" + url.QueryEscape(query))
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("pkg.go.dev search: %s", resp.Status)
+	}
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	paths := []string{}
+	for _, m := range searchResult.FindAllStringSubmatch(string(body), -1) {
+		paths = append(paths, m[1])
+	}
+	return paths, nil
+}
+
+// popularity returns the stars and forks of the repository of the module,
+// known to deps.dev for GitHub, GitLab and Bitbucket projects
+func (s *suggester) popularity(path string) (int, int, error) {
+	parts := strings.Split(path, "/")
+	if len(parts) < 3 {
+		return 0, 0, nil
+	}
+	project := url.PathEscape(strings.Join(parts[:3], "/"))
+	resp, err := s.client.Get(s.depsdev + "/v3/projects/" + project)
+	if err != nil {
+		return 0, 0, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode == http.StatusNotFound {
+		return 0, 0, nil
+	}
+	if resp.StatusCode != http.StatusOK {
+		return 0, 0, fmt.Errorf("deps.dev: %s", resp.Status)
+	}
+	var p struct {
+		StarsCount int `json:"starsCount"`
+		ForksCount int `json:"forksCount"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&p); err != nil {
+		return 0, 0, err
+	}
+	return p.StarsCount, p.ForksCount, nil
+}
+
+// candidates returns the replacements of the module, the ones named by the
+// deprecation message first, then by popularity
+func (s *suggester) candidates(path, deprecation string) ([]candidate, error) {
+	base, _ := majorOf(path)
+	seen := map[string]bool{}
+	found := []candidate{}
+	add := func(p string, named bool) {
+		if b, _ := majorOf(p); b == base || seen[p] {
+			return
+		}
+		seen[p] = true
+		found = append(found, candidate{path: p, named: named})
+	}
+	for _, m := range modulePathInText.FindAllString(deprecation, -1) {
+		add(strings.TrimRight(m, "."), true)
+	}
+	query := base[strings.LastIndex(base, "/")+1:]
+	results, err := s.search(query)
+	if err != nil {
+		return nil, err
+	}
+	for _, p := range results {
+		add(p, false)
+	}
+	for i := range found {
+		if found[i].stars, found[i].forks, err = s.popularity(found[i].path); err != nil {
+			return nil, err
+		}
+	}
+	sort.SliceStable(found, func(i, j int) bool {
+		if found[i].named != found[j].named {
+			return found[i].named
+		}
+		return found[i].stars > found[j].stars
+	})
+	return found, nil
+}
+
+// deadModules returns the direct dependencies which are deprecated or whose
+// repository is archived, with the reason
+func deadModules(hosts *providers, debug bool) (map[string]string, error) {
+	list, err := goList("", debug, "list", "-mod=mod", "-m", "-u", "-json", "all")
+	if err != nil {
+		return nil, err
+	}
+	dead := map[string]string{}
+	for _, m := range list {
+		if m.Main || m.Indirect {
+			continue
+		}
+		if m.Deprecated != "" {
+			dead[m.Path] = "deprecated: " + m.Deprecated
+			continue
+		}
+		if pr, repo, _, ok := hosts.lookup(m.Path); ok {
+			if archived, err := pr.archived(repo); err == nil && archived {
+				dead[m.Path] = "the repository is archived"
+			}
+		}
+	}
+	return dead, nil
+}
+
+func suggestCommand(args []string, debug bool) error {
+	fs := flag.NewFlagSet("suggest", flag.ExitOnError)
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+	cfg, err := loadConfig()
+	if err != nil {
+		return err
+	}
+	hosts, err := newProviders(cfg.Providers, "")
+	if err != nil {
+		return err
+	}
+	dead := map[string]string{}
+	if fs.NArg() > 0 {
+		for _, path := range fs.Args() {
+			dead[path] = ""
+		}
+	} else if dead, err = deadModules(hosts, debug); err != nil {
+		return err
+	}
+	if len(dead) == 0 {
+		fmt.Println("No deprecated or archived dependency")
+		return nil
+	}
+	paths := []string{}
+	for path := range dead {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+	s := newSuggester()
+	for _, path := range paths {
+		if dead[path] != "" {
+			fmt.Fprintf(color.Output, "%s (%s)\n", color.New(color.Bold).Sprint(path), dead[path])
+		} else {
+			fmt.Fprintln(color.Output, color.New(color.Bold).Sprint(path))
+		}
+		found, err := s.candidates(path, strings.TrimPrefix(dead[path], "deprecated: "))
+		if err != nil {
+			fmt.Printf("  Error while looking for replacements: %v\n", err)
+			continue
+		}
+		if len(found) == 0 {
+			fmt.Println("  No replacement found")
+			continue
+		}
+		maxPath := 0
+		for _, c := range found {
+			maxPath = max(maxPath, len(c.path))
+		}
+		for _, c := range found {
+			line := fmt.Sprintf("  %s ★ %-6d forks %-5d", padRight(c.path, maxPath), c.stars, c.forks)
+			if c.named {
+				line += " named in the deprecation"
+			}
+			fmt.Println(strings.TrimRight(line, " "))
+		}
+	}
+	return nil
+}
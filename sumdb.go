@@ -0,0 +1,162 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"path"
+	"strings"
+	"time"
+	"unicode"
+)
+
+// Checksum database status of a target version
+const (
+	checksumVerified = "verified"
+	checksumMissing  = "missing"
+	checksumExcluded = "excluded"
+	checksumDisabled = "disabled"
+	checksumUnknown  = "unknown"
+)
+
+// sumDB looks up versions in the checksum database configured by GOSUMDB,
+// skipping the modules matching GONOSUMDB (which defaults to GOPRIVATE)
+type sumDB struct {
+	name     string
+	url      string
+	disabled bool
+	noSumDB  []string
+	client   *http.Client
+}
+
+func newSumDB() (*sumDB, error) {
+	out, err := goCommand("env", "-json", "GOSUMDB", "GONOSUMDB", "GOPRIVATE").Output()
+	if err != nil {
+		return nil, err
+	}
+	var env map[string]string
+	if err := json.Unmarshal(out, &env); err != nil {
+		return nil, err
+	}
+	db := &sumDB{client: &http.Client{Timeout: 10 * time.Second}}
+	noSumDB := env["GONOSUMDB"]
+	if noSumDB == "" {
+		noSumDB = env["GOPRIVATE"]
+	}
+	for _, p := range strings.Split(noSumDB, ",") {
+		if p = strings.TrimSpace(p); p != "" {
+			db.noSumDB = append(db.noSumDB, p)
+		}
+	}
+	// GOSUMDB is "off", a name, or "name+key" optionally followed by its URL
+	fields := strings.Fields(env["GOSUMDB"])
+	if len(fields) == 0 {
+		fields = []string{"sum.golang.org"}
+	}
+	if fields[0] == "off" {
+		db.disabled = true
+		return db, nil
+	}
+	db.name = strings.SplitN(fields[0], "+", 2)[0]
+	db.url = "https://" + db.name
+	if len(fields) > 1 {
+		db.url = strings.TrimSuffix(fields[1], "/")
+	}
+	return db, nil
+}
+
+// excluded tells whether the module matches a GONOSUMDB pattern
+func (db *sumDB) excluded(modulePath string) bool {
+	return matchesPrefix(db.noSumDB, modulePath)
+}
+
+// matchesPrefix tells whether a glob of the GOPRIVATE-like list of patterns
+// matches a leading part of the path, as for the go command
+func matchesPrefix(patterns []string, modulePath string) bool {
+	for _, pattern := range patterns {
+		n := strings.Count(pattern, "/") + 1
+		elems := strings.SplitN(modulePath, "/", n+1)
+		if len(elems) < n {
+			continue
+		}
+		if ok, _ := path.Match(pattern, strings.Join(elems[:n], "/")); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// status looks up the target version of the module
+func (db *sumDB) status(m Module) string {
+	if db.disabled {
+		return checksumDisabled
+	}
+	if db.excluded(m.name) {
+		return checksumExcluded
+	}
+	// A version stays in the checksum database once added
+	key := db.name + " " + m.name + "@" + m.toVersion
+	var status string
+	if metadata.load("checksums", key, never, &status) && status == checksumVerified {
+		return status
+	}
+	if metadata.load("checksums", key, statusTTL, &status) {
+		return status
+	}
+	resp, err := db.client.Get(fmt.Sprintf("%s/lookup/%s@%s", db.url, escapePath(m.name), m.toVersion))
+	if err != nil {
+		return checksumUnknown
+	}
+	resp.Body.Close()
+	switch resp.StatusCode {
+	case http.StatusOK:
+		status = checksumVerified
+	case http.StatusNotFound, http.StatusGone:
+		status = checksumMissing
+	default:
+		return checksumUnknown
+	}
+	metadata.store("checksums", key, status)
+	return status
+}
+
+// escapePath escapes upper case letters as the module proxy protocol does
+func escapePath(p string) string {
+	var b strings.Builder
+	for _, r := range p {
+		if unicode.IsUpper(r) {
+			b.WriteByte('!')
+			r = unicode.ToLower(r)
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
+
+// checkChecksums fills the checksum status of the modules, warning about the
+// ones escaping verification
+func checkChecksums(modules []Module) error {
+	db, err := newSumDB()
+	if err != nil {
+		return err
+	}
+	if db.disabled {
+		progress("Warning: checksum verification is disabled (GOSUMDB=off)")
+	}
+	unknown := 0
+	for i, x := range modules {
+		modules[i].checksum = db.status(x)
+		switch modules[i].checksum {
+		case checksumUnknown:
+			unknown++
+		case checksumExcluded:
+			progress("Warning: %s is excluded from checksum verification (GONOSUMDB/GOPRIVATE)", x.name)
+		case checksumMissing:
+			progress("Warning: %s %s is not in %s", x.name, x.toVersion, db.name)
+		}
+	}
+	if unknown > 0 {
+		progress("Warning: could not look up %d versions in %s", unknown, db.name)
+	}
+	return nil
+}
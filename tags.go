@@ -0,0 +1,156 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"go/parser"
+	"go/token"
+	"io/ioutil"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// buildTags are the build tags given with --tags, passed to the go commands
+// in GOFLAGS so that they see the same packages as the builds
+var buildTags string
+
+// tagImport is a package imported by files which the build constraints
+// exclude from the current build
+type tagImport struct {
+	path string
+	// constraint is the constraint excluding the file, as windows or
+	// linux && arm64
+	constraint string
+	test       bool
+}
+
+// platformNames returns the known GOOS and GOARCH values
+func platformNames() (map[string]bool, error) {
+	list, err := distList()
+	if err != nil {
+		return nil, err
+	}
+	known := map[string]bool{}
+	for _, line := range list {
+		for _, p := range strings.Split(line, "/") {
+			known[p] = true
+		}
+	}
+	return known, nil
+}
+
+// fileConstraint returns the build constraint of a file, from its go:build
+// or +build lines, else from the GOOS and GOARCH in its name
+func fileConstraint(file string, data []byte, known map[string]bool) string {
+	plusBuild := []string{}
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if strings.HasPrefix(line, "package ") {
+			break
+		}
+		if strings.HasPrefix(line, "//go:build ") {
+			return strings.TrimSpace(strings.TrimPrefix(line, "//go:build "))
+		}
+		if strings.HasPrefix(line, "// +build ") {
+			plusBuild = append(plusBuild, strings.TrimSpace(strings.TrimPrefix(line, "// +build ")))
+		}
+	}
+	if len(plusBuild) > 0 {
+		return strings.Join(plusBuild, " ")
+	}
+	name := strings.TrimSuffix(strings.TrimSuffix(filepath.Base(file), ".go"), "_test")
+	parts := strings.Split(name, "_")
+	suffix := []string{}
+	for i := len(parts) - 1; i > 0 && len(suffix) < 2 && known[parts[i]]; i-- {
+		suffix = append([]string{parts[i]}, suffix...)
+	}
+	if len(suffix) == 0 {
+		// Files using cgo are excluded when it is disabled
+		return "cgo"
+	}
+	return strings.Join(suffix, " && ")
+}
+
+// taggedImports returns the packages imported by the files of the packages
+// of dir which the build constraints exclude
+func taggedImports(dir string) ([]tagImport, error) {
+	args := []string{"list", "-mod=mod", "-e", "-f", "{{.Dir}}{{range .IgnoredGoFiles}}\t{{.}}{{end}}", "./..."}
+	out, err := goCommandIn(dir, args...).Output()
+	if err != nil {
+		return nil, newGoError(args, err)
+	}
+	var known map[string]bool
+	imports := []tagImport{}
+	scanner := bufio.NewScanner(bytes.NewReader(out))
+	for scanner.Scan() {
+		fields := strings.Split(scanner.Text(), "\t")
+		for _, name := range fields[1:] {
+			if known == nil {
+				if known, err = platformNames(); err != nil {
+					return nil, err
+				}
+			}
+			file := filepath.Join(fields[0], name)
+			data, err := ioutil.ReadFile(file)
+			if err != nil {
+				return nil, err
+			}
+			f, err := parser.ParseFile(token.NewFileSet(), file, data, parser.ImportsOnly)
+			if err != nil {
+				// Files which don't parse are left to the go command
+				continue
+			}
+			constraint := fileConstraint(file, data, known)
+			for _, spec := range f.Imports {
+				path, err := strconv.Unquote(spec.Path.Value)
+				if err != nil || path == "C" {
+					continue
+				}
+				imports = append(imports, tagImport{path, constraint, strings.HasSuffix(name, "_test.go")})
+			}
+		}
+	}
+	return imports, scanner.Err()
+}
+
+// taggedModules returns the modules providing the packages imported by the
+// files which the build constraints exclude, with their constraints, for the
+// code and for the tests
+func taggedModules(dir string) (code, tests map[string]string, err error) {
+	code, tests = map[string]string{}, map[string]string{}
+	imports, err := taggedImports(dir)
+	if err != nil || len(imports) == 0 {
+		return code, tests, err
+	}
+	args := []string{"list", "-mod=mod", "-m", "-f", "{{.Path}}", "all"}
+	out, err := goCommandIn(dir, args...).Output()
+	if err != nil {
+		return nil, nil, newGoError(args, err)
+	}
+	paths := strings.Fields(string(out))
+	for _, i := range imports {
+		// The module with the longest path prefix provides the package
+		module := ""
+		for _, p := range paths {
+			if (i.path == p || strings.HasPrefix(i.path, p+"/")) && len(p) > len(module) {
+				module = p
+			}
+		}
+		if module == "" {
+			continue
+		}
+		found := code
+		if i.test {
+			found = tests
+		}
+		switch {
+		case found[module] == "":
+			found[module] = i.constraint
+		case !strings.Contains(", "+found[module]+", ", ", "+i.constraint+", "):
+			found[module] += ", " + i.constraint
+		}
+	}
+	return code, tests, nil
+}
@@ -0,0 +1,17 @@
+//go:build !windows
+// +build !windows
+
+package main
+
+import (
+	"os"
+
+	"golang.org/x/crypto/ssh/terminal"
+)
+
+func consoleWidth() (int, error) {
+	width, _, err := terminal.GetSize(int(os.Stdout.Fd()))
+	return width, err
+}
+
+func enableVirtualTerminal() {}
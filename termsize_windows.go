@@ -0,0 +1,38 @@
+package main
+
+import (
+	"os"
+
+	"golang.org/x/sys/windows"
+)
+
+// consoleWidth returns the width of the visible window, rather than of the
+// screen buffer which is wider in legacy consoles. Stdout isn't the console
+// when redirected or under some shells, so the console is opened directly.
+func consoleWidth() (int, error) {
+	var info windows.ConsoleScreenBufferInfo
+	err := windows.GetConsoleScreenBufferInfo(windows.Handle(os.Stdout.Fd()), &info)
+	if err != nil {
+		name, _ := windows.UTF16PtrFromString("CONOUT$")
+		h, cerr := windows.CreateFile(name, windows.GENERIC_READ|windows.GENERIC_WRITE, windows.FILE_SHARE_WRITE, nil, windows.OPEN_EXISTING, 0, 0)
+		if cerr != nil {
+			return 0, err
+		}
+		defer windows.CloseHandle(h)
+		if err := windows.GetConsoleScreenBufferInfo(h, &info); err != nil {
+			return 0, err
+		}
+	}
+	return int(info.Window.Right - info.Window.Left + 1), nil
+}
+
+// enableVirtualTerminal lets the console interpret the escape sequences
+// itself, as ConPTY does, instead of relying on their translation to legacy
+// console calls. Older consoles refuse it and keep the translation.
+func enableVirtualTerminal() {
+	h := windows.Handle(os.Stdout.Fd())
+	var mode uint32
+	if windows.GetConsoleMode(h, &mode) == nil {
+		_ = windows.SetConsoleMode(h, mode|windows.ENABLE_VIRTUAL_TERMINAL_PROCESSING)
+	}
+}
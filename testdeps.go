@@ -0,0 +1,128 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"strings"
+)
+
+const (
+	testDepsInclude = "include"
+	testDepsOnly    = "only"
+	testDepsExclude = "exclude"
+)
+
+// testDepsColumn is the column marking the modules only needed by tests, or
+// by the code of some platforms or build tags
+const testDepsColumn = "deps"
+
+// importedModules returns the modules providing the packages the packages
+// of dir depend on, including the dependencies of their tests when tests, on
+// the current platform and the target ones
+func importedModules(dir string, tests bool) (map[string]bool, error) {
+	args := []string{"list", "-mod=mod", "-deps", "-f", "{{with .Module}}{{.Path}}{{end}}"}
+	if tests {
+		args = append(args, "-test")
+	}
+	args = append(args, "./...")
+	modules := map[string]bool{}
+	for _, p := range append([]string{""}, targetPlatforms...) {
+		err := withPlatform(p, func() error {
+			out, err := goCommandIn(dir, args...).Output()
+			if err != nil {
+				return newGoError(args, err)
+			}
+			scanner := bufio.NewScanner(bytes.NewReader(out))
+			for scanner.Scan() {
+				if path := strings.TrimSpace(scanner.Text()); path != "" {
+					modules[path] = true
+				}
+			}
+			return scanner.Err()
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+	return modules, nil
+}
+
+// testOnlyModules returns the modules of dir whose packages are imported by
+// tests, but not by the code they test, and the modules only imported by
+// code which the build constraints exclude from the current build, with the
+// constraints
+func testOnlyModules(dir string) (map[string]bool, map[string]string, error) {
+	runtime, err := importedModules(dir, false)
+	if err != nil {
+		return nil, nil, err
+	}
+	all, err := importedModules(dir, true)
+	if err != nil {
+		return nil, nil, err
+	}
+	code, tests, err := taggedModules(dir)
+	if err != nil {
+		return nil, nil, err
+	}
+	tagOnly := map[string]string{}
+	for path, constraint := range code {
+		if !runtime[path] {
+			tagOnly[path] = constraint
+		}
+	}
+	testOnly := map[string]bool{}
+	for path := range all {
+		if !runtime[path] && tagOnly[path] == "" {
+			testOnly[path] = true
+		}
+	}
+	for path := range tests {
+		if !runtime[path] && tagOnly[path] == "" {
+			testOnly[path] = true
+		}
+	}
+	return testOnly, tagOnly, nil
+}
+
+// filterTestDeps marks the modules only needed by tests in a column, and
+// keeps the ones matching the mode: include keeps every module, only the
+// test-only ones, and exclude the others
+func filterTestDeps(modules []Module, mode string) ([]Module, error) {
+	switch mode {
+	case testDepsInclude, testDepsOnly, testDepsExclude:
+	default:
+		return nil, fmt.Errorf("--test-deps: unknown mode %q, expected include, only or exclude", mode)
+	}
+	byDir := map[string]map[string]bool{}
+	tagsByDir := map[string]map[string]string{}
+	kept := []Module{}
+	for _, x := range modules {
+		testOnly, ok := byDir[x.dir]
+		if !ok {
+			var err error
+			if testOnly, tagsByDir[x.dir], err = testOnlyModules(x.dir); err != nil {
+				return nil, err
+			}
+			byDir[x.dir] = testOnly
+		}
+		if testOnly[x.name] || tagsByDir[x.dir][x.name] != "" {
+			if x.columns == nil {
+				x.columns = map[string]string{}
+			}
+			x.columns[testDepsColumn] = "test only"
+			if constraint := tagsByDir[x.dir][x.name]; constraint != "" {
+				x.columns[testDepsColumn] = constraint + " only"
+			}
+		}
+		switch {
+		case mode == testDepsOnly && !testOnly[x.name]:
+			explanations.hide(x.dir, x.name, "used by the code, hidden by --test-deps only")
+		case mode == testDepsExclude && testOnly[x.name]:
+			explanations.hide(x.dir, x.name, "only used by tests, hidden by --test-deps exclude")
+		default:
+			kept = append(kept, x)
+		}
+	}
+	return kept, nil
+}
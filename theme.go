@@ -0,0 +1,126 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/fatih/color"
+)
+
+// theme colors the module names by severity, and the changed parts of the
+// target version
+type theme struct {
+	severities map[Severity][]color.Attribute
+	target     []color.Attribute
+}
+
+var themes = map[string]theme{
+	"default": {
+		severities: map[Severity][]color.Attribute{
+			SeverityMajor:      {color.FgMagenta},
+			SeverityMinor:      {color.FgYellow},
+			SeverityPatch:      {color.FgGreen},
+			SeverityPrerelease: {color.FgRed},
+			SeverityMetadata:   {color.FgWhite},
+			SeverityNonSemver:  {color.FgCyan},
+		},
+		target: []color.Attribute{color.FgGreen},
+	},
+	// colorblind avoids the red/green pair, relying on blue/orange contrasts
+	// and on bold for the most significant updates
+	"colorblind": {
+		severities: map[Severity][]color.Attribute{
+			SeverityMajor:      {color.FgHiYellow, color.Bold},
+			SeverityMinor:      {color.FgYellow},
+			SeverityPatch:      {color.FgBlue},
+			SeverityPrerelease: {color.FgMagenta},
+			SeverityMetadata:   {color.FgWhite},
+			SeverityNonSemver:  {color.FgCyan},
+		},
+		target: []color.Attribute{color.FgCyan, color.Bold},
+	},
+	"monochrome": {
+		severities: map[Severity][]color.Attribute{
+			SeverityMajor:      {color.Bold, color.Underline},
+			SeverityMinor:      {color.Bold},
+			SeverityPatch:      {color.Reset},
+			SeverityPrerelease: {color.Italic},
+			SeverityMetadata:   {color.Faint},
+			SeverityNonSemver:  {color.Underline},
+		},
+		target: []color.Attribute{color.Bold},
+	},
+}
+
+var colorNames = map[string]color.Attribute{
+	"black":     color.FgBlack,
+	"red":       color.FgRed,
+	"green":     color.FgGreen,
+	"yellow":    color.FgYellow,
+	"blue":      color.FgBlue,
+	"magenta":   color.FgMagenta,
+	"cyan":      color.FgCyan,
+	"white":     color.FgWhite,
+	"bold":      color.Bold,
+	"faint":     color.Faint,
+	"italic":    color.Italic,
+	"underline": color.Underline,
+}
+
+// parseColor parses space separated color names, as in "bold hi-red"
+func parseColor(s string) ([]color.Attribute, error) {
+	attrs := []color.Attribute{}
+	for _, name := range strings.Fields(s) {
+		hi := strings.HasPrefix(name, "hi-")
+		a, ok := colorNames[strings.TrimPrefix(name, "hi-")]
+		if !ok || hi && a > color.FgWhite {
+			return nil, fmt.Errorf("unknown color %q", name)
+		}
+		if hi {
+			a += color.FgHiBlack - color.FgBlack
+		}
+		attrs = append(attrs, a)
+	}
+	return attrs, nil
+}
+
+// applyTheme sets the colors of the list from a preset, overridden by
+// severity, or "target" for the changed parts of the target version
+func applyTheme(name string, colors map[string]string) error {
+	if name == "" {
+		name = "default"
+	}
+	preset, ok := themes[name]
+	if !ok {
+		names := []string{}
+		for n := range themes {
+			names = append(names, n)
+		}
+		sort.Strings(names)
+		return fmt.Errorf("unknown theme %q, expected one of %s", name, strings.Join(names, ", "))
+	}
+	severities := map[Severity][]color.Attribute{}
+	for s, attrs := range preset.severities {
+		severities[s] = attrs
+	}
+	target := preset.target
+	for key, value := range colors {
+		attrs, err := parseColor(value)
+		if err != nil {
+			return fmt.Errorf("colors of %s: %v", key, err)
+		}
+		if key == "target" {
+			target = attrs
+			continue
+		}
+		s, err := parseSeverity(key)
+		if err != nil {
+			return fmt.Errorf("colors: %v", err)
+		}
+		severities[s] = attrs
+	}
+	severityColors = severities
+	targetColor = target
+	return nil
+}
@@ -0,0 +1,230 @@
+package main
+
+import (
+	"bufio"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"runtime"
+	"strings"
+
+	"github.com/Masterminds/semver/v3"
+)
+
+// goBinary is the go command run by the tool, the one of the version pinned
+// by a version manager when it is installed
+var goBinary = "go"
+
+// toolchainPin is a Go version pinned by asdf, mise or a Nix flake
+type toolchainPin struct {
+	version string
+	file    string
+}
+
+var (
+	toolVersionsLine = regexp.MustCompile(`^\s*(?:go|golang)\s+(\S+)`)
+	miseToolLine     = regexp.MustCompile(`^\s*"?(?:go|golang)"?\s*=\s*["']([^"']+)["']`)
+	flakeGo          = regexp.MustCompile(`\bgo_1_(\d+)\b`)
+	goVersionOutput  = regexp.MustCompile(`go version go(\S+)`)
+)
+
+// pinIn returns the Go version pinned by the files of dir
+func pinIn(dir string) *toolchainPin {
+	for _, name := range []string{".tool-versions", "mise.toml", ".mise.toml", "flake.nix"} {
+		file := filepath.Join(dir, name)
+		f, err := os.Open(file)
+		if err != nil {
+			continue
+		}
+		version := ""
+		section := ""
+		scanner := bufio.NewScanner(f)
+		for scanner.Scan() && version == "" {
+			line := scanner.Text()
+			switch name {
+			case ".tool-versions":
+				if m := toolVersionsLine.FindStringSubmatch(line); m != nil {
+					version = m[1]
+				}
+			case "flake.nix":
+				if m := flakeGo.FindStringSubmatch(line); m != nil {
+					version = "1." + m[1]
+				}
+			default:
+				if trimmed := strings.TrimSpace(line); strings.HasPrefix(trimmed, "[") {
+					section = trimmed
+				} else if m := miseToolLine.FindStringSubmatch(line); m != nil && section == "[tools]" {
+					version = m[1]
+				}
+			}
+		}
+		f.Close()
+		// Without a version, the one installed on the system is used
+		if version != "" && version != "system" && version != "latest" {
+			return &toolchainPin{version: strings.TrimPrefix(version, "go"), file: file}
+		}
+	}
+	return nil
+}
+
+// findPin looks for the nearest pinned Go version, from the current
+// directory up to the root of the repository
+func findPin() *toolchainPin {
+	dir, err := os.Getwd()
+	if err != nil {
+		return nil
+	}
+	for {
+		if pin := pinIn(dir); pin != nil {
+			return pin
+		}
+		parent := filepath.Dir(dir)
+		if isTopLevel(dir) || parent == dir {
+			return nil
+		}
+		dir = parent
+	}
+}
+
+// goVersionOf returns the version of a go binary, as 1.22.3
+func goVersionOf(binary string) string {
+	out, err := goCmd{exec.Command(binary, "version")}.Output()
+	if err != nil {
+		return ""
+	}
+	if m := goVersionOutput.FindStringSubmatch(string(out)); m != nil {
+		return m[1]
+	}
+	return ""
+}
+
+// matches tells whether the version satisfies the pin, 1.22 matching any
+// patch release
+func (p *toolchainPin) matches(version string) bool {
+	return version == p.version || strings.HasPrefix(version, p.version+".")
+}
+
+// installedGo returns the go binaries of the version installed by asdf,
+// mise or golang.org/dl
+func installedGo(version string) []string {
+	home, _ := os.UserHomeDir()
+	asdf := os.Getenv("ASDF_DATA_DIR")
+	if asdf == "" {
+		asdf = filepath.Join(home, ".asdf")
+	}
+	mise := os.Getenv("MISE_DATA_DIR")
+	if mise == "" {
+		mise = filepath.Join(home, ".local", "share", "mise")
+	}
+	exe := "go"
+	if runtime.GOOS == "windows" {
+		exe += ".exe"
+	}
+	return []string{
+		filepath.Join(asdf, "installs", "golang", version, "go", "bin", exe),
+		filepath.Join(mise, "installs", "go", version, "bin", exe),
+		filepath.Join(home, "sdk", "go"+version, "bin", exe),
+	}
+}
+
+// useToolchain switches to the go binary of the pinned version when the
+// one in the PATH differs, and warns when it isn't installed
+func useToolchain() {
+	pin := findPin()
+	if pin == nil {
+		return
+	}
+	current := goVersionOf(goBinary)
+	if current == "" || pin.matches(current) {
+		return
+	}
+	for _, binary := range installedGo(pin.version) {
+		if _, err := os.Stat(binary); err != nil {
+			continue
+		}
+		if v := goVersionOf(binary); v != "" && pin.matches(v) {
+			goBinary = binary
+			notice("Using go %s from %s, pinned in %s", v, binary, pin.file)
+			return
+		}
+	}
+	if filepath.Base(pin.file) == "flake.nix" && os.Getenv("IN_NIX_SHELL") == "" {
+		notice("%s provides go %s but go %s is in use, run go-mod-upgrade in nix develop to resolve the upgrades with the same toolchain", pin.file, pin.version, current)
+		return
+	}
+	notice("Go %s is pinned in %s but go %s is in use and %s isn't installed, the upgrades may resolve differently than in CI", pin.version, pin.file, current, pin.version)
+}
+
+// checkToolchain reports whether the go command matches the pinned version
+func checkToolchain() *diagnosis {
+	pin := findPin()
+	if pin == nil {
+		return nil
+	}
+	current := goVersionOf(goBinary)
+	if pin.matches(current) {
+		return &diagnosis{"toolchain", checkOK, "go " + current + " matches " + pin.file, ""}
+	}
+	return &diagnosis{"toolchain", checkWarn, "go " + current + " in use, " + pin.version + " pinned in " + pin.file,
+		"install the pinned version, e.g. `asdf install golang " + pin.version + "` or `mise install go@" + pin.version + "`"}
+}
+
+// goReleaseVersion splits a Go version, as go1.22rc1, into its release and
+// prerelease parts
+var goReleaseVersion = regexp.MustCompile(`^(?:go)?(\d+(?:\.\d+){0,2})((?:rc|beta)\d+)?$`)
+
+// parseGoVersion parses a Go version of a go or toolchain directive, nil
+// when it isn't one
+func parseGoVersion(v string) *semver.Version {
+	m := goReleaseVersion.FindStringSubmatch(v)
+	if m == nil {
+		return nil
+	}
+	s := m[1]
+	if m[2] != "" {
+		s += "-" + m[2]
+	}
+	version, err := semver.NewVersion(s)
+	if err != nil {
+		return nil
+	}
+	return version
+}
+
+// memberGo holds the go binaries run in the workspace modules requiring a
+// newer Go than the go command in use, by directory
+var memberGo = map[string]string{}
+
+// selectMemberToolchains picks for each workspace module requiring a newer
+// Go than the go command in use, by its go or toolchain directive, an
+// installed go binary satisfying it. Without one, the go command switches
+// toolchains itself unless GOTOOLCHAIN=local.
+func selectMemberToolchains(directives map[string]goDirectives) {
+	current := parseGoVersion(goVersionOf(goBinary))
+	if current == nil {
+		return
+	}
+	for dir, d := range directives {
+		required := d.required()
+		if required == "" {
+			continue
+		}
+		version := parseGoVersion(required)
+		if version == nil {
+			progress("Ignoring the go version %s of %s, which can't be parsed", required, dir)
+			continue
+		}
+		if !current.LessThan(version) {
+			continue
+		}
+		for _, binary := range installedGo(strings.TrimPrefix(required, "go")) {
+			if _, err := os.Stat(binary); err != nil {
+				continue
+			}
+			memberGo[dir] = binary
+			progress("Using %s in %s, which requires go %s", binary, dir, strings.TrimPrefix(required, "go"))
+			break
+		}
+	}
+}
@@ -0,0 +1,119 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strings"
+	"text/template"
+)
+
+// failureClasses classify the output of a failed update, by the first
+// class with a matching pattern
+var failureClasses = []struct {
+	class    string
+	patterns []string
+}{
+	{"checksum", []string{"SECURITY ERROR", "checksum mismatch", "verifying module", "missing go.sum entry"}},
+	{"auth", []string{"terminal prompts disabled", "could not read Username", "Permission denied", "401 Unauthorized", "403 Forbidden", "404 Not Found", "410 Gone", "authentication required"}},
+	{"network", []string{"dial tcp", "no such host", "i/o timeout", "connection refused", "connection reset", "TLS handshake timeout", "reading file://"}},
+	{"conflict", []string{"but go.mod requires", "conflicting requirements", "cannot upgrade", "is not a known dependency", "requires go >=", "downgraded", "retracted"}},
+	{"build", []string{"step check", "undefined:", "cannot use", "too many arguments", "not enough arguments"}},
+}
+
+// classifyFailure returns the class of a failed update from its output:
+// checksum, auth, network, conflict, build, or unknown
+func classifyFailure(output string) string {
+	for _, c := range failureClasses {
+		for _, p := range c.patterns {
+			if strings.Contains(output, p) {
+				return c.class
+			}
+		}
+	}
+	return "unknown"
+}
+
+// failure is a failed update with the output of the go command
+type failure struct {
+	Path   string `json:"path"`
+	From   string `json:"from"`
+	To     string `json:"to"`
+	Dir    string `json:"dir,omitempty"`
+	Class  string `json:"class"`
+	Output string `json:"output"`
+}
+
+// failureReport collects the failed updates, written to a markdown file, or
+// a JSON one for .json files, instead of the console
+type failureReport struct {
+	file     string
+	failures []failure
+}
+
+// triage collects the failed updates when --failures is given
+var triage *failureReport
+
+// add records the failed update, returning its class
+func (r *failureReport) add(m Module, err error) string {
+	output := strings.TrimSpace(err.Error())
+	f := failure{Path: m.name, From: m.fromVersion, To: m.toVersion, Dir: m.dir, Class: classifyFailure(output), Output: output}
+	r.failures = append(r.failures, f)
+	return f.Class
+}
+
+var markdownFailures = `# Failed updates
+{{range .}}
+## {{.Path}} {{.From}} → {{.To}}{{if .Dir}} in {{.Dir}}{{end}}
+
+Class: {{.Class}}
+
+` + "```" + `
+{{.Output}}
+` + "```" + `
+{{end}}`
+
+// write writes the report and summarizes the failures by class
+func (r *failureReport) write() error {
+	if r == nil || len(r.failures) == 0 {
+		return nil
+	}
+	sort.SliceStable(r.failures, func(i, j int) bool {
+		return r.failures[i].Class < r.failures[j].Class
+	})
+	var buf bytes.Buffer
+	if strings.ToLower(filepath.Ext(r.file)) == ".json" {
+		enc := json.NewEncoder(&buf)
+		enc.SetIndent("", "  ")
+		if err := enc.Encode(struct {
+			SchemaVersion string    `json:"schemaVersion"`
+			Failures      []failure `json:"failures"`
+		}{schemaVersion, r.failures}); err != nil {
+			return err
+		}
+	} else {
+		t := template.Must(template.New("failures").Parse(markdownFailures))
+		if err := t.Execute(&buf, r.failures); err != nil {
+			return err
+		}
+	}
+	if err := writeFileAtomic(r.file, buf.Bytes()); err != nil {
+		return err
+	}
+	counts := map[string]int{}
+	classes := []string{}
+	for _, f := range r.failures {
+		if counts[f.Class] == 0 {
+			classes = append(classes, f.Class)
+		}
+		counts[f.Class]++
+	}
+	summary := []string{}
+	for _, c := range classes {
+		summary = append(summary, fmt.Sprintf("%d %s", counts[c], c))
+	}
+	fmt.Println(tr("%d update(s) failed (%s), the go output is in %s", len(r.failures), strings.Join(summary, ", "), r.file))
+	return nil
+}
@@ -0,0 +1,80 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+	"text/template"
+
+	"github.com/kballard/go-shellquote"
+)
+
+// defaultUpdateCommand is the update command template used unless configured
+const defaultUpdateCommand = "go get {{.Args}} {{.Path}}@{{.To}}"
+
+// updateCommand is the command template applying an update, e.g. to route
+// go get through Bazel or a wrapper script
+var updateCommand = defaultUpdateCommand
+
+// getArgs are the extra arguments of go get, such as -t or -x
+var getArgs []string
+
+// updateTarget is given to the update command template
+type updateTarget struct {
+	Path string
+	To   string
+	Dir  string
+	// Args are the extra arguments of go get, quoted for the shell
+	Args string
+}
+
+// goGet updates the module path to version in the module directory dir, to
+// the latest version when empty, returning the command output as error on
+// failure
+func goGet(dir, path, version string) error {
+	if version == "" {
+		version = "upgrade"
+	}
+	args, err := updateArgs(updateTarget{Path: path, To: version, Dir: dir, Args: shellquote.Join(getArgs...)})
+	if err != nil {
+		return err
+	}
+	var cmd goCmd
+	if args[0] == "go" {
+		cmd = goCommandIn(dir, args[1:]...)
+	} else {
+		cmd = goCmd{exec.Command(args[0], args[1:]...)}
+		cmd.Dir = dir
+		if len(goEnv) > 0 {
+			cmd.Env = append(os.Environ(), goEnv...)
+		}
+	}
+	out, err := cmd.CombinedOutput()
+	if err != nil {
+		return fmt.Errorf("%v: %s", err, strings.TrimSpace(string(out)))
+	}
+	return nil
+}
+
+// updateArgs renders the update command template and splits it as a shell
+// would
+func updateArgs(target updateTarget) ([]string, error) {
+	t, err := template.New("update").Option("missingkey=error").Parse(updateCommand)
+	if err != nil {
+		return nil, fmt.Errorf("update command: %v", err)
+	}
+	var buf bytes.Buffer
+	if err := t.Execute(&buf, target); err != nil {
+		return nil, fmt.Errorf("update command: %v", err)
+	}
+	args, err := shellquote.Split(buf.String())
+	if err != nil {
+		return nil, fmt.Errorf("update command: %v", err)
+	}
+	if len(args) == 0 {
+		return nil, fmt.Errorf("update command %q is empty", updateCommand)
+	}
+	return args, nil
+}
@@ -0,0 +1,20 @@
+package main
+
+import (
+	"log"
+	"os"
+)
+
+// verboseLog writes the verbose diagnostics to stderr with a timestamp, so
+// that they never mix with the output of the tool on stdout
+var verboseLog = log.New(os.Stderr, "", log.LstdFlags)
+
+// verbosef logs a verbose diagnostic, prefixed with the module path it is
+// about when there is one
+func verbosef(module, format string, args ...interface{}) {
+	message := tr(format, args...)
+	if module != "" {
+		message = "[" + module + "] " + message
+	}
+	verboseLog.Println(message)
+}
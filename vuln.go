@@ -0,0 +1,302 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Masterminds/semver/v3"
+)
+
+// defaultVulnDB is the Go vulnerability database, which can be replaced with
+// the GOVULNDB environment variable as for govulncheck
+const defaultVulnDB = "https://vuln.go.dev"
+
+type vulnDB struct {
+	url    string
+	client *http.Client
+
+	// The index is fetched once, even by concurrent lookups
+	indexOnce sync.Once
+	index     map[string][]string
+	indexErr  error
+}
+
+// osvEntry is the subset of an OSV entry of the database we rely on
+type osvEntry struct {
+	ID       string   `json:"id"`
+	Summary  string   `json:"summary"`
+	Aliases  []string `json:"aliases"`
+	Affected []struct {
+		Package struct {
+			Name string `json:"name"`
+		} `json:"package"`
+		Ranges []struct {
+			Type   string `json:"type"`
+			Events []struct {
+				Introduced string `json:"introduced"`
+				Fixed      string `json:"fixed"`
+			} `json:"events"`
+		} `json:"ranges"`
+	} `json:"affected"`
+}
+
+type vulnIndexEntry struct {
+	Path  string `json:"path"`
+	Vulns []struct {
+		ID string `json:"id"`
+	} `json:"vulns"`
+}
+
+// vulnerability is a known vulnerability affecting the version of a module
+type vulnerability struct {
+	ID      string
+	Summary string
+	// Fixed is the first version fixing the vulnerability, empty when there
+	// is no fix yet
+	Fixed string
+}
+
+func newVulnDB() *vulnDB {
+	u := os.Getenv("GOVULNDB")
+	if u == "" {
+		u = defaultVulnDB
+	}
+	return &vulnDB{
+		url:    strings.TrimSuffix(u, "/"),
+		client: &http.Client{Timeout: 30 * time.Second},
+	}
+}
+
+// get decodes the JSON document at path, from an http(s) or file URL
+func (db *vulnDB) get(path string, v interface{}) error {
+	var data []byte
+	if strings.HasPrefix(db.url, "file://") {
+		u, err := url.Parse(db.url + path)
+		if err != nil {
+			return err
+		}
+		data, err = ioutil.ReadFile(filepath.FromSlash(u.Path))
+		if err != nil {
+			return err
+		}
+	} else {
+		resp, err := db.client.Get(db.url + path)
+		if err != nil {
+			return err
+		}
+		defer resp.Body.Close()
+		if resp.StatusCode != http.StatusOK {
+			return fmt.Errorf("GET %s%s: %s", db.url, path, resp.Status)
+		}
+		data, err = ioutil.ReadAll(resp.Body)
+		if err != nil {
+			return err
+		}
+	}
+	return json.Unmarshal(data, v)
+}
+
+// ids returns the IDs of the vulnerabilities by module path
+func (db *vulnDB) ids() (map[string][]string, error) {
+	db.indexOnce.Do(func() {
+		var index []vulnIndexEntry
+		if err := db.get("/index/modules.json", &index); err != nil {
+			db.indexErr = fmt.Errorf("vulnerability database: %v", err)
+			return
+		}
+		db.index = map[string][]string{}
+		for _, e := range index {
+			for _, v := range e.Vulns {
+				db.index[e.Path] = append(db.index[e.Path], v.ID)
+			}
+		}
+	})
+	return db.index, db.indexErr
+}
+
+// vulnerabilities returns the known vulnerabilities of the modules, by path
+func (db *vulnDB) vulnerabilities(modules []goModule) (map[string][]vulnerability, error) {
+	ids, err := db.ids()
+	if err != nil {
+		return nil, err
+	}
+	found := map[string][]vulnerability{}
+	for _, m := range modules {
+		for _, id := range ids[m.Path] {
+			var entry osvEntry
+			if err := db.get("/ID/"+id+".json", &entry); err != nil {
+				return nil, fmt.Errorf("vulnerability database: %v", err)
+			}
+			if affected, fixed := entry.affects(m.Path, m.Version); affected {
+				found[m.Path] = append(found[m.Path], vulnerability{ID: entry.ID, Summary: entry.Summary, Fixed: fixed})
+			}
+		}
+	}
+	return found, nil
+}
+
+// byVersion returns the known vulnerabilities of the modules by
+// path@version, fetching each entry once
+func (db *vulnDB) byVersion(modules []goModule) (map[string][]vulnerability, error) {
+	ids, err := db.ids()
+	if err != nil {
+		return nil, err
+	}
+	entries := map[string]*osvEntry{}
+	found := map[string][]vulnerability{}
+	for _, m := range modules {
+		for _, id := range ids[m.Path] {
+			entry, ok := entries[id]
+			if !ok {
+				entry = &osvEntry{}
+				if err := db.get("/ID/"+id+".json", entry); err != nil {
+					return nil, fmt.Errorf("vulnerability database: %v", err)
+				}
+				entries[id] = entry
+			}
+			if affected, fixed := entry.affects(m.Path, m.Version); affected {
+				key := m.Path + "@" + m.Version
+				found[key] = append(found[key], vulnerability{ID: entry.ID, Summary: entry.Summary, Fixed: fixed})
+			}
+		}
+	}
+	return found, nil
+}
+
+// fixedBy counts the vulnerabilities affecting the from version of the
+// module and not the to one
+func (db *vulnDB) fixedBy(path, from, to string) (int, error) {
+	found, err := db.vulnerabilities([]goModule{{Path: path, Version: from}})
+	if err != nil {
+		return 0, err
+	}
+	target, err := semver.NewVersion(to)
+	if err != nil {
+		return 0, err
+	}
+	n := 0
+	for _, v := range found[path] {
+		if fixed, err := semver.NewVersion(v.Fixed); err == nil && !target.LessThan(fixed) {
+			n++
+		}
+	}
+	return n, nil
+}
+
+// affects reports whether version of the module is affected by the entry,
+// along with the version fixing it
+func (e osvEntry) affects(path, version string) (bool, string) {
+	v, err := semver.NewVersion(version)
+	if err != nil {
+		return false, ""
+	}
+	for _, a := range e.Affected {
+		if a.Package.Name != path {
+			continue
+		}
+		for _, r := range a.Ranges {
+			if r.Type != "SEMVER" {
+				continue
+			}
+			// Events are sorted, each introduced one opening a range which
+			// the following fixed one closes
+			in := false
+			for _, ev := range r.Events {
+				if ev.Introduced == "0" {
+					// Zero stands for all versions, pseudo-versions included
+					in = true
+				} else if ev.Introduced != "" {
+					introduced, err := semver.NewVersion(ev.Introduced)
+					in = err == nil && !v.LessThan(introduced)
+				}
+				if ev.Fixed != "" && in {
+					fixed, err := semver.NewVersion(ev.Fixed)
+					if err == nil && v.LessThan(fixed) {
+						return true, "v" + fixed.String()
+					}
+					in = false
+				}
+			}
+			if in {
+				return true, ""
+			}
+		}
+	}
+	return false, ""
+}
+
+// securityUpdates returns the modules, direct or indirect, to update to the
+// version fixing all their known vulnerabilities. Vulnerabilities without a
+// fix are reported separately.
+func securityUpdates(debug bool) ([]Module, map[string][]vulnerability, error) {
+	list, err := goList("", debug, "list", "-mod=mod", "-json", "-m", "all")
+	if err != nil {
+		return nil, nil, err
+	}
+	deps := []goModule{}
+	for _, m := range list {
+		if !m.Main && m.Version != "" {
+			deps = append(deps, m)
+		}
+	}
+	vulns, err := newVulnDB().vulnerabilities(deps)
+	if err != nil {
+		return nil, nil, err
+	}
+	modules := []Module{}
+	unfixed := map[string][]vulnerability{}
+	for _, m := range deps {
+		target := ""
+		for _, v := range vulns[m.Path] {
+			if v.Fixed == "" {
+				unfixed[m.Path] = append(unfixed[m.Path], v)
+				continue
+			}
+			if target == "" || semver.MustParse(target).LessThan(semver.MustParse(v.Fixed)) {
+				target = v.Fixed
+			}
+		}
+		if target != "" {
+			x := newModule(m.Path, m.Version, target)
+			x.vulns = vulns[m.Path]
+			x.minimum = true
+			modules = append(modules, x)
+		}
+	}
+	return modules, unfixed, nil
+}
+
+// onlySecurity upgrades exactly the modules needed to clear the known
+// vulnerabilities, without asking
+func onlySecurity(debug bool, events *eventLog, failFast bool, allowed *allowlist) ([]Module, error) {
+	progress("Looking for vulnerable modules...")
+	modules, unfixed, err := securityUpdates(debug)
+	if err != nil {
+		return nil, err
+	}
+	for path, vulns := range unfixed {
+		for _, v := range vulns {
+			fmt.Printf("No fix available for %s in %s: %s\n", v.ID, path, v.Summary)
+		}
+	}
+	if len(modules) == 0 {
+		progress("No vulnerabilities to fix")
+		return nil, nil
+	}
+	for _, x := range modules {
+		ids := []string{}
+		for _, v := range x.vulns {
+			ids = append(ids, v.ID)
+		}
+		progress("%s %s fixes %s", x.name, x.toVersion, strings.Join(ids, ", "))
+	}
+	return apply(allowed.enforce(modules), events, failFast)
+}
@@ -0,0 +1,100 @@
+package main
+
+import (
+	"sort"
+	"strings"
+)
+
+// maxTreeLines bounds the requirement trees, which grow quickly in large
+// module graphs
+const maxTreeLines = 20
+
+// requirementGraph is the module graph of a main module, to explain what
+// pulls a dependency in
+type requirementGraph struct {
+	main       string
+	versions   map[string]string
+	requiredBy map[string][]string
+}
+
+// loadRequirements reads the module graph of the module in dir
+func loadRequirements(dir string, debug bool) (*requirementGraph, error) {
+	list, err := goList(dir, debug, "list", "-mod=mod", "-json", "-m", "all")
+	if err != nil {
+		return nil, err
+	}
+	r := &requirementGraph{versions: map[string]string{}}
+	for _, m := range list {
+		if m.Main {
+			r.main = m.Path
+		} else {
+			r.versions[m.Path] = m.Version
+		}
+	}
+	if r.requiredBy, err = moduleGraph(dir, r.versions); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// tree renders the requirement chains from the main module to the module
+// as a tree, each module at its selected version
+func (r *requirementGraph) tree(path string) []string {
+	// The modules on a chain are the ancestors of the module
+	onChain := map[string]bool{path: true}
+	queue := []string{path}
+	for len(queue) > 0 {
+		current := queue[0]
+		queue = queue[1:]
+		for _, parent := range r.requiredBy[current] {
+			if !onChain[parent] {
+				onChain[parent] = true
+				queue = append(queue, parent)
+			}
+		}
+	}
+	if !onChain["main"] {
+		return nil
+	}
+	children := map[string][]string{}
+	for child, parents := range r.requiredBy {
+		if !onChain[child] {
+			continue
+		}
+		for _, parent := range parents {
+			if onChain[parent] {
+				children[parent] = append(children[parent], child)
+			}
+		}
+	}
+	lines := []string{r.main}
+	var walk func(node, indent string, visiting map[string]bool)
+	walk = func(node, indent string, visiting map[string]bool) {
+		kids := children[node]
+		sort.Strings(kids)
+		for i, kid := range kids {
+			if visiting[kid] {
+				continue
+			}
+			if len(lines) == maxTreeLines {
+				lines = append(lines, indent+"…")
+				return
+			}
+			if len(lines) > maxTreeLines {
+				return
+			}
+			branch, next := "├─ ", "│  "
+			if i == len(kids)-1 {
+				branch, next = "└─ ", "   "
+			}
+			lines = append(lines, indent+branch+strings.TrimSpace(kid+" "+r.versions[kid]))
+			if kid != path {
+				visiting[kid] = true
+				walk(kid, indent+next, visiting)
+				delete(visiting, kid)
+			}
+		}
+	}
+	walk("main", "", map[string]bool{"main": true})
+	return lines
+}
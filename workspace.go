@@ -0,0 +1,178 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// workspaceRoot is the current directory in recursive mode
+var workspaceRoot string
+
+// workspaceDirs lists the module directories of the workspace: the modules
+// used by go.work, or else every go.mod found below the current directory.
+func workspaceDirs() ([]string, error) {
+	if _, err := os.Stat("go.work"); err == nil {
+		out, err := goCommand("work", "edit", "-json").Output()
+		if err != nil {
+			return nil, newGoError([]string{"work", "edit", "-json"}, err)
+		}
+		var work struct {
+			Use []struct {
+				DiskPath string
+			}
+		}
+		if err := json.Unmarshal(out, &work); err != nil {
+			return nil, err
+		}
+		dirs := []string{}
+		for _, u := range work.Use {
+			dirs = append(dirs, filepath.Clean(u.DiskPath))
+		}
+		return dirs, nil
+	}
+	dirs := []string{}
+	err := filepath.Walk(".", func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		name := info.Name()
+		if info.IsDir() && path != "." {
+			// Skipped by the go command too
+			if name == "vendor" || name == "testdata" || strings.HasPrefix(name, ".") || strings.HasPrefix(name, "_") {
+				return filepath.SkipDir
+			}
+		}
+		if !info.IsDir() && name == "go.mod" {
+			dirs = append(dirs, filepath.Dir(path))
+		}
+		return nil
+	})
+	sort.Strings(dirs)
+	return dirs, err
+}
+
+// enterWorkspace makes the current directory the root of the recursive
+// mode and returns its module directories, listed before the workspace is
+// turned off for the go commands run in each module
+func enterWorkspace() ([]string, error) {
+	root, err := filepath.Abs(".")
+	if err != nil {
+		return nil, err
+	}
+	workspaceRoot = root
+	dirs, err := workspaceDirs()
+	if err != nil {
+		return nil, err
+	}
+	// Each module is updated on its own, without the workspace
+	goEnv = append(goEnv, "GOWORK=off")
+	return dirs, nil
+}
+
+// discoverAll discovers the updates of every module directory, running at
+// most jobs go commands at once. The modules are merged in directory order.
+func discoverAll(dirs []string, jobs int, verbose, debug bool, events *eventLog) ([]Module, error) {
+	if jobs < 1 {
+		jobs = 1
+	}
+	events.emit(event{Event: "discovery_started"})
+	results := make([][]Module, len(dirs))
+	errs := make([]error, len(dirs))
+	sem := make(chan struct{}, jobs)
+	var wg sync.WaitGroup
+	for i, dir := range dirs {
+		wg.Add(1)
+		go func(i int, dir string) {
+			defer wg.Done()
+			sem <- struct{}{}
+			defer func() { <-sem }()
+			results[i], errs[i] = discoverIn(dir, verbose, debug, events)
+		}(i, dir)
+	}
+	wg.Wait()
+	modules := []Module{}
+	for i, dir := range dirs {
+		if errs[i] != nil {
+			return nil, fmt.Errorf("%s: %v", dir, errs[i])
+		}
+		modules = append(modules, results[i]...)
+	}
+	return modules, nil
+}
+
+// moduleDirsOf lists the distinct directories of the modules
+func moduleDirsOf(modules []Module) []string {
+	dirs := []string{}
+	seen := map[string]bool{}
+	for _, x := range modules {
+		if !seen[x.dir] {
+			seen[x.dir] = true
+			dirs = append(dirs, x.dir)
+		}
+	}
+	return dirs
+}
+
+// aggregate collapses the updates of a dependency to the same version in
+// several workspace modules into one row, updating all its members. The row
+// shows the oldest version in use.
+func aggregate(modules []Module) []Module {
+	rows := []Module{}
+	index := map[string]int{}
+	for _, x := range modules {
+		key := x.name + "@" + x.toVersion
+		i, ok := index[key]
+		if !ok {
+			index[key] = len(rows)
+			rows = append(rows, x)
+			continue
+		}
+		members := rows[i].members
+		if members == nil {
+			members = []Module{rows[i]}
+		}
+		members = append(members, x)
+		if x.from != nil && rows[i].from != nil && x.from.LessThan(rows[i].from) {
+			rows[i] = x
+		}
+		rows[i].members = members
+		rows[i].dir = fmt.Sprintf("%d modules", len(members))
+		rows[i].review = rows[i].review || x.review
+	}
+	return rows
+}
+
+// expand replaces the aggregated rows by their members
+func expand(modules []Module) []Module {
+	expanded := []Module{}
+	for _, x := range modules {
+		if x.members == nil {
+			expanded = append(expanded, x)
+		} else {
+			expanded = append(expanded, x.members...)
+		}
+	}
+	return expanded
+}
+
+// memberVersions tells which members of an aggregated row use which version
+func memberVersions(x Module) string {
+	versions := []string{}
+	dirs := map[string][]string{}
+	for _, m := range x.members {
+		if dirs[m.fromVersion] == nil {
+			versions = append(versions, m.fromVersion)
+		}
+		dirs[m.fromVersion] = append(dirs[m.fromVersion], m.dir)
+	}
+	parts := []string{}
+	for _, v := range versions {
+		parts = append(parts, fmt.Sprintf("%s in %s", v, strings.Join(dirs[v], ", ")))
+	}
+	return strings.Join(parts, "; ")
+}